@@ -0,0 +1,148 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestAllowedTablesValidateQuery verifies ALLOWED_TABLES is applied to every table a statement references
+func TestAllowedTablesValidateQuery(t *testing.T) {
+	t.Setenv("MYSQL_DATABASE", "shop")
+	t.Setenv("ALLOWED_TABLES", "customers, orders, reporting.daily")
+	t.Setenv("ALLOW_DDL", "true")
+	client := mysql.NewClient()
+
+	tests := []struct {
+		name    string
+		sql     string
+		errPart string // empty when the statement passes
+	}{
+		// Allowed tables
+		{"Plain table", "SELECT * FROM customers", ""},
+		{"Case-insensitive", "SELECT * FROM `Customers`", ""},
+		{"Current schema qualifier", "SELECT * FROM shop.customers", ""},
+		{"Join of allowed tables", "SELECT * FROM customers c JOIN orders o ON o.customer_id = c.id", ""},
+		{"Allowed CTE", "WITH c AS (SELECT * FROM customers) SELECT * FROM c", ""},
+		{"Other schema entry", "SELECT * FROM reporting.daily", ""},
+		{"No table", "SELECT 1", ""},
+		{"Functions only", "SELECT NOW(), DATABASE()", ""},
+		{"Write", "UPDATE orders SET status = 'paid' WHERE id = 1", ""},
+		{"Explain", "EXPLAIN SELECT * FROM orders", ""},
+		{"Show index", "SHOW INDEX FROM customers", ""},
+		{"Show variables", "SHOW VARIABLES LIKE 'max_connections'", ""},
+
+		// Tables outside the allowlist, wherever they appear
+		{"Other table", "SELECT * FROM users", "access to table 'users'"},
+		{"Count tool statement", "SELECT COUNT(*) as count FROM `users`", "access to table 'users'"},
+		{"Join", "SELECT * FROM customers c LEFT JOIN users u ON u.id = c.user_id", "access to table 'users'"},
+		{"Comma join", "SELECT * FROM customers, users", "access to table 'users'"},
+		{"Subquery", "SELECT * FROM customers WHERE id IN (SELECT customer_id FROM users)", "access to table 'users'"},
+		{"Derived table", "SELECT * FROM (SELECT * FROM users) u", "access to table 'users'"},
+		{"CTE body", "WITH u AS (SELECT * FROM users) SELECT * FROM u", "access to table 'users'"},
+		{"Other schema", "SELECT * FROM mysql.user", "access to table 'mysql.user'"},
+		{"Information schema", "SELECT * FROM information_schema.tables", "access to table 'information_schema.tables'"},
+		{"Same name in other schema", "SELECT * FROM archive.customers", "access to table 'archive.customers'"},
+		{"Entry only for other schema", "SELECT * FROM daily", "access to table 'daily'"},
+		{"Update with subquery", "UPDATE customers SET n = 0 WHERE id IN (SELECT id FROM users)", "access to table 'users'"},
+		{"Insert select", "INSERT INTO orders SELECT * FROM users", "access to table 'users'"},
+		{"Delete", "DELETE FROM users WHERE id = 1", "access to table 'users'"},
+		{"Multi-table delete", "DELETE o FROM orders o JOIN users u ON u.id = o.user_id WHERE u.id = 1", "access to table 'users'"},
+		{"Explain other table", "EXPLAIN SELECT * FROM users", "access to table 'users'"},
+		{"Describe", "DESCRIBE users", "access to table 'users'"},
+		{"Show columns", "SHOW COLUMNS FROM users", "access to table 'users'"},
+		{"DDL", "DROP TABLE users", "access to table 'users'"},
+
+		// Statements whose tables cannot be determined
+		{"Show tables", "SHOW TABLES", "cannot be determined"},
+		{"Table function", "SELECT * FROM JSON_TABLE('[]', '$[*]' COLUMNS (x INT PATH '$')) t", "cannot be determined"},
+		{"Create view", "CREATE VIEW v AS SELECT * FROM customers", "cannot be determined"},
+		{"Call", "CALL refresh_stats()", "CALL is not allowed with ALLOWED_TABLES"},
+		{"Use", "USE mysql", "USE is not allowed with ALLOWED_TABLES"},
+		{"Unbalanced quote", "SELECT * FROM customers WHERE name = 'x", "cannot be determined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.ValidateQuery(tt.sql)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("ValidateQuery(%q) = %v, want nil", tt.sql, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("ValidateQuery(%q) = %v, want error containing %q", tt.sql, err, tt.errPart)
+			}
+		})
+	}
+}
+
+// TestAllowedTablesHiddenReferences verifies tables named through comment
+// tricks and TABLE statements are checked
+func TestAllowedTablesHiddenReferences(t *testing.T) {
+	t.Setenv("ALLOWED_TABLES", "allowed,customers")
+	client := mysql.NewClient()
+
+	tests := []struct {
+		name    string
+		sql     string
+		errPart string
+	}{
+		{"Double dash without space", "SELECT * FROM allowed WHERE 0--0 UNION SELECT * FROM secret", "access to table 'secret'"},
+		{"Table in EXISTS", "SELECT * FROM allowed WHERE EXISTS(TABLE secret)", "access to table 'secret'"},
+		{"Table in union", "SELECT * FROM allowed UNION TABLE secret", "access to table 'secret'"},
+		{"MariaDB executable comment", "SELECT * FROM allowed /*M! , secret */", "cannot be determined"},
+		{"Versioned executable comment", "SELECT * FROM allowed /*!50000 , secret */", "cannot be determined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.ValidateQuery(tt.sql)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("ValidateQuery(%q) = %v, want error containing %q", tt.sql, err, tt.errPart)
+			}
+		})
+	}
+
+	if err := client.ValidateQuery("SELECT * FROM allowed WHERE EXISTS(TABLE customers)"); err != nil {
+		t.Errorf("TABLE of an allowed table should pass: %v", err)
+	}
+}
+
+// TestAllowedTablesOff verifies statements are not restricted without ALLOWED_TABLES
+func TestAllowedTablesOff(t *testing.T) {
+	t.Setenv("ALLOWED_TABLES", "")
+	client := mysql.NewClient()
+
+	for _, sql := range []string{"SELECT * FROM mysql.user", "SHOW TABLES", "CALL refresh_stats()", "USE shop"} {
+		if err := client.ValidateQuery(sql); err != nil {
+			t.Errorf("ValidateQuery(%q) = %v, want nil", sql, err)
+		}
+	}
+}
+
+// TestAllowedTablesTools verifies table-level tools refuse tables outside ALLOWED_TABLES before connecting
+func TestAllowedTablesTools(t *testing.T) {
+	t.Setenv("ALLOWED_TABLES", "customers")
+	client := mysql.NewClient()
+
+	tests := []struct {
+		tool    string
+		args    map[string]interface{}
+		errPart string
+	}{
+		{"indexes", map[string]interface{}{"table": "users"}, "access to table 'users' is not allowed"},
+		{"call_procedure", map[string]interface{}{"procedure": "refresh_stats"}, "CALL is not allowed with ALLOWED_TABLES"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			_, err := callClientMethod(client, tt.tool, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("%s error = %v, want %q", tt.tool, err, tt.errPart)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// newApprovalClient returns a client in approval mode with an empty queue
+func newApprovalClient(t *testing.T) *mysql.Client {
+	t.Setenv("APPROVAL_QUEUE_FILE", filepath.Join(t.TempDir(), "approvals.json"))
+	return mysql.NewClient()
+}
+
+// TestApprovalQueue verifies queued operations can be listed, read and rejected once
+func TestApprovalQueue(t *testing.T) {
+	client := newApprovalClient(t)
+	queue := client.Approvals()
+
+	op := &mysql.PendingOperation{
+		SQL:         "DELETE FROM orders WHERE id = 7",
+		Requester:   "test client",
+		Reason:      "duplicate order",
+		RequestedAt: time.Now().UTC(),
+	}
+	if err := queue.Add(op); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+	if !strings.HasPrefix(op.ID, "ap_") || op.Status != mysql.ApprovalPending {
+		t.Fatalf("Add() gave ID %q status %q, want ap_... pending", op.ID, op.Status)
+	}
+
+	got, err := client.OperationStatus(op.ID)
+	if err != nil {
+		t.Fatalf("OperationStatus() error: %v", err)
+	}
+	if got.SQL != op.SQL || got.Reason != op.Reason {
+		t.Errorf("OperationStatus() = %+v, want the queued operation", got)
+	}
+
+	pending, err := queue.List(mysql.ApprovalPending)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("List(pending) = %d operations, %v; want 1", len(pending), err)
+	}
+
+	rejected, err := client.RejectOperation(op.ID, "dba", "wrong customer")
+	if err != nil {
+		t.Fatalf("RejectOperation() error: %v", err)
+	}
+	if rejected.Status != mysql.ApprovalRejected || rejected.DecidedBy != "dba" || rejected.Note != "wrong customer" {
+		t.Errorf("RejectOperation() = %+v", rejected)
+	}
+
+	if _, err := client.RejectOperation(op.ID, "dba", ""); err == nil || !strings.Contains(err.Error(), "not pending") {
+		t.Errorf("second RejectOperation() = %v, want a not pending error", err)
+	}
+	if _, err := client.ApproveOperation(op.ID, "dba", ""); err == nil || !strings.Contains(err.Error(), "not pending") {
+		t.Errorf("ApproveOperation() after reject = %v, want a not pending error", err)
+	}
+	if pending, _ := queue.List(mysql.ApprovalPending); len(pending) != 0 {
+		t.Errorf("List(pending) after reject = %d operations, want 0", len(pending))
+	}
+}
+
+// TestStaleRunningOperation verifies running operations are listed, and reject marks them failed only once they are stale
+func TestStaleRunningOperation(t *testing.T) {
+	client := newApprovalClient(t)
+	started := time.Now().UTC().Add(-time.Hour)
+	past, future := started.Add(10*time.Minute), time.Now().UTC().Add(time.Hour)
+	ops := []*mysql.PendingOperation{
+		{ID: "ap_00000000000000aa", Status: mysql.ApprovalRunning, SQL: "DELETE FROM t WHERE id = 1", RequestedAt: started, StartedAt: &started, StaleAt: &past},
+		{ID: "ap_00000000000000bb", Status: mysql.ApprovalRunning, SQL: "DELETE FROM t WHERE id = 2", RequestedAt: started, StartedAt: &started, StaleAt: &future},
+	}
+	data, _ := json.Marshal(ops)
+	if err := os.WriteFile(client.Approvals().Path(), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runApprovalCLI(client, []string{"list"}, strings.NewReader(""), &out, &errOut); code != 0 ||
+		!strings.Contains(out.String(), "ap_00000000000000aa") || !strings.Contains(out.String(), "Still running") {
+		t.Errorf("list = %d %q, want the running operations", code, out.String())
+	}
+
+	if _, err := client.RejectOperation("ap_00000000000000bb", "dba", ""); err == nil || !strings.Contains(err.Error(), "is running") {
+		t.Errorf("RejectOperation() before StaleAt = %v, want a running error", err)
+	}
+
+	op, err := client.RejectOperation("ap_00000000000000aa", "dba", "server restarted")
+	if err != nil {
+		t.Fatalf("RejectOperation() after StaleAt error: %v", err)
+	}
+	if op.Status != mysql.ApprovalFailed || op.DecidedBy != "dba" || !strings.Contains(op.Result, "may or may not have been committed") {
+		t.Errorf("RejectOperation() after StaleAt = %+v, want failed with an unknown outcome", op)
+	}
+	if _, err := client.ApproveOperation("ap_00000000000000aa", "dba", ""); err == nil || !strings.Contains(err.Error(), "not pending") {
+		t.Errorf("ApproveOperation() after marking failed = %v, want a not pending error", err)
+	}
+}
+
+// TestApprovalModeRefusals verifies write paths that bypass the queue are refused in approval mode
+func TestApprovalModeRefusals(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"execute_batch", map[string]interface{}{"statements": []interface{}{"DELETE FROM a WHERE id = 1"}}},
+		{"bulk_insert", map[string]interface{}{"table": "customers", "csv": "name\na"}},
+		{"begin_transaction", map[string]interface{}{}},
+		{"revert_operation", map[string]interface{}{"operation_id": "op_0123456789abcdef"}},
+	}
+
+	client := newApprovalClient(t)
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			_, err := callClientMethod(client, tt.tool, tt.args)
+			if err == nil || !strings.Contains(err.Error(), "not available in approval mode") {
+				t.Errorf("%s error = %v, want approval mode refusal", tt.tool, err)
+			}
+		})
+	}
+}
+
+// TestOperationStatusErrors verifies operation_status rejects bad IDs and needs approval mode
+func TestOperationStatusErrors(t *testing.T) {
+	t.Run("Approval mode off", func(t *testing.T) {
+		t.Setenv("APPROVAL_QUEUE_FILE", "")
+		_, err := callClientMethod(mysql.NewClient(), "operation_status", map[string]interface{}{"id": "ap_0123456789abcdef"})
+		if err == nil || !strings.Contains(err.Error(), "approval mode is off") {
+			t.Errorf("error = %v, want approval mode is off", err)
+		}
+	})
+
+	client := newApprovalClient(t)
+	tests := []struct {
+		name    string
+		id      string
+		errPart string
+	}{
+		{"Invalid ID", "../etc/passwd", "invalid pending operation ID"},
+		{"Unknown ID", "ap_0123456789abcdef", "unknown pending operation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := callClientMethod(client, "operation_status", map[string]interface{}{"id": tt.id})
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("error = %v, want %q", err, tt.errPart)
+			}
+		})
+	}
+}
+
+// TestApprovalCLI verifies the list and reject subcommands and the usage errors
+func TestApprovalCLI(t *testing.T) {
+	client := newApprovalClient(t)
+	op := &mysql.PendingOperation{SQL: "UPDATE t SET n = 1 WHERE id = 2", Requester: "test client", RequestedAt: time.Now().UTC()}
+	if err := client.Approvals().Add(op); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	run := func(args ...string) (int, string, string) {
+		var out, errOut bytes.Buffer
+		code := runApprovalCLI(client, args, strings.NewReader(""), &out, &errOut)
+		return code, out.String(), errOut.String()
+	}
+
+	if code, out, _ := run("list"); code != 0 || !strings.Contains(out, op.ID) {
+		t.Errorf("list = %d %q, want the pending operation", code, out)
+	}
+	if code, out, _ := run("reject", op.ID, "not", "today"); code != 0 || !strings.Contains(out, "Rejected: not today") {
+		t.Errorf("reject = %d %q", code, out)
+	}
+	if code, out, _ := run("list"); code != 0 || !strings.Contains(out, "No pending or running operations") {
+		t.Errorf("list after reject = %d %q", code, out)
+	}
+	if code, out, _ := run("list", "-all"); code != 0 || !strings.Contains(out, "rejected") {
+		t.Errorf("list -all = %d %q", code, out)
+	}
+	if code, _, errOut := run("reject", op.ID); code != 1 || !strings.Contains(errOut, "not pending") {
+		t.Errorf("second reject = %d %q", code, errOut)
+	}
+	if code, _, errOut := run("approve"); code != 1 || !strings.Contains(errOut, "missing operation ID") {
+		t.Errorf("approve without ID = %d %q", code, errOut)
+	}
+	if code, _, errOut := run("purge"); code != 2 || !strings.Contains(errOut, "usage:") {
+		t.Errorf("unknown command = %d %q", code, errOut)
+	}
+
+	t.Setenv("APPROVAL_QUEUE_FILE", "")
+	var errOut bytes.Buffer
+	if code := runApprovalCLI(mysql.NewClient(), []string{"list"}, strings.NewReader(""), &bytes.Buffer{}, &errOut); code != 2 {
+		t.Errorf("list without APPROVAL_QUEUE_FILE = %d %q, want 2", code, errOut.String())
+	}
+}
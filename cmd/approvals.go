@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// Approval CLI.
+//
+// With APPROVAL_QUEUE_FILE set, execute queues writes instead of running
+// them. A human decides with the same binary:
+//
+//	mcp-go-mysql list [-all | -status S]
+//	mcp-go-mysql approve <id> [-confirm-key KEY]
+//	mcp-go-mysql reject <id> [reason]
+//
+// approve runs the statement in this process under the server's rules,
+// so it reads the same environment and .env as the server.
+
+const approvalUsage = `usage:
+  list [-all | -status pending|running|executed|failed|rejected]   show queued writes (pending and running by default)
+  approve <id> [-confirm-key KEY]                                   run a pending write
+  reject <id> [reason]                                              reject a pending write, or mark a stale running one failed`
+
+// runApprovalCLI runs one subcommand and returns the process exit code.
+func runApprovalCLI(client *mysql.Client, args []string, in io.Reader, out, errOut io.Writer) int {
+	if !client.ApprovalMode() {
+		fmt.Fprintln(errOut, "approval mode is off: set APPROVAL_QUEUE_FILE to the server's queue file")
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "list":
+		err = approvalList(client, args[1:], out)
+	case "approve":
+		err = approvalApprove(client, args[1:], in, out)
+	case "reject":
+		err = approvalReject(client, args[1:], out)
+	default:
+		fmt.Fprintf(errOut, "unknown command %q\n%s\n", args[0], approvalUsage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(errOut, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+// isApprovalCommand reports whether args start with a CLI subcommand.
+func isApprovalCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "list", "approve", "reject":
+		return true
+	}
+	return false
+}
+
+func approvalList(client *mysql.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	all := fs.Bool("all", false, "show decided operations too")
+	status := fs.String("status", "", "show operations in this state (pending and running by default)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	statuses := []string{mysql.ApprovalPending, mysql.ApprovalRunning}
+	switch {
+	case *all:
+		statuses = nil
+	case *status != "":
+		statuses = []string{*status}
+	}
+
+	ops, err := client.Approvals().List(statuses...)
+	if err != nil {
+		return err
+	}
+	if len(ops) == 0 {
+		if statuses == nil {
+			fmt.Fprintln(out, "The approval queue is empty.")
+		} else {
+			fmt.Fprintf(out, "No %s operations.\n", strings.Join(statuses, " or "))
+		}
+		return nil
+	}
+	for i, op := range ops {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, formatPendingOperation(op))
+	}
+	return nil
+}
+
+func approvalApprove(client *mysql.Client, args []string, in io.Reader, out io.Writer) error {
+	fs := flag.NewFlagSet("approve", flag.ContinueOnError)
+	confirmKey := fs.String("confirm-key", "", "SAFETY_KEY, for writes past MAX_SAFE_ROWS")
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	op, err := client.ApproveOperation(id, approverName(), *confirmKey)
+	var confirmErr *mysql.ConfirmationError
+	if errors.As(err, &confirmErr) && confirmErr.Token != "" {
+		// Tokens live in this process, so the retry has to happen here.
+		fmt.Fprintln(out, err)
+		fmt.Fprint(out, "Type the token to confirm, or press Enter to leave the operation pending: ")
+		line, _ := bufio.NewReader(in).ReadString('\n')
+		if token := strings.TrimSpace(line); token != "" {
+			op, err = client.ApproveOperation(id, approverName(), token)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, formatPendingOperation(op))
+	if op.Status == mysql.ApprovalFailed {
+		return fmt.Errorf("operation %s failed", op.ID)
+	}
+	return nil
+}
+
+func approvalReject(client *mysql.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("reject", flag.ContinueOnError)
+	id, err := parseWithID(fs, args)
+	if err != nil {
+		return err
+	}
+
+	op, err := client.RejectOperation(id, approverName(), strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, formatPendingOperation(op))
+	return nil
+}
+
+// parseWithID parses flags before and after the leading operation ID and
+// returns the ID; fs.Args() holds what follows.
+func parseWithID(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if fs.NArg() == 0 {
+		return "", fmt.Errorf("missing operation ID\n%s", approvalUsage)
+	}
+	id := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// requesterName identifies who queued a write: the MCP client and the
+// account the server runs as.
+func requesterName() string {
+	name := mcpClientName
+	if name == "" {
+		name = "unknown MCP client"
+	}
+	return fmt.Sprintf("%s (server user %s)", name, osUserName())
+}
+
+// approverName identifies who decided: the account running the CLI.
+func approverName() string {
+	return osUserName()
+}
+
+func osUserName() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		name += "@" + host
+	}
+	return name
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestApplyAutoLimit verifies where the automatic LIMIT goes and when it is skipped
+func TestApplyAutoLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+		applied  bool
+	}{
+		{"Plain select", "SELECT * FROM big", "SELECT * FROM big LIMIT 11", true},
+		{"Trailing semicolon", "SELECT * FROM big;", "SELECT * FROM big LIMIT 11;", true},
+		{"Trailing comment", "SELECT * FROM big -- everything", "SELECT * FROM big LIMIT 11 -- everything", true},
+		{"Order by", "SELECT * FROM big ORDER BY id DESC", "SELECT * FROM big ORDER BY id DESC LIMIT 11", true},
+		{"Subquery limit does not count", "SELECT * FROM big WHERE id IN (SELECT id FROM t LIMIT 5)", "SELECT * FROM big WHERE id IN (SELECT id FROM t LIMIT 5) LIMIT 11", true},
+		{"CTE", "WITH x AS (SELECT 1) SELECT * FROM x", "WITH x AS (SELECT 1) SELECT * FROM x LIMIT 11", true},
+		{"Union", "SELECT a FROM t1 UNION SELECT a FROM t2", "SELECT a FROM t1 UNION SELECT a FROM t2 LIMIT 11", true},
+		{"Parenthesized", "(SELECT a FROM t1 LIMIT 3) UNION (SELECT a FROM t2)", "(SELECT a FROM t1 LIMIT 3) UNION (SELECT a FROM t2) LIMIT 11", true},
+		{"For update", "SELECT * FROM t WHERE a = 1 FOR UPDATE", "SELECT * FROM t WHERE a = 1 LIMIT 11 FOR UPDATE", true},
+		{"Lock in share mode", "SELECT * FROM t LOCK IN SHARE MODE", "SELECT * FROM t LIMIT 11 LOCK IN SHARE MODE", true},
+		{"Insert function", "SELECT INSERT(name, 1, 1, 'x') FROM t", "SELECT INSERT(name, 1, 1, 'x') FROM t LIMIT 11", true},
+		{"String mentioning limit", "SELECT 'no limit' FROM t", "SELECT 'no limit' FROM t LIMIT 11", true},
+		{"Has limit", "SELECT * FROM t LIMIT 5", "", false},
+		{"Has limit offset", "select * from t limit 5, 10", "", false},
+		{"Into variable", "SELECT COUNT(*) INTO @n FROM t", "", false},
+		{"Show", "SHOW TABLES", "", false},
+		{"CTE with delete", "WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)", "", false},
+		{"Unclosed quote", "SELECT * FROM t WHERE a = 'x", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := mysql.ApplyAutoLimit(tt.sql, 11)
+			if applied != tt.applied {
+				t.Fatalf("Expected applied=%v, got %v (%s)", tt.applied, applied, got)
+			}
+			if !applied {
+				if got != tt.sql {
+					t.Errorf("Statement must be unchanged when not applied, got %q", got)
+				}
+				return
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestLimitNote verifies the response says whether more rows exist
+func TestLimitNote(t *testing.T) {
+	more := formatQueryResultVerbose(&QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}, {2}}, RowCount: 2, LimitApplied: 2, HasMore: true})
+	if !contains(more, "more rows exist") || !contains(more, "no_limit") {
+		t.Errorf("Expected has-more note, got %q", more)
+	}
+	done := formatQueryResultVerbose(&QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}, RowCount: 1, LimitApplied: 2})
+	if !contains(done, "no more rows") {
+		t.Errorf("Expected no-more note, got %q", done)
+	}
+	truncated := formatQueryResultVerbose(&QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}, RowCount: 1, LimitApplied: 2, Truncated: true})
+	if !contains(truncated, "more rows exist") || contains(truncated, "no more rows") {
+		t.Errorf("Expected a truncated result to report more rows, got %q", truncated)
+	}
+	CompactMode = true
+	defer func() { CompactMode = false }()
+	compact := limitNote(&QueryResult{RowCount: 1, LimitApplied: 2, Truncated: true})
+	if !contains(compact, "has_more: true") {
+		t.Errorf("Expected has_more: true for a truncated result, got %q", compact)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestGetSQLArgs verifies JSON values convert to placeholder arguments
+func TestGetSQLArgs(t *testing.T) {
+	got, err := getSQLArgs([]interface{}{float64(42), 1.5, "x", true, nil})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []interface{}{int64(42), 1.5, "x", true, nil}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Arg %d: expected %#v, got %#v", i, want[i], got[i])
+		}
+	}
+
+	if _, err := getSQLArgs([]interface{}{map[string]interface{}{}}); err == nil {
+		t.Error("Expected error for an object parameter")
+	}
+}
+
+// TestExecuteBatchArguments verifies malformed statement lists are rejected before reaching the database
+func TestExecuteBatchArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"Missing", map[string]interface{}{}, "'statements'"},
+		{"Empty", map[string]interface{}{"statements": []interface{}{}}, "'statements'"},
+		{"Wrong element", map[string]interface{}{"statements": []interface{}{float64(1)}}, "statement 1 must be an object"},
+		{"Missing sql", map[string]interface{}{"statements": []interface{}{map[string]interface{}{"params": []interface{}{}}}}, "statement 1: missing or invalid 'sql'"},
+		{"Bad param", map[string]interface{}{"statements": []interface{}{
+			"DELETE FROM t WHERE id = 1",
+			map[string]interface{}{"sql": "UPDATE t SET a = ?", "params": []interface{}{[]interface{}{1}}},
+		}}, "statement 2: parameter 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handleExecuteBatch(nil, tt.args)
+			if err == nil || !contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestBatchErrorReportsEachStatement verifies a rolled-back batch says what each statement did
+func TestBatchErrorReportsEachStatement(t *testing.T) {
+	cause := errors.New("statement 2 of 3 failed. All statements have been rolled back")
+	err := error(&mysql.BatchError{Err: cause, Result: &mysql.BatchResult{Steps: []mysql.BatchStepResult{
+		{SQL: "UPDATE a SET x = 1", Affected: 4},
+		{SQL: "DELETE FROM b", Error: "Error 1146: Table 'b' doesn't exist"},
+	}}})
+
+	want := "statement 2 of 3 failed. All statements have been rolled back\n  1. ok, 4 rows (rolled back)\n  2. FAILED: Error 1146: Table 'b' doesn't exist"
+	if err.Error() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, err.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected BatchError to unwrap to its cause")
+	}
+
+	out := formatBatchResult(&mysql.BatchResult{Message: "Batch of 2 statements committed. Rows affected: 5",
+		Steps: []mysql.BatchStepResult{{SQL: "UPDATE a\n  SET x = 1", Affected: 4}, {SQL: "DELETE FROM b", Affected: 1}}})
+	if !contains(out, "1. 4 rows: UPDATE a SET x = 1") || !contains(out, "2. 1 rows: DELETE FROM b") {
+		t.Errorf("Unexpected batch output:\n%s", out)
+	}
+}
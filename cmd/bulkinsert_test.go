@@ -0,0 +1,139 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestGetBulkRows verifies JSON and CSV rows are read into columns and bound values
+func TestGetBulkRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantCols []string
+		wantRows [][]interface{}
+		errPart  string
+	}{
+		{
+			name: "JSON rows",
+			args: map[string]interface{}{
+				"columns": []interface{}{"id", "name"},
+				"rows":    []interface{}{[]interface{}{float64(1), "a"}, []interface{}{float64(2), nil}},
+			},
+			wantCols: []string{"id", "name"},
+			wantRows: [][]interface{}{{int64(1), "a"}, {int64(2), nil}},
+		},
+		{
+			name:     "CSV with header",
+			args:     map[string]interface{}{"csv": "id,name\n1,\"Smith, J\"\n2,\\N\n"},
+			wantCols: []string{"id", "name"},
+			wantRows: [][]interface{}{{"1", "Smith, J"}, {"2", nil}},
+		},
+		{
+			name:     "CSV with columns",
+			args:     map[string]interface{}{"columns": []interface{}{"code"}, "csv": "ES\nFR"},
+			wantCols: []string{"code"},
+			wantRows: [][]interface{}{{"ES"}, {"FR"}},
+		},
+		{"Both inputs", map[string]interface{}{"columns": []interface{}{"id"}, "rows": []interface{}{}, "csv": "1"}, nil, nil, "not both"},
+		{"No input", map[string]interface{}{"columns": []interface{}{"id"}}, nil, nil, "missing 'rows' or 'csv'"},
+		{"JSON without columns", map[string]interface{}{"rows": []interface{}{[]interface{}{"a"}}}, nil, nil, "'columns'"},
+		{"Row not an array", map[string]interface{}{"columns": []interface{}{"id"}, "rows": []interface{}{"a"}}, nil, nil, "row 1 must be an array"},
+		{"Nested value", map[string]interface{}{"columns": []interface{}{"id"}, "rows": []interface{}{[]interface{}{[]interface{}{}}}}, nil, nil, "row 1: parameter 1"},
+		{"Bad column name", map[string]interface{}{"columns": []interface{}{"id", float64(2)}, "rows": []interface{}{}}, nil, nil, "column 2"},
+		{"Broken CSV", map[string]interface{}{"csv": "id\n\"open"}, nil, nil, "invalid csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, rows, err := getBulkRows(tt.args)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(cols, tt.wantCols) || !reflect.DeepEqual(rows, tt.wantRows) {
+				t.Errorf("Got %v %v, want %v %v", cols, rows, tt.wantCols, tt.wantRows)
+			}
+		})
+	}
+}
+
+// TestBulkInsertStatement verifies the generated multi-row INSERT for each on_duplicate mode
+func TestBulkInsertStatement(t *testing.T) {
+	columns := []mysql.ColumnInfo{
+		{Name: "id", Key: "PRI"},
+		{Name: "code", Key: "UNI"},
+		{Name: "Name"},
+		{Name: "search", Extra: "VIRTUAL GENERATED"},
+	}
+
+	tests := []struct {
+		name        string
+		names       []string
+		onDuplicate string
+		want        string
+		errPart     string
+	}{
+		{"Error mode", []string{"id", "name"}, mysql.OnDuplicateError, "INSERT INTO `items` (`id`, `Name`) VALUES (?, ?), (?, ?)", ""},
+		{"Ignore mode", []string{"id", "name"}, mysql.OnDuplicateIgnore, "INSERT IGNORE INTO `items` (`id`, `Name`) VALUES (?, ?), (?, ?)", ""},
+		{"Update mode skips keys", []string{"id", "code", "name"}, mysql.OnDuplicateUpdate,
+			"INSERT INTO `items` (`id`, `code`, `Name`) VALUES (?, ?, ?), (?, ?, ?) ON DUPLICATE KEY UPDATE `Name` = VALUES(`Name`)", ""},
+		{"Unknown column", []string{"id", "price"}, mysql.OnDuplicateError, "", `unknown column "price"`},
+		{"Duplicate column", []string{"id", "ID"}, mysql.OnDuplicateError, "", "listed twice"},
+		{"Generated column", []string{"search"}, mysql.OnDuplicateError, "", "generated"},
+		{"Update with keys only", []string{"id", "code"}, mysql.OnDuplicateUpdate, "", "outside the primary and unique keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mysql.BulkInsertStatement("items", columns, tt.names, tt.onDuplicate, 2)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Got %q (%v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestBulkInsertArgs verifies bad calls are refused before any SQL runs
+func TestBulkInsertArgs(t *testing.T) {
+	client := mysql.NewClient()
+	tooMany := make([]interface{}, mysql.MaxBulkInsertRows+1)
+	for i := range tooMany {
+		tooMany[i] = []interface{}{float64(i)}
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		errPart string
+	}{
+		{"Missing table", map[string]interface{}{"csv": "id\n1"}, "missing or invalid 'table'"},
+		{"Bad on_duplicate", map[string]interface{}{"table": "t", "csv": "id\n1", "on_duplicate": "replace"}, "invalid on_duplicate"},
+		{"No rows", map[string]interface{}{"table": "t", "csv": "id\n"}, "no rows given"},
+		{"Ragged row", map[string]interface{}{"table": "t", "csv": "id,name\n1"}, "row 1 has 1 values, expected 2"},
+		{"Too many rows", map[string]interface{}{"table": "t", "columns": []interface{}{"id"}, "rows": tooMany}, "the limit is"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := callClientMethod(client, "bulk_insert", tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
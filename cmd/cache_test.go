@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+func newTestCache(maxEntries int, ttl time.Duration) *mysql.ResultCache {
+	return mysql.NewResultCache(&mysql.CacheConfig{
+		Enabled:    true,
+		MaxEntries: maxEntries,
+		TTL: map[mysql.CacheKind]time.Duration{
+			mysql.CacheQuery: ttl,
+			mysql.CacheCount: ttl,
+		},
+	})
+}
+
+// cachePut caches query under kind with the tables it references
+func cachePut(t *testing.T, c *mysql.ResultCache, kind mysql.CacheKind, query string) string {
+	t.Helper()
+	key, ok := c.Key(kind, "shop", query, nil)
+	if !ok {
+		t.Fatalf("Expected %q to be cacheable", query)
+	}
+	tables, known := mysql.ReferencedTables(query)
+	c.Put(key, kind, query, tables, known)
+	return key
+}
+
+// TestResultCacheKey verifies what distinguishes and what merges cache keys
+func TestResultCacheKey(t *testing.T) {
+	c := newTestCache(10, time.Minute)
+
+	a, _ := c.Key(mysql.CacheQuery, "shop", "select * from users where id = ?", []interface{}{1})
+	b, _ := c.Key(mysql.CacheQuery, "shop", "SELECT *\n  FROM users WHERE id = ?", []interface{}{1})
+	if a != b {
+		t.Errorf("Expected layout-insensitive keys, got %q and %q", a, b)
+	}
+
+	for name, other := range map[string]func() (string, bool){
+		"args": func() (string, bool) {
+			return c.Key(mysql.CacheQuery, "shop", "SELECT * FROM users WHERE id = ?", []interface{}{2})
+		},
+		"database": func() (string, bool) {
+			return c.Key(mysql.CacheQuery, "crm", "SELECT * FROM users WHERE id = ?", []interface{}{1})
+		},
+		"kind": func() (string, bool) {
+			return c.Key(mysql.CacheCount, "shop", "SELECT * FROM users WHERE id = ?", []interface{}{1})
+		},
+	} {
+		if k, _ := other(); k == a {
+			t.Errorf("Expected different %s to give a different key", name)
+		}
+	}
+
+	if _, ok := c.Key(mysql.CacheNone, "shop", "SELECT 1", nil); ok {
+		t.Errorf("CacheNone should not be cacheable")
+	}
+	if _, ok := c.Key(mysql.CacheQuery, "shop", "SELECT 'unterminated", nil); ok {
+		t.Errorf("Untokenizable statements should not be cacheable")
+	}
+
+	disabled := mysql.NewResultCache(&mysql.CacheConfig{TTL: map[mysql.CacheKind]time.Duration{mysql.CacheQuery: time.Minute}})
+	if _, ok := disabled.Key(mysql.CacheQuery, "shop", "SELECT 1", nil); ok {
+		t.Errorf("Disabled cache should not produce keys")
+	}
+}
+
+// TestResultCacheInvalidation verifies writes drop only dependent entries
+func TestResultCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		write     string
+		survivors []string
+	}{
+		{"Unrelated table", "UPDATE logs SET seen = 1", []string{"users", "orders join"}},
+		{"Written table", "UPDATE users SET active = 0 WHERE id = 1", []string{"orders join"}},
+		{"Joined table", "DELETE FROM customers WHERE id = 1", []string{"users"}},
+		{"Unknown tables", "CALL cleanup()", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(10, time.Minute)
+			keys := map[string]string{
+				"users":       cachePut(t, c, mysql.CacheQuery, "SELECT * FROM users"),
+				"orders join": cachePut(t, c, mysql.CacheQuery, "SELECT * FROM orders o JOIN customers c ON o.cid = c.id"),
+				"unknown":     cachePut(t, c, mysql.CacheQuery, "SHOW TABLES"),
+			}
+
+			c.Invalidate(mysql.ReferencedTables(tt.write))
+
+			if _, _, ok := c.Get(keys["unknown"]); ok {
+				t.Errorf("Entries with unknown tables should be dropped by any write")
+			}
+			alive := map[string]bool{}
+			for _, s := range tt.survivors {
+				alive[s] = true
+			}
+			for name, key := range keys {
+				if name == "unknown" {
+					continue
+				}
+				if _, _, ok := c.Get(key); ok != alive[name] {
+					t.Errorf("Entry %q: expected alive=%v, got %v", name, alive[name], ok)
+				}
+			}
+		})
+	}
+}
+
+// TestResultCacheExpiryAndEviction verifies TTL expiry and the LRU bound
+func TestResultCacheExpiryAndEviction(t *testing.T) {
+	c := newTestCache(2, 50*time.Millisecond)
+
+	a := cachePut(t, c, mysql.CacheQuery, "SELECT 1 FROM a")
+	b := cachePut(t, c, mysql.CacheQuery, "SELECT 1 FROM b")
+	if _, hit, ok := c.Get(a); !ok || hit == nil {
+		t.Fatalf("Expected a fresh hit for a")
+	}
+	cachePut(t, c, mysql.CacheQuery, "SELECT 1 FROM c") // evicts b, the least recently used
+
+	if _, _, ok := c.Get(b); ok {
+		t.Errorf("Expected b to be evicted")
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if _, _, ok := c.Get(a); ok {
+		t.Errorf("Expected a to expire")
+	}
+}
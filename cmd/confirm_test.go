@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestConfirmConfig verifies CONFIRMATION_MODE and CONFIRM_TOKEN_TTL parsing and fallbacks
+func TestConfirmConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		ttl  string
+		want mysql.ConfirmConfig
+	}{
+		{"Defaults", "", "", mysql.ConfirmConfig{Mode: mysql.ConfirmModeKey, TokenTTL: mysql.DefaultConfirmTokenTTL}},
+		{"Token mode", "token", "90s", mysql.ConfirmConfig{Mode: mysql.ConfirmModeToken, TokenTTL: 90 * time.Second}},
+		{"Mode is case-insensitive", "TOKEN", "", mysql.ConfirmConfig{Mode: mysql.ConfirmModeToken, TokenTTL: mysql.DefaultConfirmTokenTTL}},
+		{"Unknown mode falls back to key", "otp", "", mysql.ConfirmConfig{Mode: mysql.ConfirmModeKey, TokenTTL: mysql.DefaultConfirmTokenTTL}},
+		{"Invalid TTL falls back", "token", "soon", mysql.ConfirmConfig{Mode: mysql.ConfirmModeToken, TokenTTL: mysql.DefaultConfirmTokenTTL}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIRMATION_MODE", tt.mode)
+			t.Setenv("CONFIRM_TOKEN_TTL", tt.ttl)
+			if got := mysql.NewConfirmConfig(); *got != tt.want {
+				t.Errorf("NewConfirmConfig() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfirmationErrorMessage verifies the gate message in key and token mode
+func TestConfirmationErrorMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *mysql.ConfirmationError
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "Key mode keeps the existing wording",
+			err:     &mysql.ConfirmationError{Subject: "operation affects 150 rows", Limit: 100, Outcome: "Changes have been rolled back"},
+			want:    []string{"operation affects 150 rows (>100). Provide safety key to confirm. Changes have been rolled back"},
+			notWant: []string{"ct_"},
+		},
+		{
+			name: "Token mode hands out the token",
+			err: &mysql.ConfirmationError{Subject: "operation affects 150 rows", Limit: 100, Outcome: "Changes have been rolled back",
+				Token: "ct_abc", TokenTTL: 5 * time.Minute},
+			want:    []string{"(>100)", `confirm_key="ct_abc"`, "within 5m0s", "works once"},
+			notWant: []string{"safety key", "not valid"},
+		},
+		{
+			name: "Rejected token is explained",
+			err: &mysql.ConfirmationError{Subject: "batch affects 300 rows in total", Limit: 100, Outcome: "All statements have been rolled back",
+				Token: "ct_def", TokenTTL: time.Minute, Rejected: true},
+			want: []string{"confirm_key is not valid for this call", "a new token was issued", `confirm_key="ct_def"`},
+		},
+		{
+			name:    "Up-front confirmation has no row limit",
+			err:     &mysql.ConfirmationError{Subject: "logs (MyISAM) cannot be rolled back", Limit: -1, Outcome: "Nothing was executed"},
+			want:    []string{"logs (MyISAM) cannot be rolled back. Provide safety key to confirm. Nothing was executed"},
+			notWant: []string{"(>"},
+		},
+		{
+			name: "Up-front token does not mention the row count",
+			err: &mysql.ConfirmationError{Subject: "logs (MyISAM) cannot be rolled back", Limit: -1, Outcome: "Nothing was executed",
+				Token: "ct_ghi", TokenTTL: time.Minute},
+			want:    []string{`confirm_key="ct_ghi"`, "the token works once."},
+			notWant: []string{"(>", "row count"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.err.Error()
+			for _, w := range tt.want {
+				if !strings.Contains(msg, w) {
+					t.Errorf("Expected %q in %q", w, msg)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(msg, w) {
+					t.Errorf("Did not expect %q in %q", w, msg)
+				}
+			}
+		})
+	}
+}
+
+// TestConfirmationTokens verifies token mode: tokens work once, expire, and are bound to the SQL, the parameters and the row count
+func TestConfirmationTokens(t *testing.T) {
+	const query = "UPDATE orders SET status = ? WHERE region = ?"
+	args := []interface{}{"closed", "eu"}
+
+	newClient := func(t *testing.T, ttl string) *mysql.Client {
+		t.Setenv("CONFIRMATION_MODE", "token")
+		t.Setenv("CONFIRM_TOKEN_TTL", ttl)
+		t.Setenv("MAX_SAFE_ROWS", "100")
+		return mysql.NewClient()
+	}
+	// issue returns the token the gate hands out for a write of 150 rows.
+	issue := func(t *testing.T, client *mysql.Client) string {
+		t.Helper()
+		var confirmErr *mysql.ConfirmationError
+		if err := client.ConfirmWrite(query, args, 150, ""); !errors.As(err, &confirmErr) || !strings.HasPrefix(confirmErr.Token, "ct_") {
+			t.Fatalf("ConfirmWrite() without a key = %v, want a ConfirmationError with a ct_ token", err)
+		}
+		return confirmErr.Token
+	}
+	// rejected reports whether err refused the token that was given.
+	rejected := func(err error) bool {
+		var confirmErr *mysql.ConfirmationError
+		return errors.As(err, &confirmErr) && confirmErr.Rejected && confirmErr.Token != ""
+	}
+
+	t.Run("Under the limit", func(t *testing.T) {
+		if err := newClient(t, "").ConfirmWrite(query, args, 100, ""); err != nil {
+			t.Errorf("ConfirmWrite() of 100 rows = %v, want nil", err)
+		}
+	})
+
+	t.Run("Single use", func(t *testing.T) {
+		client := newClient(t, "")
+		token := issue(t, client)
+		if err := client.ConfirmWrite(query, args, 150, token); err != nil {
+			t.Fatalf("ConfirmWrite() with the token = %v, want nil", err)
+		}
+		if err := client.ConfirmWrite(query, args, 150, token); !rejected(err) {
+			t.Errorf("ConfirmWrite() with a used token = %v, want a rejection", err)
+		}
+	})
+
+	t.Run("SAFETY_KEY is not a token", func(t *testing.T) {
+		t.Setenv("SAFETY_KEY", "let-me-in")
+		client := newClient(t, "")
+		if err := client.ConfirmWrite(query, args, 150, "let-me-in"); !rejected(err) {
+			t.Errorf("ConfirmWrite() with SAFETY_KEY = %v, want a rejection", err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		client := newClient(t, "10ms")
+		token := issue(t, client)
+		time.Sleep(30 * time.Millisecond)
+		if err := client.ConfirmWrite(query, args, 150, token); !rejected(err) {
+			t.Errorf("ConfirmWrite() with an expired token = %v, want a rejection", err)
+		}
+	})
+
+	bindings := []struct {
+		name     string
+		query    string
+		args     []interface{}
+		affected int64
+	}{
+		{"Different SQL", "UPDATE orders SET status = ? WHERE region <> ?", args, 150},
+		{"Different parameter value", query, []interface{}{"closed", "us"}, 150},
+		{"Different parameter type", query, []interface{}{"closed", []byte("eu")}, 150},
+		{"Missing parameter", query, args[:1], 150},
+		{"Row count changed", query, args, 151},
+	}
+	for _, b := range bindings {
+		t.Run(b.name, func(t *testing.T) {
+			client := newClient(t, "")
+			token := issue(t, client)
+			if err := client.ConfirmWrite(b.query, b.args, b.affected, token); !rejected(err) {
+				t.Errorf("ConfirmWrite() = %v, want a rejection", err)
+			}
+			// A token presented for another call is used up.
+			if err := client.ConfirmWrite(query, args, 150, token); !rejected(err) {
+				t.Errorf("ConfirmWrite() for the original call afterwards = %v, want a rejection", err)
+			}
+		})
+	}
+}
+
+// TestConfirmationKey verifies key mode accepts SAFETY_KEY and nothing else
+func TestConfirmationKey(t *testing.T) {
+	t.Setenv("CONFIRMATION_MODE", "")
+	t.Setenv("SAFETY_KEY", "let-me-in")
+	t.Setenv("MAX_SAFE_ROWS", "100")
+	client := mysql.NewClient()
+
+	if err := client.ConfirmWrite("DELETE FROM logs WHERE id < ?", []interface{}{1000}, 150, "let-me-in"); err != nil {
+		t.Errorf("ConfirmWrite() with SAFETY_KEY = %v, want nil", err)
+	}
+	var confirmErr *mysql.ConfirmationError
+	err := client.ConfirmWrite("DELETE FROM logs WHERE id < ?", []interface{}{1000}, 150, "guess")
+	if !errors.As(err, &confirmErr) || confirmErr.Token != "" || confirmErr.Limit != 100 {
+		t.Errorf("ConfirmWrite() with a wrong key = %#v, want a key-mode ConfirmationError", err)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Binary content.
+//
+// BLOB, BINARY and VARBINARY columns are read as []byte. In the text
+// rendering they show as a short hex literal or a "<mime, N bytes>"
+// placeholder; the bytes themselves travel as extra content items:
+//
+//   - any cell whose bytes sniff as PNG, JPEG or GIF becomes an image item
+//   - with binary_column, every non-NULL value of that column is returned,
+//     as an image item when it sniffs as one, otherwise as an embedded
+//     base64 resource
+//
+// At most MaxBinaryItems items are attached to one response.
+
+// MaxBinaryItems caps the image/resource items attached to one response.
+const MaxBinaryItems = 10
+
+// maxHexBytes is the largest binary value rendered inline as 0x... text.
+const maxHexBytes = 32
+
+// textContent wraps a text-only handler result as content items.
+func textContent(text string, err error) ([]ContentItem, error) {
+	if err != nil {
+		return nil, err
+	}
+	return []ContentItem{{Type: "text", Text: text}}, nil
+}
+
+// resultContent renders a query result as a text item followed by image
+// and resource items for its binary values. binaryColumn, when not empty,
+// names a column whose values are returned whatever their type.
+func resultContent(result *QueryResult, binaryColumn string) ([]ContentItem, error) {
+	col := -1
+	if binaryColumn != "" {
+		for i, c := range result.Columns {
+			if strings.EqualFold(c, binaryColumn) {
+				col = i
+				break
+			}
+		}
+		if col < 0 {
+			return nil, fmt.Errorf("binary_column %q is not in the result (columns: %s)", binaryColumn, strings.Join(result.Columns, ", "))
+		}
+	}
+
+	var items []ContentItem
+	skipped := 0
+	for r, row := range result.Rows {
+		for i, v := range row {
+			item, ok := binaryItem(v, i == col, fmt.Sprintf("mysql://result/row/%d/%s", r+1, result.Columns[i]))
+			if !ok {
+				continue
+			}
+			if len(items) == MaxBinaryItems {
+				skipped++
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+
+	text := formatQueryResultStructured(result)
+	if skipped > 0 {
+		text += fmt.Sprintf("\n%d more binary values not attached (limit %d per response); narrow the query to fetch them.", skipped, MaxBinaryItems)
+	}
+	return append([]ContentItem{{Type: "text", Text: text}}, items...), nil
+}
+
+// binaryItem turns one cell into an image or resource item. Images are
+// attached whenever they sniff as one; other values only when requested.
+func binaryItem(v interface{}, requested bool, uri string) (ContentItem, bool) {
+	var b []byte
+	switch x := v.(type) {
+	case []byte:
+		b = x
+	case string:
+		if !requested {
+			return ContentItem{}, false
+		}
+		b = []byte(x)
+	default:
+		return ContentItem{}, false
+	}
+
+	mime := sniffImage(b)
+	if mime != "" {
+		return ContentItem{Type: "image", Data: base64.StdEncoding.EncodeToString(b), MimeType: mime}, true
+	}
+	if !requested {
+		return ContentItem{}, false
+	}
+	return ContentItem{Type: "resource", Resource: &ResourceContent{
+		URI:      uri,
+		MimeType: http.DetectContentType(b),
+		Blob:     base64.StdEncoding.EncodeToString(b),
+	}}, true
+}
+
+// sniffImage returns the MIME type of a PNG, JPEG or GIF, or "".
+func sniffImage(b []byte) string {
+	switch mime := http.DetectContentType(b); mime {
+	case "image/png", "image/jpeg", "image/gif":
+		return mime
+	}
+	return ""
+}
+
+// formatBinary renders a binary value for the text table: printable UTF-8
+// as is, short values as a hex literal, anything else as a placeholder.
+func formatBinary(b []byte) string {
+	if utf8.Valid(b) && strings.IndexFunc(string(b), isControl) < 0 {
+		return string(b)
+	}
+	if len(b) <= maxHexBytes {
+		return fmt.Sprintf("0x%X", b)
+	}
+	return fmt.Sprintf("<%s, %d bytes>", http.DetectContentType(b), len(b))
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t' && r != '\n' && r != '\r'
+}
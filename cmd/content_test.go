@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+var (
+	pngBytes = append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 40)...)
+	gifBytes = append([]byte("GIF89a"), make([]byte, 40)...)
+	zipBytes = append([]byte("PK\x03\x04"), make([]byte, 40)...)
+)
+
+// TestIsBinaryType verifies which driver column types are kept as bytes
+func TestIsBinaryType(t *testing.T) {
+	tests := []struct {
+		dbType string
+		binary bool
+	}{
+		{"BLOB", true},
+		{"MEDIUMBLOB", true},
+		{"VARBINARY", true},
+		{"BINARY", true},
+		{"BIT", true},
+		{"TEXT", false},
+		{"VARCHAR", false},
+		{"JSON", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := mysql.IsBinaryType(tt.dbType); got != tt.binary {
+			t.Errorf("IsBinaryType(%q) = %v, want %v", tt.dbType, got, tt.binary)
+		}
+	}
+}
+
+// TestResultContent verifies images are attached automatically and other
+// binaries only for binary_column
+func TestResultContent(t *testing.T) {
+	result := &QueryResult{
+		Columns:  []string{"id", "photo", "attachment"},
+		Rows:     [][]interface{}{{int64(1), pngBytes, zipBytes}, {int64(2), nil, gifBytes}},
+		RowCount: 2,
+	}
+
+	tests := []struct {
+		name   string
+		column string
+		types  []string
+	}{
+		{"Images only", "", []string{"text", "image", "image"}},
+		{"Requested column", "attachment", []string{"text", "image", "resource", "image"}},
+		{"Column name is case-insensitive", "ATTACHMENT", []string{"text", "image", "resource", "image"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := resultContent(result, tt.column)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(items) != len(tt.types) {
+				t.Fatalf("Expected %d items, got %d: %+v", len(tt.types), len(items), items)
+			}
+			for i, typ := range tt.types {
+				if items[i].Type != typ {
+					t.Errorf("Item %d: expected type %s, got %s", i, typ, items[i].Type)
+				}
+			}
+		})
+	}
+
+	items, _ := resultContent(result, "attachment")
+	if items[1].MimeType != "image/png" || items[1].Data != base64.StdEncoding.EncodeToString(pngBytes) {
+		t.Errorf("Unexpected image item: %+v", items[1])
+	}
+	res := items[2].Resource
+	if res == nil || res.URI != "mysql://result/row/1/attachment" || res.MimeType != "application/zip" || res.Blob != base64.StdEncoding.EncodeToString(zipBytes) {
+		t.Errorf("Unexpected resource item: %+v", res)
+	}
+	if contains(items[0].Text, "\x89PNG") {
+		t.Errorf("Binary must not be written into the text: %q", items[0].Text)
+	}
+
+	if _, err := resultContent(result, "missing"); err == nil {
+		t.Error("Expected error for unknown binary_column")
+	}
+}
+
+// TestResultContentLimit verifies the per-response cap on binary items
+func TestResultContentLimit(t *testing.T) {
+	result := &QueryResult{Columns: []string{"photo"}}
+	for i := 0; i < MaxBinaryItems+3; i++ {
+		result.Rows = append(result.Rows, []interface{}{pngBytes})
+	}
+	result.RowCount = len(result.Rows)
+
+	items, err := resultContent(result, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(items) != MaxBinaryItems+1 {
+		t.Errorf("Expected %d items, got %d", MaxBinaryItems+1, len(items))
+	}
+	if !contains(items[0].Text, "3 more binary values not attached") {
+		t.Errorf("Expected overflow note, got %q", items[0].Text)
+	}
+}
+
+// TestFormatBinary verifies how binary values render in the text table
+func TestFormatBinary(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    []byte
+		expected string
+	}{
+		{"Printable", []byte("a1b2-c3"), "a1b2-c3"},
+		{"Short binary as hex", []byte{0x01, 0xAB, 0x00}, "0x01AB00"},
+		{"Image placeholder", pngBytes, "<image/png, 48 bytes>"},
+		{"Other placeholder", zipBytes, "<application/zip, 44 bytes>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatRowCompact([]interface{}{tt.value}); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestContentItemJSON verifies the wire shape of each content type
+func TestContentItemJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		item     ContentItem
+		expected string
+	}{
+		{"Text", ContentItem{Type: "text", Text: "ok"}, `{"type":"text","text":"ok"}`},
+		{"Empty text keeps field", ContentItem{Type: "text"}, `{"type":"text","text":""}`},
+		{"Image", ContentItem{Type: "image", Data: "AAE=", MimeType: "image/png"}, `{"type":"image","data":"AAE=","mimeType":"image/png"}`},
+		{"Resource", ContentItem{Type: "resource", Resource: &ResourceContent{URI: "mysql://result/row/1/f", MimeType: "application/pdf", Blob: "AAE="}},
+			`{"type":"resource","resource":{"uri":"mysql://result/row/1/f","mimeType":"application/pdf","blob":"AAE="}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.item)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(b) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"regexp"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// Config-defined tools.
+//
+// CUSTOM_TOOLS_FILE points at a JSON file that declares extra tools:
+//
+//	{"tools": [{
+//	  "name": "get_customer_balance",
+//	  "description": "Current balance for one customer",
+//	  "input_schema": {"type": "object",
+//	    "properties": {"customer_id": {"type": "integer", "minimum": 1}},
+//	    "required": ["customer_id"], "additionalProperties": false},
+//	  "sql": "SELECT balance FROM accounts WHERE customer_id = :customer_id",
+//	  "mode": "read"
+//	}]}
+//
+// Arguments are validated against input_schema (see schema.go), then bound
+// to the :name placeholders as prepared-statement parameters. Read tools run
+// through the query path and write tools through Execute, so ValidateQuery,
+// the read-only transaction and the MAX_SAFE_ROWS gate all still apply.
+
+// CustomTool is one tool declared in CUSTOM_TOOLS_FILE.
+type CustomTool struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+	SQL         string                 `json:"sql"`
+	Mode        string                 `json:"mode,omitempty"`
+}
+
+// CustomTools are the tools loaded from CUSTOM_TOOLS_FILE, in file order.
+var CustomTools []*CustomTool
+
+var customToolName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// loadCustomTools loads CUSTOM_TOOLS_FILE at startup. Invalid tools are
+// logged and skipped.
+func loadCustomTools() {
+	path := os.Getenv("CUSTOM_TOOLS_FILE")
+	if path == "" {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("WARNING: cannot read CUSTOM_TOOLS_FILE: %v", err)
+		return
+	}
+	tools, errs := parseCustomTools(data)
+	for _, err := range errs {
+		log.Printf("WARNING: custom tool skipped: %v", err)
+	}
+	CustomTools = tools
+	log.Printf("Loaded %d custom tools from %s", len(tools), path)
+}
+
+// parseCustomTools decodes and checks a tools file, returning the valid
+// tools and one error per rejected tool.
+func parseCustomTools(data []byte) ([]*CustomTool, []error) {
+	var file struct {
+		Tools []*CustomTool `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, []error{fmt.Errorf("invalid JSON: %w", err)}
+	}
+
+	taken := map[string]bool{"run_saved_query": true}
+	for _, t := range builtinTools() {
+		taken[t.Name] = true
+	}
+
+	var tools []*CustomTool
+	var errs []error
+	for i, t := range file.Tools {
+		if err := t.check(taken); err != nil {
+			errs = append(errs, fmt.Errorf("tool #%d (%s): %w", i+1, t.Name, err))
+			continue
+		}
+		taken[t.Name] = true
+		tools = append(tools, t)
+	}
+	return tools, errs
+}
+
+// check validates a tool definition and fills in defaults.
+func (t *CustomTool) check(taken map[string]bool) error {
+	if !customToolName.MatchString(t.Name) {
+		return fmt.Errorf("name must match %s", customToolName)
+	}
+	if taken[t.Name] {
+		return fmt.Errorf("name %q is already in use", t.Name)
+	}
+	if t.Description == "" || t.SQL == "" {
+		return fmt.Errorf("description and sql are required")
+	}
+
+	if t.Mode == "" {
+		t.Mode = "read"
+	}
+	stripped := mysql.StripComments(t.SQL)
+	switch t.Mode {
+	case "read":
+		if !isReadOnlyQuery(stripped) {
+			return fmt.Errorf("mode read requires a SELECT, WITH or SHOW statement")
+		}
+	case "write":
+		if !isWriteQuery(stripped) {
+			return fmt.Errorf("mode write requires an INSERT, UPDATE or DELETE statement")
+		}
+	default:
+		return fmt.Errorf("mode must be read or write, got %q", t.Mode)
+	}
+
+	if t.InputSchema == nil {
+		t.InputSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	if typ, ok := t.InputSchema["type"]; ok && typ != "object" {
+		return fmt.Errorf("input_schema must be of type object")
+	}
+	if err := checkSchema(t.InputSchema, "input_schema"); err != nil {
+		return err
+	}
+
+	used, err := mysql.NamedPlaceholders(t.SQL)
+	if err != nil {
+		return err
+	}
+	props, _ := t.InputSchema["properties"].(map[string]interface{})
+	for name := range used {
+		prop, ok := props[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("placeholder :%s has no matching input_schema property", name)
+		}
+		switch prop["type"] {
+		case "string", "integer", "number", "boolean":
+		default:
+			return fmt.Errorf("property %s bound to :%s must have a scalar type", name, name)
+		}
+	}
+	if _, ok := props["confirm_key"]; ok && t.Mode == "write" {
+		return fmt.Errorf("confirm_key is reserved for write tools")
+	}
+	return nil
+}
+
+// definition returns the tools/list entry. Write tools get a confirm_key
+// argument for the MAX_SAFE_ROWS gate.
+func (t *CustomTool) definition() ToolDefinition {
+	schema := t.InputSchema
+	if t.Mode == "write" {
+		schema = make(map[string]interface{}, len(t.InputSchema))
+		for k, v := range t.InputSchema {
+			schema[k] = v
+		}
+		props := map[string]interface{}{
+			"confirm_key": map[string]interface{}{
+				"type":        "string",
+				"description": "Safety confirmation key for large operations",
+			},
+		}
+		if declared, ok := t.InputSchema["properties"].(map[string]interface{}); ok {
+			for k, v := range declared {
+				props[k] = v
+			}
+		}
+		schema["properties"] = props
+	}
+	return ToolDefinition{
+		Name:        t.Name,
+		Title:       t.Title,
+		Description: t.Description,
+		InputSchema: schema,
+	}
+}
+
+// findCustomTool returns the custom tool with the given name, or nil.
+func findCustomTool(name string) *CustomTool {
+	for _, t := range CustomTools {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// handleCustomTool validates the arguments and runs the tool's SQL
+func handleCustomTool(client *mysql.Client, tool *CustomTool, args map[string]interface{}) ([]ContentItem, error) {
+	input := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		input[k] = v
+	}
+	confirmKey := ""
+	if tool.Mode == "write" {
+		confirmKey = getOptionalString(input, "confirm_key", "")
+		delete(input, "confirm_key")
+	}
+
+	props, _ := tool.InputSchema["properties"].(map[string]interface{})
+	for name, p := range props {
+		if def, ok := p.(map[string]interface{})["default"]; ok {
+			if _, present := input[name]; !present {
+				input[name] = def
+			}
+		}
+	}
+	if err := validateSchema(tool.InputSchema, input, ""); err != nil {
+		return nil, fmt.Errorf("invalid arguments for %s: %w", tool.Name, err)
+	}
+
+	// Absent optional arguments bind as NULL.
+	used, _ := mysql.NamedPlaceholders(tool.SQL)
+	values := make(map[string]interface{}, len(used))
+	for name := range used {
+		v := input[name]
+		if n, ok := v.(float64); ok && props[name].(map[string]interface{})["type"] == "integer" && math.Abs(n) < 1<<53 {
+			v = int64(n)
+		}
+		values[name] = v
+	}
+	query, queryArgs, err := mysql.BindNamedParams(tool.SQL, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if tool.Mode == "write" {
+		result, err := client.ExecuteWithArgs(query, confirmKey, queryArgs...)
+		if err != nil {
+			return nil, err
+		}
+		return textContent(formatWriteResult(result), nil)
+	}
+
+	result, err := client.QueryWithOptions(query, mysql.QueryOptions{Args: queryArgs, Cache: mysql.CacheQuery})
+	if err != nil {
+		return nil, err
+	}
+	return resultContent(result, "")
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+const customToolsJSON = `{"tools": [
+  {
+    "name": "get_customer_balance",
+    "description": "Current balance for one customer",
+    "input_schema": {
+      "type": "object",
+      "properties": {
+        "customer_id": {"type": "integer", "minimum": 1},
+        "currency": {"type": "string", "enum": ["EUR", "USD"], "default": "EUR"}
+      },
+      "required": ["customer_id"],
+      "additionalProperties": false
+    },
+    "sql": "SELECT balance FROM accounts WHERE customer_id = :customer_id AND currency = :currency"
+  },
+  {
+    "name": "close_ticket",
+    "description": "Close a support ticket",
+    "mode": "write",
+    "input_schema": {"type": "object", "properties": {"id": {"type": "integer"}}, "required": ["id"]},
+    "sql": "UPDATE tickets SET status = 'closed' WHERE id = :id"
+  },
+  {"name": "query", "description": "shadows a builtin", "sql": "SELECT 1"},
+  {"name": "sneaky", "description": "write in read mode", "sql": "DELETE FROM t"},
+  {"name": "loose", "description": "undeclared placeholder", "sql": "SELECT * FROM t WHERE id = :id"},
+  {"name": "fancy", "description": "unsupported keyword", "sql": "SELECT 1",
+   "input_schema": {"type": "object", "properties": {"x": {"type": "string", "format": "email"}}}}
+]}`
+
+// TestParseCustomTools verifies valid tools load and invalid ones are rejected
+func TestParseCustomTools(t *testing.T) {
+	tools, errs := parseCustomTools([]byte(customToolsJSON))
+
+	if len(tools) != 2 || tools[0].Name != "get_customer_balance" || tools[1].Name != "close_ticket" {
+		t.Fatalf("Expected the two valid tools, got %d", len(tools))
+	}
+	if tools[0].Mode != "read" {
+		t.Errorf("Expected default mode read, got %q", tools[0].Mode)
+	}
+
+	expected := []string{"already in use", "mode read", "no matching input_schema property", "unsupported schema keyword"}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), errs)
+	}
+	for i, e := range expected {
+		if !strings.Contains(errs[i].Error(), e) {
+			t.Errorf("Expected error containing %q, got %v", e, errs[i])
+		}
+	}
+}
+
+// TestCustomToolsRegistered verifies custom tools appear in tools/list
+func TestCustomToolsRegistered(t *testing.T) {
+	CustomTools, _ = parseCustomTools([]byte(customToolsJSON))
+	defer func() { CustomTools = nil }()
+
+	defs := map[string]ToolDefinition{}
+	for _, d := range getToolsList() {
+		defs[d.Name] = d
+	}
+	if _, ok := defs["get_customer_balance"]; !ok {
+		t.Fatalf("Expected get_customer_balance in tools/list")
+	}
+	props := defs["close_ticket"].InputSchema["properties"].(map[string]interface{})
+	if _, ok := props["confirm_key"]; !ok {
+		t.Errorf("Expected write tools to accept confirm_key")
+	}
+	if _, ok := findCustomTool("get_customer_balance").InputSchema["properties"].(map[string]interface{})["confirm_key"]; ok {
+		t.Errorf("Adding confirm_key must not modify the declared schema")
+	}
+}
+
+// TestCustomToolArgumentValidation verifies bad arguments are rejected before any SQL runs
+func TestCustomToolArgumentValidation(t *testing.T) {
+	tools, _ := parseCustomTools([]byte(customToolsJSON))
+	client := mysql.NewClient()
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		errPart string
+	}{
+		{"Missing required", map[string]interface{}{}, "missing required argument customer_id"},
+		{"Wrong type", map[string]interface{}{"customer_id": "1"}, "customer_id must be integer"},
+		{"Fractional integer", map[string]interface{}{"customer_id": 1.5}, "customer_id must be integer"},
+		{"Below minimum", map[string]interface{}{"customer_id": float64(0)}, "customer_id must be >= 1"},
+		{"Not in enum", map[string]interface{}{"customer_id": float64(1), "currency": "GBP"}, "currency must be one of"},
+		{"Unknown argument", map[string]interface{}{"customer_id": float64(1), "sql": "DROP TABLE x"}, "unknown argument sql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := handleCustomTool(client, tools[0], tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
+// TestValidateSchemaNested verifies arrays, patterns and nested objects
+func TestValidateSchemaNested(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"tags": map[string]interface{}{
+				"type":     "array",
+				"maxItems": float64(2),
+				"items":    map[string]interface{}{"type": "string", "pattern": "^[a-z]+$"},
+			},
+		},
+	}
+	if err := checkSchema(schema, "input_schema"); err != nil {
+		t.Fatalf("Expected schema to be supported, got %v", err)
+	}
+
+	tests := []struct {
+		value interface{}
+		ok    bool
+	}{
+		{map[string]interface{}{"tags": []interface{}{"a", "b"}}, true},
+		{map[string]interface{}{"tags": []interface{}{"a", "B"}}, false},
+		{map[string]interface{}{"tags": []interface{}{"a", "b", "c"}}, false},
+		{map[string]interface{}{"tags": "a"}, false},
+	}
+	for _, tt := range tests {
+		if err := validateSchema(schema, tt.value, ""); (err == nil) != tt.ok {
+			t.Errorf("%v: expected ok=%v, got %v", tt.value, tt.ok, err)
+		}
+	}
+}
+
+// TestCheckSchemaEnum verifies enum entries must be scalars, and an array argument against a scalar enum is refused without panicking
+func TestCheckSchemaEnum(t *testing.T) {
+	tests := []struct {
+		name    string
+		enum    interface{}
+		errPart string
+	}{
+		{"Scalars", []interface{}{"a", float64(1), true, nil}, ""},
+		{"Not an array", "a", "enum must be an array"},
+		{"Array entry", []interface{}{"a", []interface{}{"b"}}, "enum entries must be strings, numbers, booleans or null, got array"},
+		{"Object entry", []interface{}{map[string]interface{}{"k": "v"}}, "got object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSchema(map[string]interface{}{"enum": tt.enum}, "input_schema")
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("checkSchema() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("checkSchema() = %v, want %q", err, tt.errPart)
+			}
+		})
+	}
+
+	schema := map[string]interface{}{"enum": []interface{}{"a", "b"}}
+	for _, value := range []interface{}{[]interface{}{"a"}, map[string]interface{}{"a": true}} {
+		if err := validateSchema(schema, value, "tag"); err == nil || !strings.Contains(err.Error(), "tag must be one of") {
+			t.Errorf("validateSchema(%v) = %v, want an enum error", value, err)
+		}
+	}
+}
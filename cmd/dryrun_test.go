@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestBeforeImageQuery verifies the SELECT a dry run uses to read the rows a write will touch
+func TestBeforeImageQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected string
+		skip     int
+		ok       bool
+	}{
+		{"Update", "UPDATE users SET status = 'x' WHERE id < 5",
+			"SELECT * FROM (SELECT * FROM users WHERE id < 5) AS dry_run LIMIT 10", 0, true},
+		{"Update with alias and params", "UPDATE users u SET u.name = ?, u.age = ? WHERE u.id = ?;",
+			"SELECT * FROM (SELECT * FROM users u WHERE u.id = ?) AS dry_run LIMIT 10", 2, true},
+		{"Update order and limit", "UPDATE LOW_PRIORITY jobs SET state = 'queued' ORDER BY id LIMIT 3",
+			"SELECT * FROM (SELECT * FROM jobs ORDER BY id LIMIT 3) AS dry_run LIMIT 10", 0, true},
+		{"Update without where", "UPDATE t SET a = 1", "SELECT * FROM (SELECT * FROM t) AS dry_run LIMIT 10", 0, true},
+		{"Delete", "DELETE FROM `orders` WHERE created_at < '2020-01-01'",
+			"SELECT * FROM (SELECT * FROM `orders` WHERE created_at < '2020-01-01') AS dry_run LIMIT 10", 0, true},
+		{"Delete with subquery", "DELETE FROM s.logs WHERE id IN (SELECT id FROM old, older)",
+			"SELECT * FROM (SELECT * FROM s.logs WHERE id IN (SELECT id FROM old, older)) AS dry_run LIMIT 10", 0, true},
+		{"Multi-table update", "UPDATE a JOIN b ON a.id = b.id SET a.x = b.x", "", 0, false},
+		{"Comma update", "UPDATE a, b SET a.x = b.x WHERE a.id = b.id", "", 0, false},
+		{"Multi-table delete", "DELETE a FROM a JOIN b ON a.id = b.id", "", 0, false},
+		{"Delete using", "DELETE FROM a USING a JOIN b", "", 0, false},
+		{"Insert", "INSERT INTO t (a) VALUES (1)", "", 0, false},
+		{"CTE", "WITH x AS (SELECT 1) UPDATE t SET a = 1", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sel, skip, ok := mysql.BeforeImageQuery(tt.sql)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v (%q)", tt.ok, ok, sel)
+			}
+			if sel != tt.expected || skip != tt.skip {
+				t.Errorf("Expected %q skipping %d, got %q skipping %d", tt.expected, tt.skip, sel, skip)
+			}
+		})
+	}
+}
+
+// TestFormatDryRunResult verifies the preview shows the before/after images and the outcome
+func TestFormatDryRunResult(t *testing.T) {
+	out := formatDryRunResult(&mysql.DryRunResult{
+		Verb:     "UPDATE",
+		Table:    "users",
+		Affected: 150,
+		Columns:  []string{"id", "status"},
+		Changes: []mysql.RowChange{
+			{Before: []interface{}{int64(1), "active"}, After: []interface{}{int64(1), "archived"}, Changed: []string{"status"}},
+		},
+		Matched:      1,
+		NeedsConfirm: true,
+	})
+	for _, want := range []string{
+		"Dry run of UPDATE on users: 150 rows affected. Rolled back",
+		"needs confirm_key",
+		"before: 1\tactive\nafter:  1\tarchived\nchanged: status",
+	} {
+		if !contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	del := formatDryRunResult(&mysql.DryRunResult{Verb: "DELETE", Table: "t", Affected: 1, Columns: []string{"id"},
+		Changes: []mysql.RowChange{{Before: []interface{}{int64(7)}}}})
+	if !contains(del, "deleted: 7") || contains(del, "confirm_key") {
+		t.Errorf("Unexpected delete preview:\n%s", del)
+	}
+}
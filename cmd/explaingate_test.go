@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestEvaluatePlan verifies the row estimate and the full-scan rule
+func TestEvaluatePlan(t *testing.T) {
+	gate := &mysql.ExplainGateConfig{Mode: mysql.ExplainGateReject, MaxRows: 1000000, FullScanRows: 100000}
+
+	tests := []struct {
+		name       string
+		steps      []mysql.PlanStep
+		estimate   float64
+		violations []string
+	}{
+		{
+			"Indexed lookup",
+			[]mysql.PlanStep{{ID: "1", Table: "users", Type: "const", Key: "PRIMARY", Rows: 1}},
+			1, nil,
+		},
+		{
+			"Small full scan",
+			[]mysql.PlanStep{{ID: "1", Table: "countries", Type: "ALL", Rows: 250}},
+			250, nil,
+		},
+		{
+			"Large full scan",
+			[]mysql.PlanStep{{ID: "1", Table: "events", Type: "ALL", Rows: 500000}},
+			500000, []string{"full scan of events"},
+		},
+		{
+			"Join multiplies",
+			[]mysql.PlanStep{
+				{ID: "1", Table: "orders", Type: "range", Key: "idx_date", Rows: 5000},
+				{ID: "1", Table: "lines", Type: "ref", Key: "idx_order", Rows: 400},
+			},
+			2000000, []string{"estimated 2000000 rows examined"},
+		},
+		{
+			"Subqueries add",
+			[]mysql.PlanStep{
+				{ID: "1", Table: "a", Type: "ref", Rows: 10},
+				{ID: "2", SelectType: "SUBQUERY", Table: "b", Type: "index", Rows: 90},
+			},
+			100, nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost := gate.EvaluatePlan(tt.steps)
+			if cost.EstimatedRows != tt.estimate {
+				t.Errorf("Expected estimate %v, got %v", tt.estimate, cost.EstimatedRows)
+			}
+			if len(cost.Violations) != len(tt.violations) {
+				t.Fatalf("Expected violations %v, got %v", tt.violations, cost.Violations)
+			}
+			for i, v := range tt.violations {
+				if !strings.Contains(cost.Violations[i], v) {
+					t.Errorf("Expected violation containing %q, got %q", v, cost.Violations[i])
+				}
+			}
+		})
+	}
+}
+
+// TestQueryCostErrorIncludesPlan verifies the rejection carries the plan summary
+func TestQueryCostErrorIncludesPlan(t *testing.T) {
+	gate := &mysql.ExplainGateConfig{Mode: mysql.ExplainGateReject, FullScanRows: 1000}
+	cost := gate.EvaluatePlan([]mysql.PlanStep{{ID: "1", SelectType: "SIMPLE", Table: "events", Type: "ALL", Rows: 50000, Extra: "Using where"}})
+
+	msg := (&mysql.QueryCostError{Plan: cost}).Error()
+	for _, want := range []string{"rejected by EXPLAIN cost gate", "full scan of events", "table=events type=ALL key=- rows=50000 (Using where)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Expected %q in %q", want, msg)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// fakeRowsDriver is a minimal database/sql driver that answers every query
+// with a synthetic result set, so the row pipeline can be tested and
+// benchmarked without a MySQL server. The query text is the row count.
+//
+// It also supports transactions and writes, enough for a Client to pin
+// connections and run statements on them; fakeTxLog records each
+// transaction's read-only flag and how it ended. A write to a table named
+// "deadlock" fails with ER_LOCK_DEADLOCK, one to "bad_syntax" with
+// ER_PARSE_ERROR, and one to "blocking" waits for fakeExecGate. KILL
+// statements are recorded in fakeExecGate. Every other write affects one
+// row. Routine lookups in INFORMATION_SCHEMA describe fakeProc, and CALL
+// runs it against the session variables in fakeProc.vars.
+type fakeRowsDriver struct{}
+
+// fakeProc is the stored procedure INFORMATION_SCHEMA lookups return:
+// its SQL_DATA_ACCESS and its parameters as (name, mode, data type) rows.
+// A CALL returns one result set per entry of sets, holding that many
+// rows, and then stores out in the session variables. SET @v and SELECT
+// @v write and read vars.
+var fakeProc struct {
+	access string
+	params [][]driver.Value
+	sets   []int
+	out    map[string]driver.Value
+	vars   map[string]driver.Value
+}
+
+// fakeExecGate holds writes to "blocking": the write sends on entered,
+// then waits for release, or fails with ER_QUERY_INTERRUPTED when a KILL
+// arrives first. kills lists the KILL statements received.
+var fakeExecGate struct {
+	entered chan struct{}
+	release chan struct{}
+	kill    chan struct{}
+	kills   []string
+}
+
+func init() {
+	sql.Register("fakerows", fakeRowsDriver{})
+}
+
+func (fakeRowsDriver) Open(string) (driver.Conn, error) { return fakeRowsConn{}, nil }
+
+type fakeRowsConn struct{}
+
+func (fakeRowsConn) Prepare(query string) (driver.Stmt, error) { return fakeRowsStmt{query}, nil }
+func (fakeRowsConn) Close() error                              { return nil }
+
+func (c fakeRowsConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (fakeRowsConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	tx := &fakeTx{readOnly: opts.ReadOnly}
+	fakeTxLog.Lock()
+	fakeTxLog.txs = append(fakeTxLog.txs, tx)
+	fakeTxLog.Unlock()
+	return tx, nil
+}
+
+// fakeTxLog records every transaction begun, in order.
+var fakeTxLog struct {
+	sync.Mutex
+	txs []*fakeTx
+}
+
+// fakeTx records how a transaction was opened and how it ended.
+type fakeTx struct {
+	readOnly bool
+	end      string // "commit" or "rollback" once finished
+}
+
+func (t *fakeTx) Commit() error   { return t.finish("commit") }
+func (t *fakeTx) Rollback() error { return t.finish("rollback") }
+
+func (t *fakeTx) finish(end string) error {
+	fakeTxLog.Lock()
+	defer fakeTxLog.Unlock()
+	t.end = end
+	return nil
+}
+
+// fakeTxs returns the transactions begun since the last call.
+func fakeTxs() []fakeTx {
+	fakeTxLog.Lock()
+	defer fakeTxLog.Unlock()
+	txs := make([]fakeTx, len(fakeTxLog.txs))
+	for i, tx := range fakeTxLog.txs {
+		txs[i] = *tx
+	}
+	fakeTxLog.txs = nil
+	return txs
+}
+
+type fakeRowsStmt struct{ query string }
+
+func (fakeRowsStmt) Close() error  { return nil }
+func (fakeRowsStmt) NumInput() int { return -1 }
+func (s fakeRowsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(s.query, "SET @"):
+		// SET @v = ? or SET @v = NULL
+		f := strings.Fields(s.query)
+		var v driver.Value
+		if f[3] == "?" {
+			v = args[0]
+		}
+		fakeProc.vars[f[1]] = v
+	case strings.Contains(s.query, "deadlock"):
+		return nil, &mysqldriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+	case strings.Contains(s.query, "bad_syntax"):
+		return nil, &mysqldriver.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	case strings.Contains(s.query, "blocking"):
+		fakeExecGate.entered <- struct{}{}
+		select {
+		case <-fakeExecGate.release:
+		case <-fakeExecGate.kill:
+			return nil, &mysqldriver.MySQLError{Number: 1317, Message: "Query execution was interrupted"}
+		}
+	case strings.HasPrefix(s.query, "KILL"):
+		fakeExecGate.kills = append(fakeExecGate.kills, s.query)
+		select {
+		case fakeExecGate.kill <- struct{}{}:
+		default:
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeRowsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	switch {
+	case s.query == "SELECT CONNECTION_ID()":
+		return &fakeIDRows{}, nil
+	case strings.Contains(s.query, "INFORMATION_SCHEMA.ROUTINES"):
+		return &fakeTableRows{
+			columns: []string{"ROUTINE_NAME", "SQL_DATA_ACCESS"},
+			rows:    [][]driver.Value{{args[0], fakeProc.access}},
+		}, nil
+	case strings.Contains(s.query, "INFORMATION_SCHEMA.PARAMETERS"):
+		return &fakeTableRows{
+			columns: []string{"PARAMETER_NAME", "PARAMETER_MODE", "DATA_TYPE"},
+			rows:    fakeProc.params,
+		}, nil
+	case strings.HasPrefix(s.query, "CALL "):
+		for name, v := range fakeProc.out {
+			fakeProc.vars[name] = v
+		}
+		return &fakeResultSets{sets: fakeProc.sets, cur: &fakeRows{total: fakeProc.sets[0]}}, nil
+	case strings.HasPrefix(s.query, "SELECT @"):
+		names := strings.Split(strings.TrimPrefix(s.query, "SELECT "), ", ")
+		row := make([]driver.Value, len(names))
+		for i, name := range names {
+			row[i] = fakeProc.vars[name]
+		}
+		return &fakeTableRows{columns: names, rows: [][]driver.Value{row}}, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s.query))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{total: n}, nil
+}
+
+type fakeRows struct{ total, next int }
+
+func (*fakeRows) Columns() []string {
+	return []string{"id", "name", "email", "created_at", "amount"}
+}
+
+func (*fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= r.total {
+		return io.EOF
+	}
+	dest[0] = int64(r.next)
+	dest[1] = []byte("Customer " + strconv.Itoa(r.next))
+	dest[2] = []byte("customer@example.com")
+	dest[3] = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	dest[4] = []byte("1234.56")
+	r.next++
+	return nil
+}
+
+// fakeResultSets answers a CALL with one fakeRows result set per entry of
+// sets.
+type fakeResultSets struct {
+	sets []int
+	next int
+	cur  *fakeRows
+}
+
+func (r *fakeResultSets) Columns() []string              { return r.cur.Columns() }
+func (*fakeResultSets) Close() error                     { return nil }
+func (r *fakeResultSets) Next(dest []driver.Value) error { return r.cur.Next(dest) }
+func (r *fakeResultSets) HasNextResultSet() bool         { return r.next+1 < len(r.sets) }
+
+func (r *fakeResultSets) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.next++
+	r.cur = &fakeRows{total: r.sets[r.next]}
+	return nil
+}
+
+// fakeIDRows answers SELECT CONNECTION_ID().
+type fakeIDRows struct{ done bool }
+
+func (*fakeIDRows) Columns() []string { return []string{"CONNECTION_ID()"} }
+func (*fakeIDRows) Close() error      { return nil }
+
+func (r *fakeIDRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0] = int64(42)
+	r.done = true
+	return nil
+}
+
+// fakeTableRows answers with fixed rows.
+type fakeTableRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeTableRows) Columns() []string { return r.columns }
+func (*fakeTableRows) Close() error        { return nil }
+
+func (r *fakeTableRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// openFakeRows opens a *sql.DB backed by fakeRowsDriver.
+func openFakeRows(tb testing.TB) *sql.DB {
+	tb.Helper()
+	db, err := sql.Open("fakerows", "")
+	if err != nil {
+		tb.Fatalf("open fake driver: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	return db
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	mysql "mcp-gp-mysql/internal"
 )
@@ -15,6 +16,12 @@ var CompactMode = false
 type QueryResult = mysql.QueryResult
 type TableInfo = mysql.TableInfo
 type ColumnInfo = mysql.ColumnInfo
+type CacheHit = mysql.CacheHit
+type ProcedureResult = mysql.ProcedureResult
+type ResultSummary = mysql.ResultSummary
+type DryRunResult = mysql.DryRunResult
+type BatchResult = mysql.BatchResult
+type PendingOperation = mysql.PendingOperation
 
 // ============================================================================
 // Query Result Formatting
@@ -30,12 +37,12 @@ func formatQueryResultStructured(result *QueryResult) string {
 
 func formatQueryResultCompact(result *QueryResult) string {
 	if result.RowCount == 0 {
-		return "0 rows"
+		return "0 rows" + costNote(result) + cacheNote(result.Cache) + maskNote(result.Masked)
 	}
 
 	// Single row: show values inline
 	if result.RowCount == 1 {
-		return formatRowCompact(result.Columns, result.Rows[0])
+		return formatRowCompact(result.Rows[0]) + limitNote(result) + costNote(result) + cacheNote(result.Cache) + maskNote(result.Masked)
 	}
 
 	// Multiple rows: tabulated compact
@@ -52,19 +59,24 @@ func formatQueryResultCompact(result *QueryResult) string {
 		limit = 5
 	}
 	for i := 0; i < limit; i++ {
-		sb.WriteString(formatRowCompact(result.Columns, result.Rows[i]))
+		sb.WriteString(formatRowCompact(result.Rows[i]))
 		sb.WriteString("\n")
 	}
 	if result.RowCount > 5 {
 		sb.WriteString(fmt.Sprintf("... +%d more rows", result.RowCount-5))
 	}
+	sb.WriteString(truncationNote(result))
+	sb.WriteString(limitNote(result))
+	sb.WriteString(costNote(result))
+	sb.WriteString(cacheNote(result.Cache))
+	sb.WriteString(maskNote(result.Masked))
 
 	return sb.String()
 }
 
 func formatQueryResultVerbose(result *QueryResult) string {
 	if result.RowCount == 0 {
-		return "Query returned 0 rows."
+		return "Query returned 0 rows." + costNote(result) + cacheNote(result.Cache) + maskNote(result.Masked)
 	}
 
 	var sb strings.Builder
@@ -83,20 +95,92 @@ func formatQueryResultVerbose(result *QueryResult) string {
 		limit = 20
 	}
 	for i := 0; i < limit; i++ {
-		sb.WriteString(formatRowCompact(result.Columns, result.Rows[i]))
+		sb.WriteString(formatRowCompact(result.Rows[i]))
 		sb.WriteString("\n")
 	}
 	if result.RowCount > 20 {
 		sb.WriteString(fmt.Sprintf("... +%d more rows", result.RowCount-20))
 	}
+	sb.WriteString(truncationNote(result))
+	sb.WriteString(limitNote(result))
+	sb.WriteString(costNote(result))
+	sb.WriteString(cacheNote(result.Cache))
+	sb.WriteString(maskNote(result.Masked))
 
 	return sb.String()
 }
 
-func formatRowCompact(columns []string, row map[string]interface{}) string {
-	values := make([]string, len(columns))
-	for i, col := range columns {
-		if v, ok := row[col]; ok && v != nil {
+// truncationNote explains a result cut short by MAX_RESULT_ROWS/MAX_RESULT_BYTES.
+func truncationNote(result *QueryResult) string {
+	if !result.Truncated {
+		return ""
+	}
+	if CompactMode {
+		return "\ntruncated: true"
+	}
+	return fmt.Sprintf("\ntruncated: true (stopped reading after %d rows; MAX_RESULT_ROWS/MAX_RESULT_BYTES reached). Add a WHERE or LIMIT to narrow the result.", result.RowCount)
+}
+
+// limitNote reports the automatic LIMIT (AUTO_LIMIT) added to a query. A
+// truncated result stopped before the probe row, so it has more rows too.
+func limitNote(result *QueryResult) string {
+	if result.LimitApplied == 0 {
+		return ""
+	}
+	hasMore := result.HasMore || result.Truncated
+	if CompactMode {
+		return fmt.Sprintf("\nlimit: %d, has_more: %v", result.LimitApplied, hasMore)
+	}
+	if hasMore {
+		return fmt.Sprintf("\nlimit: %d applied automatically; more rows exist. Add WHERE/LIMIT/OFFSET, or pass no_limit=true.", result.LimitApplied)
+	}
+	return fmt.Sprintf("\nlimit: %d applied automatically; no more rows.", result.LimitApplied)
+}
+
+// costNote carries the EXPLAIN_GATE=warn finding for an expensive query.
+func costNote(result *QueryResult) string {
+	if result.CostWarning == "" {
+		return ""
+	}
+	return "\nwarning: " + result.CostWarning
+}
+
+// cacheNote marks an answer served from the result cache (RESULT_CACHE).
+func cacheNote(hit *CacheHit) string {
+	if hit == nil {
+		return ""
+	}
+	if CompactMode {
+		return fmt.Sprintf("\ncached: %s", hit.Age.Round(time.Second))
+	}
+	return fmt.Sprintf("\n(%s; data may lag writes made outside this server)", hit)
+}
+
+// maskNote names the columns whose values MASKING_RULES replaced.
+func maskNote(masked []mysql.MaskedColumn) string {
+	if len(masked) == 0 {
+		return ""
+	}
+	parts := make([]string, len(masked))
+	for i, m := range masked {
+		if CompactMode {
+			parts[i] = m.Column + "=" + m.Strategy
+		} else {
+			parts[i] = fmt.Sprintf("%s (%s)", m.Column, m.Strategy)
+		}
+	}
+	if CompactMode {
+		return "\nmasked: " + strings.Join(parts, ", ")
+	}
+	return "\nmasked columns (MASKING_RULES): " + strings.Join(parts, ", ")
+}
+
+func formatRowCompact(row []interface{}) string {
+	values := make([]string, len(row))
+	for i, v := range row {
+		if b, ok := v.([]byte); ok {
+			values[i] = formatBinary(b)
+		} else if v != nil {
 			values[i] = fmt.Sprintf("%v", v)
 		} else {
 			values[i] = "NULL"
@@ -105,6 +189,74 @@ func formatRowCompact(columns []string, row map[string]interface{}) string {
 	return strings.Join(values, "\t")
 }
 
+// formatResultSummary formats the per-column statistics of summarize mode
+func formatResultSummary(s *ResultSummary) string {
+	var sb strings.Builder
+	if CompactMode {
+		sb.WriteString(fmt.Sprintf("%d rows, %d columns\n", s.RowCount, len(s.Columns)))
+	} else {
+		sb.WriteString(fmt.Sprintf("Summary of %d rows (%d columns):\n\n", s.RowCount, len(s.Columns)))
+	}
+
+	for _, c := range s.Columns {
+		distinct := fmt.Sprintf("%d", c.Distinct)
+		if !c.DistinctExact {
+			distinct = "~" + distinct
+		}
+		parts := []string{
+			fmt.Sprintf("count %d", c.Count),
+			fmt.Sprintf("nulls %d", c.Nulls),
+			fmt.Sprintf("distinct %s", distinct),
+		}
+		if c.Masked != "" {
+			parts = append(parts, "masked: "+c.Masked)
+		}
+		if c.Min != "" || c.Max != "" {
+			parts = append(parts, fmt.Sprintf("min %s", c.Min), fmt.Sprintf("max %s", c.Max))
+		}
+		if c.Kind == mysql.KindNumber && c.Count > 0 {
+			parts = append(parts, fmt.Sprintf("mean %.4g", c.Mean), fmt.Sprintf("stddev %.4g", c.Stddev))
+		}
+		if c.Kind == mysql.KindString && c.Count > 0 {
+			parts = append(parts, fmt.Sprintf("length %d-%d", c.MinLength, c.MaxLength))
+		}
+		if len(c.Top) > 0 {
+			top := make([]string, len(c.Top))
+			for i, v := range c.Top {
+				top[i] = fmt.Sprintf("%s (%d)", truncateValue(v.Value, 40), v.Count)
+			}
+			label := "top"
+			if c.TopApproximate {
+				label = "top (approx.)"
+			}
+			parts = append(parts, label+": "+strings.Join(top, ", "))
+		}
+
+		kind := c.Kind
+		if c.Type != "" {
+			kind = c.Type + ", " + kind
+		}
+		sb.WriteString(fmt.Sprintf("• %s (%s): %s\n", c.Name, kind, strings.Join(parts, "; ")))
+	}
+
+	if s.Truncated {
+		sb.WriteString(fmt.Sprintf("truncated: statistics cover the first %d rows (SUMMARY_MAX_ROWS).\n", s.RowCount))
+	}
+	if s.CostWarning != "" {
+		sb.WriteString("warning: " + s.CostWarning + "\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// truncateValue shortens a value for inline display.
+func truncateValue(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max]) + "…"
+}
+
 // ============================================================================
 // Table/View Listing Formatting
 // ============================================================================
@@ -241,6 +393,49 @@ func formatExecuteResult(affected int64) string {
 	return fmt.Sprintf("Rows affected: %d", affected)
 }
 
+// formatWriteResult formats a committed or pending write: the message,
+// then last insert ID, matched/changed rows, elapsed time and warnings
+func formatWriteResult(result *QueryResult) string {
+	w := result.Write
+	if w == nil {
+		return result.Message
+	}
+
+	var details []string
+	if w.Matched != nil {
+		details = append(details, fmt.Sprintf("matched %d, changed %d", *w.Matched, *w.Changed))
+	}
+	if w.LastInsertID != 0 {
+		details = append(details, fmt.Sprintf("last insert ID %d", w.LastInsertID))
+	}
+	details = append(details, fmt.Sprintf("%s elapsed", w.Elapsed.Round(time.Millisecond)))
+
+	var sb strings.Builder
+	sb.WriteString(result.Message)
+	if CompactMode {
+		sb.WriteString(" (" + strings.Join(details, ", ") + ")")
+	} else {
+		sb.WriteString("\nRows " + strings.Join(details, "; "))
+	}
+	if w.WarningCount > 0 {
+		sb.WriteString(fmt.Sprintf("\nwarnings: %d", w.WarningCount))
+		if w.WarningsWithheld {
+			sb.WriteString(" (messages withheld: MASKING_RULES)")
+		}
+		for _, warn := range w.Warnings {
+			if warn.Message == "" {
+				sb.WriteString(fmt.Sprintf("\n  %s %d", warn.Level, warn.Code))
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("\n  %s %d: %s", warn.Level, warn.Code, warn.Message))
+		}
+		if len(w.Warnings) < w.WarningCount {
+			sb.WriteString(fmt.Sprintf("\n  ... %d more not shown", w.WarningCount-len(w.Warnings)))
+		}
+	}
+	return sb.String()
+}
+
 // formatExecuteResultWithConfirm formats execute result with confirmation
 func formatExecuteResultWithConfirm(affected int64, confirmed bool) string {
 	status := "confirmed"
@@ -250,17 +445,141 @@ func formatExecuteResultWithConfirm(affected int64, confirmed bool) string {
 	return fmt.Sprintf("Rows affected: %d (%s)", affected, status)
 }
 
+// formatDryRunResult formats a rolled-back write: the affected rows and
+// the before/after images of the sampled rows
+func formatDryRunResult(r *DryRunResult) string {
+	var sb strings.Builder
+	target := r.Verb
+	if r.Table != "" {
+		target += " on " + r.Table
+	}
+	sb.WriteString(fmt.Sprintf("Dry run of %s: %d rows affected. Rolled back, nothing was changed.\n", target, r.Affected))
+	if r.NeedsConfirm {
+		sb.WriteString("The real run exceeds MAX_SAFE_ROWS and needs confirm_key.\n")
+	}
+
+	if len(r.Changes) > 0 {
+		sb.WriteString(fmt.Sprintf("\nSample of changed rows (up to %d):\n", mysql.DryRunSampleRows))
+		sb.WriteString(strings.Join(r.Columns, "\t"))
+		sb.WriteString("\n")
+		for _, c := range r.Changes {
+			switch {
+			case c.Before != nil && c.After != nil:
+				sb.WriteString("before: " + formatRowCompact(c.Before) + "\n")
+				sb.WriteString("after:  " + formatRowCompact(c.After) + "\n")
+				if len(c.Changed) > 0 {
+					sb.WriteString("changed: " + strings.Join(c.Changed, ", ") + "\n")
+				} else {
+					sb.WriteString("changed: (none)\n")
+				}
+			case c.Before != nil:
+				sb.WriteString("deleted: " + formatRowCompact(c.Before) + "\n")
+			default:
+				sb.WriteString("inserted: " + formatRowCompact(c.After) + "\n")
+			}
+		}
+	} else if r.Note == "" && r.Affected == 0 {
+		sb.WriteString("No rows matched.\n")
+	}
+
+	if r.Note != "" {
+		sb.WriteString("note: " + r.Note + "\n")
+	}
+	sb.WriteString(strings.TrimPrefix(maskNote(r.Masked), "\n"))
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// formatBatchResult formats a committed batch, one line per statement
+func formatBatchResult(result *BatchResult) string {
+	var sb strings.Builder
+	sb.WriteString(result.Message)
+	for i, s := range result.Steps {
+		if CompactMode {
+			sb.WriteString(fmt.Sprintf("\n%d. %d rows", i+1, s.Affected))
+		} else {
+			sb.WriteString(fmt.Sprintf("\n%d. %d rows: %s", i+1, s.Affected, truncateValue(strings.Join(strings.Fields(s.SQL), " "), 80)))
+		}
+	}
+	return sb.String()
+}
+
+// formatPendingOperation formats a write in the approval queue: the
+// request, its dry-run impact and, once decided, the outcome
+func formatPendingOperation(op *PendingOperation) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Operation %s: %s\n", op.ID, op.Status))
+	sb.WriteString(fmt.Sprintf("Requested %s by %s\n", op.RequestedAt.Format(time.RFC3339), op.Requester))
+	if op.Reason != "" {
+		sb.WriteString("Reason: " + op.Reason + "\n")
+	}
+	sb.WriteString("SQL: " + op.SQL + "\n")
+	if op.AllowFullTable {
+		sb.WriteString("allow_full_table: true\n")
+	}
+	switch {
+	case op.Impact != nil:
+		sb.WriteString("Impact: " + formatDryRunResult(op.Impact) + "\n")
+	case op.ImpactError != "":
+		sb.WriteString("Impact: unknown, the dry run failed: " + op.ImpactError + "\n")
+	}
+
+	if op.StartedAt != nil {
+		sb.WriteString("Started " + op.StartedAt.Format(time.RFC3339) + "\n")
+	}
+	if op.DecidedAt != nil {
+		sb.WriteString(fmt.Sprintf("Decided %s by %s\n", op.DecidedAt.Format(time.RFC3339), op.DecidedBy))
+	}
+	switch op.Status {
+	case mysql.ApprovalRunning:
+		if op.StaleAt != nil {
+			sb.WriteString(fmt.Sprintf("Still running; if the approving process is gone, reject %s after %s marks it failed\n", op.ID, op.StaleAt.Format(time.RFC3339)))
+		}
+	case mysql.ApprovalExecuted:
+		sb.WriteString("Result: " + op.Result + "\n")
+	case mysql.ApprovalFailed:
+		sb.WriteString("Error: " + op.Result + "\n")
+		if op.Note != "" {
+			sb.WriteString("Note: " + op.Note + "\n")
+		}
+	case mysql.ApprovalRejected:
+		if op.Note != "" {
+			sb.WriteString("Rejected: " + op.Note + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
 // ============================================================================
 // Database Info Formatting
 // ============================================================================
 
+// formatProcedureResult formats each result set of a CALL separately,
+// followed by OUT parameters and the outcome
+func formatProcedureResult(result *ProcedureResult) string {
+	var sb strings.Builder
+	for i, set := range result.ResultSets {
+		sb.WriteString(fmt.Sprintf("Result set %d:\n", i+1))
+		sb.WriteString(formatQueryResultStructured(set))
+		sb.WriteString("\n\n")
+	}
+	if len(result.OutParams) > 0 {
+		sb.WriteString("OUT parameters:\n")
+		for _, p := range result.OutParams {
+			sb.WriteString(fmt.Sprintf("• %s = %s\n", p.Name, formatRowCompact([]interface{}{p.Value})))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(result.Message)
+	return sb.String()
+}
+
 // formatDatabaseInfo formats database connection information
 func formatDatabaseInfo(result *QueryResult) string {
 	if result.RowCount == 0 || len(result.Rows) == 0 {
 		return "Could not retrieve database information."
 	}
 
-	row := result.Rows[0]
+	row := result.RowMap(0)
 
 	if CompactMode {
 		version := getMapValue(row, "version")
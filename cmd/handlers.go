@@ -3,8 +3,13 @@ package main
 import (
 	"log"
 	mysql "mcp-gp-mysql/internal"
+	"strings"
 )
 
+// mcpClientName is the client's clientInfo from initialize, recorded as
+// the requester of queued writes.
+var mcpClientName string
+
 func handleMessage(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 	log.Printf("Handling method: %s", msg.Method)
 
@@ -18,6 +23,11 @@ func handleMessage(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 			if v, ok := params["protocolVersion"].(string); ok && v != "" {
 				clientVersion = v
 			}
+			if info, ok := params["clientInfo"].(map[string]interface{}); ok {
+				name, _ := info["name"].(string)
+				version, _ := info["version"].(string)
+				mcpClientName = strings.TrimSpace(name + " " + version)
+			}
 		}
 
 		// MCP spec: Server MUST respond with the same version if supported,
@@ -43,8 +53,13 @@ func handleMessage(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 					"version": Version,
 				},
 				"instructions": "MySQL/MariaDB MCP server. Available tools:\n" +
-					"- query: Execute read-only SELECT, WITH (CTE), and SHOW queries\n" +
-					"- execute: Run INSERT/UPDATE/DELETE statements inside a transaction (operations affecting more than MAX_SAFE_ROWS rows require confirm_key; without it the changes are rolled back)\n" +
+					"- query: Execute read-only SELECT, WITH (CTE), and SHOW queries (run in a READ ONLY transaction, always rolled back; a SELECT without LIMIT gets one automatically, pass no_limit=true to skip it; PNG/JPEG/GIF BLOBs are returned as image content, binary_column returns a binary column as base64 resources; summarize=true returns per-column statistics over the full result instead of rows)\n" +
+					"- execute: Run INSERT/UPDATE/DELETE statements inside a transaction (operations affecting more than MAX_SAFE_ROWS rows require confirm_key; without it the changes are rolled back, and if the error includes a one-time ct_ token, repeat the identical call with it as confirm_key; dry_run=true previews affected rows and before/after rows, then rolls back; the result lists server warnings such as truncation, matched vs changed rows for UPDATE, and the last insert ID; writes to MyISAM or other non-transactional tables cannot be rolled back and may need confirm_key before they run; if REQUIRE_WHERE rejects an UPDATE/DELETE, add a WHERE that selects the rows, and only when the user confirms that every row must change pass allow_full_table=true with confirm_key; in approval mode the write is queued for a human instead of run, pass a short reason and poll operation_status with the returned ap_ ID)\n" +
+					"- execute_batch: Run several INSERT/UPDATE/DELETE statements (each with optional params) atomically in one transaction; the first failure rolls back all of them and MAX_SAFE_ROWS applies to the total\n" +
+					"- bulk_insert: Insert many rows into one table from a JSON rows array or inline CSV (on_duplicate: error, ignore or update); never build large INSERT strings by hand, and LOAD DATA is not available\n" +
+					"- begin_transaction / commit / rollback: Open a transaction that spans several calls; pass the returned handle as transaction to query and execute to see uncommitted writes, then commit (MAX_SAFE_ROWS checked on the total) or roll back. Idle transactions are rolled back automatically\n" +
+					"- revert_operation: Undo a committed execute by the operation_id it returned (only when UNDO_JOURNAL_DIR is configured); refused if the rows changed since\n" +
+					"- operation_status: Status and result of a write queued for human approval (only when APPROVAL_QUEUE_FILE is configured); pending writes have not run yet\n" +
 					"- tables: List all tables with metadata (type, engine, row count)\n" +
 					"- describe: Show table structure (columns, types, keys, constraints)\n" +
 					"- views: List all database views\n" +
@@ -52,15 +67,23 @@ func handleMessage(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 					"- explain: Get EXPLAIN execution plan (SELECT queries only)\n" +
 					"- count: Count rows in a table\n" +
 					"- sample: Get sample rows from a table (default 10, max 100)\n" +
-					"- database_info: Get server version, user, hostname, port, and database name\n\n" +
+					"- database_info: Get server version, user, hostname, port, and database name\n" +
+					"- call_procedure: Call a stored procedure with typed arguments; returns all result sets and OUT parameters\n" +
+					"- run_saved_query: Run a vetted saved query by name (only when SAVED_QUERIES_DIR is configured)\n" +
+					"Operators may add domain-specific tools; see tools/list for the full set.\n\n" +
 					"Workflow: Use 'tables' and 'describe' to explore the schema before writing queries. " +
 					"Use 'query' for all read operations (including filtered counts via SELECT COUNT(*) ... WHERE). " +
 					"Use 'explain' to optimize slow queries. " +
+					"Prefer 'run_saved_query' over hand-written SQL when a saved query fits. " +
 					"Use 'execute' only for data modifications. " +
 					"Security: statements are classified by their leading verb. Privilege management " +
 					"(GRANT/REVOKE/CREATE USER/SET/FLUSH), filesystem access (LOAD DATA, INTO OUTFILE), " +
 					"and stacked statements (multiple ';' in one call) are always rejected. DDL is " +
-					"rejected unless ALLOW_DDL=true. The primary security boundary is the MySQL user's " +
+					"rejected unless ALLOW_DDL=true. When ALLOWED_TABLES is configured, statements may only " +
+					"reference listed tables, and statements whose tables cannot be determined (CALL, USE, " +
+					"SHOW TABLES, table functions) are rejected; use 'tables' to see what is available. " +
+					"When MASKING_RULES is configured, values of masked columns are replaced and the response " +
+					"lists them; select masked columns by plain name, without an alias or expression. The primary security boundary is the MySQL user's " +
 					"own grants — give it only the privileges it actually needs.",
 			},
 		}
@@ -86,6 +109,20 @@ func handleMessage(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 	case "tools/call":
 		log.Println("-> tools/call")
 		return handleToolCall(client, msg)
+
+	case "saved_queries/list":
+		log.Println("-> saved_queries/list")
+		queries := SavedQueries.List()
+		if queries == nil {
+			queries = []*mysql.SavedQuery{}
+		}
+		return &MCPMessage{
+			JSONRpc: JSONRPCVer,
+			ID:      msg.ID,
+			Result: map[string]interface{}{
+				"queries": queries,
+			},
+		}
 		
 	case "notifications/initialized":
 		log.Println("-> notifications/initialized (ignored)")
@@ -165,12 +202,7 @@ func handleToolCall(client *mysql.Client, msg *MCPMessage) *MCPMessage {
 		JSONRpc: JSONRPCVer,
 		ID:      msg.ID,
 		Result: ToolResponse{
-			Content: []ContentItem{
-				{
-					Type: "text",
-					Text: result,
-				},
-			},
+			Content: result,
 		},
 	}
 }
\ No newline at end of file
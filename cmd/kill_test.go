@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestQueryKilledErrorMessage verifies the error reports elapsed time and reason
+func TestQueryKilledErrorMessage(t *testing.T) {
+	driverErr := errors.New("invalid connection")
+
+	tests := []struct {
+		name     string
+		err      *mysql.QueryKilledError
+		expected string
+	}{
+		{
+			"Timeout",
+			&mysql.QueryKilledError{Elapsed: 30*time.Second + 200*time.Millisecond, Reason: context.DeadlineExceeded, Err: driverErr},
+			"query killed after 30s (timeout)",
+		},
+		{
+			"Cancelled",
+			&mysql.QueryKilledError{Elapsed: 2 * time.Second, Reason: context.Canceled, Err: driverErr},
+			"query killed after 2s (cancelled)",
+		},
+		{
+			"Kill failed",
+			&mysql.QueryKilledError{Elapsed: 5 * time.Second, Reason: context.DeadlineExceeded, Err: driverErr, KillErr: errors.New("access denied")},
+			"KILL QUERY failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(tt.err.Error(), tt.expected) {
+				t.Errorf("Expected %q in %q", tt.expected, tt.err.Error())
+			}
+			if !errors.Is(tt.err, driverErr) {
+				t.Errorf("QueryKilledError should unwrap to the driver error")
+			}
+		})
+	}
+}
+
+// TestKillStaysForbidden verifies user SQL still cannot issue KILL
+func TestKillStaysForbidden(t *testing.T) {
+	client := mysql.NewClient()
+
+	for _, q := range []string{"KILL QUERY 42", "KILL 42", "kill connection 42", "/* x */ KILL QUERY 1"} {
+		if err := client.ValidateQuery(q); err == nil {
+			t.Errorf("Expected %q to be rejected", q)
+		}
+	}
+}
+
+// TestQueryKilledOnTimeout verifies a write that outlives TIMEOUT_WRITE is
+// killed on the server from a control connection
+func TestQueryKilledOnTimeout(t *testing.T) {
+	t.Setenv("TIMEOUT_WRITE", "200ms")
+	client := newFakeTxClient(t, "", "")
+	fakeExecGate.entered = make(chan struct{}, 1)
+	fakeExecGate.release = make(chan struct{})
+	fakeExecGate.kill = make(chan struct{}, 1)
+	fakeExecGate.kills = nil
+	defer func() { fakeExecGate.kill = nil }()
+
+	_, err := client.Execute("DELETE FROM blocking WHERE id = 1", "")
+
+	var killed *mysql.QueryKilledError
+	if !errors.As(err, &killed) {
+		t.Fatalf("Execute() error = %v, want a QueryKilledError", err)
+	}
+	if !errors.Is(killed.Reason, context.DeadlineExceeded) || killed.KillErr != nil {
+		t.Errorf("QueryKilledError = %+v, want a deadline with a successful KILL", killed)
+	}
+	if killed.Elapsed < 200*time.Millisecond {
+		t.Errorf("Elapsed = %v, want at least TIMEOUT_WRITE", killed.Elapsed)
+	}
+	if len(fakeExecGate.kills) != 1 || fakeExecGate.kills[0] != "KILL QUERY 42" {
+		t.Errorf("KILL statements = %q, want [KILL QUERY 42]", fakeExecGate.kills)
+	}
+}
@@ -22,6 +22,11 @@ func main() {
 		defer logFile.Close()
 	}
 
+	// Approval subcommands (list, approve, reject) run and exit.
+	if args := os.Args[1:]; isApprovalCommand(args) {
+		os.Exit(runApprovalCLI(mysql.NewClient(), args, os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	log.Printf("=== Starting MCP MySQL Server %s ===", Version)
 
 	// Show configuration
@@ -32,6 +37,9 @@ func main() {
 	client := mysql.NewClient()
 	log.Println("MySQL client created")
 
+	loadSavedQueries()
+	loadCustomTools()
+
 	// Test connection
 	if err := testConnection(client); err != nil {
 		log.Printf("WARNING: Cannot connect to MySQL: %v", err)
@@ -218,13 +226,15 @@ func validateLogPath(logPath string) string {
 
 func getConfiguration() map[string]string {
 	return map[string]string{
-		"MYSQL_HOST":     os.Getenv("MYSQL_HOST"),
-		"MYSQL_PORT":     os.Getenv("MYSQL_PORT"),
-		"MYSQL_USER":     os.Getenv("MYSQL_USER"),
-		"MYSQL_PASSWORD": "***", // No mostrar la contraseña en logs
-		"MYSQL_DATABASE": os.Getenv("MYSQL_DATABASE"),
-		"LOG_PATH":       os.Getenv("LOG_PATH"),
-		"MAX_SAFE_ROWS":  os.Getenv("MAX_SAFE_ROWS"),
+		"MYSQL_HOST":        os.Getenv("MYSQL_HOST"),
+		"MYSQL_PORT":        os.Getenv("MYSQL_PORT"),
+		"MYSQL_USER":        os.Getenv("MYSQL_USER"),
+		"MYSQL_PASSWORD":    "***", // No mostrar la contraseña en logs
+		"MYSQL_DATABASE":    os.Getenv("MYSQL_DATABASE"),
+		"LOG_PATH":          os.Getenv("LOG_PATH"),
+		"MAX_SAFE_ROWS":     os.Getenv("MAX_SAFE_ROWS"),
+		"SAVED_QUERIES_DIR": os.Getenv("SAVED_QUERIES_DIR"),
+		"CUSTOM_TOOLS_FILE": os.Getenv("CUSTOM_TOOLS_FILE"),
 	}
 }
 
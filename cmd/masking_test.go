@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestParseMaskRule verifies MASKING_RULES entries parse into scoped rules and bad entries are rejected
+func TestParseMaskRule(t *testing.T) {
+	tests := []struct {
+		entry   string
+		want    mysql.MaskRule
+		errPart string
+	}{
+		{"customers.email=partial", mysql.MaskRule{Table: "customers", Column: "email", Strategy: "partial"}, ""},
+		{"SSN=hash", mysql.MaskRule{Column: "ssn", Strategy: "hash"}, ""},
+		{"*.phone = NULL", mysql.MaskRule{Column: "phone", Strategy: "null"}, ""},
+		{"notes=fixed", mysql.MaskRule{Column: "notes", Strategy: "fixed", Text: "[REDACTED]"}, ""},
+		{"notes=fixed:--", mysql.MaskRule{Column: "notes", Strategy: "fixed", Text: "--"}, ""},
+		{"*card*=partial", mysql.MaskRule{Column: "*card*", Strategy: "partial"}, ""},
+		{"email", mysql.MaskRule{}, "expected pattern=strategy"},
+		{"customers.=hash", mysql.MaskRule{}, "missing column pattern"},
+		{"email=scramble", mysql.MaskRule{}, "unknown strategy"},
+		{"email=hash:x", mysql.MaskRule{}, "only fixed takes a text"},
+		{"[email=hash", mysql.MaskRule{}, "bad pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, err := mysql.ParseMaskRule(tt.entry)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("ParseMaskRule(%q) error = %v, want %q", tt.entry, err, tt.errPart)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("ParseMaskRule(%q) = %+v, %v; want %+v", tt.entry, got, err, tt.want)
+			}
+		})
+	}
+}
+
+// TestPartialMask verifies the partial strategy keeps the first character and domain of e-mails and a short tail otherwise
+func TestPartialMask(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"john@x.com", "j***@x.com"},
+		{"éva@example.org", "é***@example.org"},
+		{"4111111111111111", "************1111"},
+		{"555-1234", "******34"},
+		{"abc", "***"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := mysql.PartialMask(tt.in); got != tt.want {
+			t.Errorf("PartialMask(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestMaskRows verifies each strategy, table scoping and that NULL stays NULL
+func TestMaskRows(t *testing.T) {
+	t.Setenv("MASKING_RULES", "customers.email=partial, ssn=hash, phone=null, notes=fixed:[removed], bogus")
+	t.Setenv("MASKING_HASH_KEY", "test-key")
+	policy := mysql.NewMaskingPolicy()
+	if policy == nil || len(policy.Rules) != 4 {
+		t.Fatalf("NewMaskingPolicy() = %+v, want 4 rules", policy)
+	}
+
+	columns := []string{"id", "email", "ssn", "phone", "notes"}
+	rows := [][]interface{}{
+		{int64(1), "john@x.com", "123-45-6789", "555-1234", []byte("vip")},
+		{int64(2), nil, "123-45-6789", nil, nil},
+	}
+	masked := policy.MaskRows(columns, rows, []mysql.TableRef{{Name: "Customers"}}, true)
+	if len(masked) != 4 {
+		t.Fatalf("MaskRows() masked %+v, want 4 columns", masked)
+	}
+
+	if rows[0][0] != int64(1) || rows[0][1] != "j***@x.com" || rows[0][3] != nil || rows[0][4] != "[removed]" {
+		t.Errorf("masked row = %v", rows[0])
+	}
+	hash, _ := rows[0][2].(string)
+	if !strings.HasPrefix(hash, "hash:") || len(hash) != len("hash:")+16 || rows[1][2] != hash {
+		t.Errorf("hashed ssn = %v and %v, want equal hash:<16 hex>", rows[0][2], rows[1][2])
+	}
+	if rows[1][1] != nil || rows[1][4] != nil {
+		t.Errorf("NULL values changed: %v", rows[1])
+	}
+
+	// customers.email does not apply to other tables, but does when the tables are unknown
+	rows = [][]interface{}{{"a@b.c"}}
+	if masked := policy.MaskRows([]string{"email"}, rows, []mysql.TableRef{{Name: "orders"}}, true); masked != nil || rows[0][0] != "a@b.c" {
+		t.Errorf("email on orders masked: %+v %v", masked, rows[0])
+	}
+	if masked := policy.MaskRows([]string{"email"}, rows, nil, false); len(masked) != 1 || rows[0][0] != "a***@b.c" {
+		t.Errorf("email with unknown tables = %+v %v, want masked", masked, rows[0])
+	}
+
+	t.Setenv("MASKING_RULES", "")
+	if policy := mysql.NewMaskingPolicy(); policy != nil {
+		t.Errorf("NewMaskingPolicy() without rules = %+v, want nil", policy)
+	}
+}
+
+// TestMaskingValidateQuery verifies masked columns can only be selected under their own name
+func TestMaskingValidateQuery(t *testing.T) {
+	t.Setenv("MASKING_RULES", "customers.email=partial, ssn=hash")
+	client := mysql.NewClient()
+
+	tests := []struct {
+		name    string
+		sql     string
+		errPart string // empty when the statement passes
+	}{
+		{"Plain column", "SELECT id, email FROM customers", ""},
+		{"Qualified column", "SELECT c.email, c.ssn FROM customers c", ""},
+		{"Star", "SELECT * FROM customers", ""},
+		{"Filter on masked column", "SELECT id FROM customers WHERE email LIKE 'j%'", ""},
+		{"Table rule on other table", "SELECT LOWER(email) AS e FROM orders", ""},
+		{"Count", "SELECT COUNT(*) AS count FROM customers", ""},
+		{"Union without masked columns", "SELECT id FROM customers UNION SELECT id FROM orders", ""},
+		{"Write", "UPDATE customers SET email = 'x@y.z' WHERE id = 1", ""},
+		{"Variable without masked columns", "SELECT id INTO @id FROM customers LIMIT 1", ""},
+		{"Reading a variable", "SELECT @e", ""},
+		{"Function on other columns", "SELECT id FROM customers WHERE LOWER(name) = 'x' AND email IN ('a@b.c')", ""},
+		{"Insert column list", "INSERT INTO customers (id, email) VALUES (1, 'a@b.c')", ""},
+
+		{"Alias", "SELECT email AS contact FROM customers", "can only be selected as a plain column"},
+		{"Implicit alias", "SELECT email contact FROM customers", "can only be selected as a plain column"},
+		{"Expression", "SELECT CONCAT(email, '') FROM customers", "can only be selected as a plain column"},
+		{"Column rule anywhere", "SELECT UPPER(ssn) FROM orders", "can only be selected as a plain column"},
+		{"Subquery", "SELECT * FROM (SELECT email AS e FROM customers) t", "can only be selected as a plain column"},
+		{"Union", "SELECT id, email FROM customers UNION SELECT id, name FROM orders", "rename result columns"},
+		{"Union with star", "SELECT * FROM customers UNION ALL SELECT * FROM customers", "rename result columns"},
+		{"Union after double dash", "SELECT 1 AS x FROM allowed WHERE 0--0 UNION SELECT ssn FROM customers", "rename result columns"},
+		{"Union of same name after double dash", "SELECT email FROM allowed WHERE 0--0 UNION SELECT email FROM customers", "rename result columns"},
+		{"Union with table", "SELECT 1 AS x FROM allowed UNION TABLE customers", "rename result columns"},
+		{"CTE column list", "WITH c (id, contact) AS (SELECT id, email FROM customers) SELECT * FROM c", "rename result columns"},
+		{"Derived column list", "SELECT * FROM (SELECT id, email FROM customers) AS t (a, b)", "rename result columns"},
+		{"Into variable", "SELECT email INTO @e FROM customers LIMIT 1", "SELECT ... INTO @var"},
+		{"Trailing into variable", "SELECT email FROM customers LIMIT 1 INTO @e", "SELECT ... INTO @var"},
+		{"Star into variables", "SELECT * FROM customers LIMIT 1 INTO @a, @b", "SELECT ... INTO @var"},
+		{"Variable assignment", "SELECT @e := email FROM customers", "SELECT ... INTO @var"},
+		{"Assignment next to star", "SELECT *, @n := id FROM customers", "SELECT ... INTO @var"},
+		{"Function in WHERE", "SELECT id FROM customers WHERE EXTRACTVALUE(1, CONCAT(0x7e, email)) = 1", "cannot be passed to a function"},
+		{"Qualified function argument", "SELECT id FROM customers c WHERE LENGTH(c.email) > 3", "cannot be passed to a function"},
+		{"Nested in subquery", "SELECT id FROM orders WHERE id IN (SELECT id FROM customers WHERE UPPER(email) = 'X')", "cannot be passed to a function"},
+		{"Aggregate in HAVING", "SELECT id FROM orders GROUP BY id HAVING MAX(ssn) > 0", "cannot be passed to a function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.ValidateQuery(tt.sql)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("ValidateQuery(%q) = %v, want nil", tt.sql, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("ValidateQuery(%q) = %v, want error containing %q", tt.sql, err, tt.errPart)
+			}
+		})
+	}
+}
+
+// TestMaskNote verifies masked columns are marked in query output
+func TestMaskNote(t *testing.T) {
+	result := &QueryResult{
+		Columns:  []string{"id", "email"},
+		Rows:     [][]interface{}{{int64(1), "j***@x.com"}},
+		RowCount: 1,
+		Masked:   []mysql.MaskedColumn{{Column: "email", Strategy: "partial"}},
+	}
+
+	CompactMode = true
+	defer func() { CompactMode = false }()
+	if got := formatQueryResultStructured(result); !strings.Contains(got, "masked: email=partial") {
+		t.Errorf("compact output = %q, want masked note", got)
+	}
+	CompactMode = false
+	if got := formatQueryResultStructured(result); !strings.Contains(got, "masked columns (MASKING_RULES): email (partial)") {
+		t.Errorf("verbose output = %q, want masked note", got)
+	}
+}
+
+// TestWithholdError verifies server error text is withheld from statements a masking rule applies to
+func TestWithholdError(t *testing.T) {
+	t.Setenv("MASKING_RULES", "customers.email=partial")
+	policy := mysql.NewMaskingPolicy()
+
+	xpath := &driver.MySQLError{Number: 1105, Message: "XPATH syntax error: '~john@x.com'"}
+	err := policy.WithholdError("SELECT id FROM customers WHERE id = 1", xpath)
+	if strings.Contains(err.Error(), "john") || !strings.Contains(err.Error(), "MySQL error 1105") {
+		t.Errorf("WithholdError() = %q, want the number without the message", err)
+	}
+	var me *driver.MySQLError
+	if !errors.As(err, &me) || me != xpath {
+		t.Errorf("WithholdError() does not unwrap to the server error")
+	}
+
+	if err := policy.WithholdError("SELECT id FROM orders", xpath); err != xpath {
+		t.Errorf("WithholdError() on an unmasked table = %v, want unchanged", err)
+	}
+	syntax := &driver.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+	if err := policy.WithholdError("SELECT id FROM customers", syntax); err != syntax {
+		t.Errorf("WithholdError() on a syntax error = %v, want unchanged", err)
+	}
+	var none *mysql.MaskingPolicy
+	if err := none.WithholdError("SELECT id FROM customers", xpath); err != xpath {
+		t.Errorf("WithholdError() without a policy = %v, want unchanged", err)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestNonTxPolicy verifies NONTX_WRITE_POLICY parsing and its fallback
+func TestNonTxPolicy(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", mysql.NonTxPrecount},
+		{"precount", mysql.NonTxPrecount},
+		{"confirm", mysql.NonTxConfirm},
+		{"BLOCK", mysql.NonTxBlock},
+		{"off", mysql.NonTxOff},
+		{"rollback", mysql.NonTxPrecount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("NONTX_WRITE_POLICY", tt.value)
+			if got := mysql.NewNonTxPolicy(); got != tt.want {
+				t.Errorf("NewNonTxPolicy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestIsTransactionalEngine verifies which engines the rollback-based gate can rely on
+func TestIsTransactionalEngine(t *testing.T) {
+	tests := []struct {
+		engine string
+		want   bool
+	}{
+		{"InnoDB", true},
+		{"ndbcluster", true},
+		{"ROCKSDB", true},
+		{"", true}, // views
+		{"MyISAM", false},
+		{"MEMORY", false},
+		{"ARCHIVE", false},
+		{"CSV", false},
+		{"Aria", false},
+		{"MRG_MyISAM", false},
+		{"BLACKHOLE", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			if got := mysql.IsTransactionalEngine(tt.engine); got != tt.want {
+				t.Errorf("IsTransactionalEngine(%q) = %v, want %v", tt.engine, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCountInsertRows verifies the row counts precount derives from INSERT and REPLACE statements
+func TestCountInsertRows(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		want   int64
+		wantOK bool
+	}{
+		{"Single row", "INSERT INTO logs (a, b) VALUES (1, 2)", 1, true},
+		{"Several rows", "INSERT INTO logs (a) VALUES (1), (2), (3)", 3, true},
+		{"VALUE keyword", "INSERT INTO logs VALUE (1)", 1, true},
+		{"Row constructors", "INSERT INTO logs VALUES ROW(1), ROW(2)", 2, true},
+		{"Nested parentheses", "INSERT INTO logs (a) VALUES ((1 + 2)), (CONCAT('a', 'b'))", 2, true},
+		{"Placeholders", "INSERT INTO logs (a, b) VALUES (?, ?), (?, ?)", 2, true},
+		{"SET form", "INSERT INTO logs SET a = 1, b = (SELECT 2)", 1, true},
+		{"Ignore", "INSERT IGNORE INTO logs VALUES (1), (2)", 2, true},
+		{"On duplicate key counts twice", "INSERT INTO logs (a) VALUES (1), (2) ON DUPLICATE KEY UPDATE a = VALUES(a)", 4, true},
+		{"Row alias", "INSERT INTO logs (a) VALUES (1) AS new ON DUPLICATE KEY UPDATE a = new.a", 2, true},
+		{"Replace counts twice", "REPLACE INTO logs VALUES (1), (2)", 4, true},
+		{"Insert select", "INSERT INTO logs (a) SELECT id FROM t", 0, false},
+		{"Insert table", "INSERT INTO logs TABLE t", 0, false},
+		{"Update", "UPDATE logs SET a = 1 WHERE id = 2", 0, false},
+		{"Unbalanced", "INSERT INTO logs VALUES (1", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mysql.CountInsertRows(tt.sql)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("CountInsertRows(%q) = %d, %v, want %d, %v", tt.sql, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
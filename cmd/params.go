@@ -1,6 +1,12 @@
 package main
 
-import "fmt"
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
 
 // Parameter extraction helpers for MCP tool arguments
 
@@ -29,6 +35,14 @@ func getIntArg(args map[string]interface{}, key string, defaultVal int) int {
 	return defaultVal
 }
 
+// getBoolArg extracts a boolean argument with default
+func getBoolArg(args map[string]interface{}, key string, defaultVal bool) bool {
+	if v, ok := args[key].(bool); ok {
+		return v
+	}
+	return defaultVal
+}
+
 // getIntArgClamped extracts an integer clamped to min/max range
 func getIntArgClamped(args map[string]interface{}, key string, defaultVal, minVal, maxVal int) int {
 	v := getIntArg(args, key, defaultVal)
@@ -40,3 +54,91 @@ func getIntArgClamped(args map[string]interface{}, key string, defaultVal, minVa
 	}
 	return v
 }
+
+// getSQLArgs converts a JSON array into '?' placeholder arguments. Whole
+// numbers become int64 so they bind as integers; objects and arrays are
+// rejected.
+func getSQLArgs(list []interface{}) ([]interface{}, error) {
+	out := make([]interface{}, len(list))
+	for i, v := range list {
+		switch x := v.(type) {
+		case nil, string, bool:
+			out[i] = x
+		case float64:
+			if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+				out[i] = int64(x)
+			} else {
+				out[i] = x
+			}
+		default:
+			return nil, fmt.Errorf("parameter %d must be a string, number, boolean or null", i+1)
+		}
+	}
+	return out, nil
+}
+
+// csvNull is how CSV input spells NULL, as in mysqldump and SELECT ... INTO OUTFILE.
+const csvNull = `\N`
+
+// getBulkRows reads the columns and rows of a bulk_insert call, from either
+// a JSON 'rows' array or a 'csv' string. Without 'columns', the first CSV
+// line is the header.
+func getBulkRows(args map[string]interface{}) (columns []string, rows [][]interface{}, err error) {
+	if list, ok := args["columns"].([]interface{}); ok {
+		for i, v := range list {
+			name, ok := v.(string)
+			if !ok || name == "" {
+				return nil, nil, fmt.Errorf("column %d must be a non-empty string", i+1)
+			}
+			columns = append(columns, name)
+		}
+	}
+
+	list, hasRows := args["rows"].([]interface{})
+	text, hasCSV := args["csv"].(string)
+	switch {
+	case hasRows && hasCSV:
+		return nil, nil, fmt.Errorf("pass either 'rows' or 'csv', not both")
+	case hasRows:
+		if len(columns) == 0 {
+			return nil, nil, fmt.Errorf("missing or invalid 'columns' parameter")
+		}
+		for i, item := range list {
+			values, ok := item.([]interface{})
+			if !ok {
+				return nil, nil, fmt.Errorf("row %d must be an array of values", i+1)
+			}
+			row, err := getSQLArgs(values)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: %w", i+1, err)
+			}
+			rows = append(rows, row)
+		}
+		return columns, rows, nil
+	case hasCSV:
+		r := csv.NewReader(strings.NewReader(text))
+		r.FieldsPerRecord = -1
+		for {
+			record, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid csv: %w", err)
+			}
+			if len(columns) == 0 {
+				columns = record
+				continue
+			}
+			row := make([]interface{}, len(record))
+			for i, field := range record {
+				if field != csvNull {
+					row[i] = field
+				}
+			}
+			rows = append(rows, row)
+		}
+		return columns, rows, nil
+	}
+	return nil, nil, fmt.Errorf("missing 'rows' or 'csv' parameter")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestBuildCall verifies how call_procedure binds arguments to the declared parameters
+func TestBuildCall(t *testing.T) {
+	params := []mysql.ProcedureParam{
+		{Name: "customer_id", Mode: "IN", DataType: "int"},
+		{Name: "amount", Mode: "IN", DataType: "decimal"},
+		{Name: "ratio", Mode: "IN", DataType: "double"},
+		{Name: "total", Mode: "OUT", DataType: "decimal"},
+		{Name: "counter", Mode: "INOUT", DataType: "bigint"},
+	}
+	// args returns valid arguments with overrides applied; missing removes one.
+	missing := struct{}{}
+	args := func(overrides map[string]interface{}) map[string]interface{} {
+		a := map[string]interface{}{"customer_id": float64(7), "amount": 12.5, "ratio": 0.25, "counter": float64(1)}
+		for k, v := range overrides {
+			if v == missing {
+				delete(a, k)
+				continue
+			}
+			a[k] = v
+		}
+		return a
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		wantArgs []interface{}
+		errPart  string
+	}{
+		{"Integer, decimal and double", args(nil), []interface{}{int64(7), "12.5", 0.25}, ""},
+		{"Boolean as integer", args(map[string]interface{}{"customer_id": true}), []interface{}{int64(1), "12.5", 0.25}, ""},
+		{"String passes through", args(map[string]interface{}{"amount": "0.10"}), []interface{}{int64(7), "0.10", 0.25}, ""},
+		{"Whole number for decimal", args(map[string]interface{}{"amount": float64(3)}), []interface{}{int64(7), "3", 0.25}, ""},
+		{"Null binds NULL", args(map[string]interface{}{"amount": nil}), []interface{}{int64(7), nil, 0.25}, ""},
+		{"Fraction for double", args(map[string]interface{}{"ratio": 1.5}), []interface{}{int64(7), "12.5", 1.5}, ""},
+
+		{"Fraction for integer", args(map[string]interface{}{"customer_id": 7.5}), nil, `argument "customer_id" must be an integer (int), got 7.5`},
+		{"Fraction for INOUT integer", args(map[string]interface{}{"counter": 0.5}), nil, `argument "counter" must be an integer (bigint)`},
+		{"Missing IN argument", args(map[string]interface{}{"amount": missing}), nil, `missing argument "amount" (IN decimal)`},
+		{"Missing INOUT argument", args(map[string]interface{}{"counter": missing}), nil, `missing argument "counter" (INOUT bigint)`},
+		{"Value for OUT parameter", args(map[string]interface{}{"total": float64(0)}), nil, `argument "total" is an OUT parameter`},
+		{"Unknown parameter", args(map[string]interface{}{"customerid": float64(7)}), nil, `procedure refund has no parameter "customerid"`},
+		{"Object argument", args(map[string]interface{}{"ratio": map[string]interface{}{"x": 1}}), nil, `argument "ratio" must be a string, number, boolean or null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, callArgs, err := mysql.BuildCall("refund", params, tt.args)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Errorf("BuildCall() error = %v, want %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildCall() error: %v", err)
+			}
+			if want := "CALL `refund`(?, ?, ?, @mcp_out_4, @mcp_out_5)"; call != want {
+				t.Errorf("call = %q, want %q", call, want)
+			}
+			if !reflect.DeepEqual(callArgs, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", callArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// TestProcedureOutParamsMasking verifies procedures with OUT or INOUT
+// parameters are refused under MASKING_RULES
+func TestProcedureOutParamsMasking(t *testing.T) {
+	t.Setenv("MASKING_RULES", "ssn=hash")
+	client := mysql.NewClient()
+	client.UseDB(openFakeRows(t))
+
+	fakeProc.access = "READS SQL DATA"
+	for _, mode := range []string{"OUT", "INOUT"} {
+		fakeProc.params = [][]driver.Value{{"id", "IN", "int"}, {"contact", mode, "varchar"}}
+		args := map[string]interface{}{"id": float64(1)}
+		if mode == "INOUT" {
+			args["contact"] = nil
+		}
+		_, err := client.CallProcedure("get_contact", args, "")
+		if err == nil || !strings.Contains(err.Error(), "MASKING_RULES cannot mask") {
+			t.Errorf("%s parameter: expected a MASKING_RULES refusal, got %v", mode, err)
+		}
+	}
+}
+
+// TestCallProcedure verifies call_procedure returns every result set and
+// the OUT and INOUT values read back from their session variables
+func TestCallProcedure(t *testing.T) {
+	client := newFakeTxClient(t, "", "")
+	fakeProc.access = "READS SQL DATA"
+	fakeProc.params = [][]driver.Value{{"customer_id", "IN", "int"}, {"total", "OUT", "decimal"}, {"counter", "INOUT", "bigint"}}
+	fakeProc.sets = []int{2, 0, 3}
+	fakeProc.out = map[string]driver.Value{"@mcp_out_2": []byte("99.50")}
+	fakeProc.vars = map[string]driver.Value{}
+	fakeTxs()
+
+	res, err := client.CallProcedure("order_totals", map[string]interface{}{"customer_id": float64(1), "counter": float64(7)}, "")
+	if err != nil {
+		t.Fatalf("CallProcedure() error: %v", err)
+	}
+
+	var counts []int
+	for _, set := range res.ResultSets {
+		counts = append(counts, set.RowCount)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 0, 3}) {
+		t.Errorf("Result set row counts = %v, want [2 0 3]", counts)
+	}
+	want := []mysql.OutParam{{Name: "total", Value: "99.50"}, {Name: "counter", Value: int64(7)}}
+	if !reflect.DeepEqual(res.OutParams, want) {
+		t.Errorf("OutParams = %#v, want %#v", res.OutParams, want)
+	}
+	if !res.ReadOnly {
+		t.Errorf("A READS SQL DATA procedure should run read-only")
+	}
+	if txs := fakeTxs(); len(txs) != 1 || !txs[0].readOnly || txs[0].end != "rollback" {
+		t.Errorf("Transactions = %+v, want one read-only rollback", txs)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestReadOnlyViolation verifies the server's read-only refusal (1792) points to execute, and other errors pass through unchanged
+func TestReadOnlyViolation(t *testing.T) {
+	readOnly := &driver.MySQLError{Number: 1792, Message: "Cannot execute statement in a READ ONLY transaction."}
+
+	for _, err := range []error{readOnly, fmt.Errorf("query failed: %w", readOnly)} {
+		got := mysql.ReadOnlyViolation(err)
+		if got == nil || !strings.Contains(got.Error(), "use 'execute' for modifications") {
+			t.Errorf("ReadOnlyViolation(%v) = %v, want the read-only message", err, got)
+		}
+		if !errors.Is(got, readOnly) {
+			t.Errorf("ReadOnlyViolation(%v) does not wrap the server error", err)
+		}
+	}
+
+	others := []error{
+		nil,
+		&driver.MySQLError{Number: 1146, Message: "Table 'shop.nope' doesn't exist"},
+		&driver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+		errors.New("connection refused"),
+	}
+	for _, err := range others {
+		if got := mysql.ReadOnlyViolation(err); got != err {
+			t.Errorf("ReadOnlyViolation(%v) = %v, want it unchanged", err, got)
+		}
+	}
+}
+
+// TestReadsRollBackReadOnly verifies reads run in a read-only transaction
+// that is rolled back, whether they succeed or fail
+func TestReadsRollBackReadOnly(t *testing.T) {
+	client := newFakeTxClient(t, "", "")
+	fakeTxs()
+
+	if _, err := client.QueryPrepared("3"); err != nil {
+		t.Fatalf("QueryPrepared() error: %v", err)
+	}
+	if _, err := client.QueryPrepared("not a row count"); err == nil {
+		t.Fatalf("QueryPrepared() of a failing read should return an error")
+	}
+	txs := fakeTxs()
+	if len(txs) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(txs))
+	}
+	for i, tx := range txs {
+		if !tx.readOnly || tx.end != "rollback" {
+			t.Errorf("Read %d: transaction read-only=%v ended with %q, want read-only and rollback", i+1, tx.readOnly, tx.end)
+		}
+	}
+
+	if _, err := client.Execute("DELETE FROM t WHERE id = 1", ""); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if txs := fakeTxs(); len(txs) != 1 || txs[0].readOnly || txs[0].end != "commit" {
+		t.Errorf("Write transactions = %+v, want one read-write commit", txs)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestCheckWhereClause verifies which UPDATE/DELETE statements REQUIRE_WHERE rejects
+func TestCheckWhereClause(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		errPart string // empty when the statement passes
+	}{
+		// Statements the policy does not apply to
+		{"Insert", "INSERT INTO customers (name) VALUES ('a')", ""},
+		{"Select for update", "SELECT * FROM customers FOR UPDATE", ""},
+		{"CTE select for update", "WITH c AS (SELECT id FROM customers) SELECT * FROM c FOR UPDATE", ""},
+		{"Insert on duplicate key update", "INSERT INTO t (id, n) VALUES (1, 2) ON DUPLICATE KEY UPDATE n = 3", ""},
+
+		// Row-selecting WHERE clauses
+		{"Delete by key", "DELETE FROM customers WHERE id = 5", ""},
+		{"Update by key", "UPDATE customers SET name = 'x' WHERE id = ?", ""},
+		{"Quoted column", "DELETE FROM customers WHERE `id` = 5", ""},
+		{"OR of columns", "DELETE FROM customers WHERE id = 5 OR email = 'a@b.c'", ""},
+		{"Parenthesized branches", "DELETE FROM customers WHERE (id = 5) OR (email IS NULL)", ""},
+		{"Subquery", "DELETE FROM customers WHERE EXISTS (SELECT 1 FROM blocked)", ""},
+		{"WHERE in executable comment", "DELETE FROM customers /*! WHERE id = 5 */", ""},
+		{"Function of a column", "UPDATE customers SET n = 0 WHERE DATE(created_at) < CURRENT_DATE()", ""},
+		{"AND of columns", "DELETE FROM customers WHERE id > 5 AND email IS NULL", ""},
+		{"BETWEEN", "DELETE FROM customers WHERE id BETWEEN 1 AND 10 AND active = 0", ""},
+		{"Column named date", "DELETE FROM customers WHERE date < NOW()", ""},
+		{"Column compared to expression", "UPDATE customers SET n = n + 1 WHERE n = n + 1", ""},
+		{"ORDER BY and LIMIT", "DELETE FROM logs WHERE level = 'debug' ORDER BY id LIMIT 100", ""},
+		{"Multi-table update", "UPDATE a JOIN b ON a.id = b.a_id SET a.n = b.n WHERE b.id = 3", ""},
+		{"Multi-table delete", "DELETE a FROM a JOIN b ON a.id = b.a_id WHERE b.flag = 1", ""},
+		{"Delete using", "DELETE FROM a USING a, b WHERE a.id = b.a_id", ""},
+		{"CTE update", "WITH x AS (SELECT id FROM b) UPDATE a SET n = 0 WHERE id IN (SELECT id FROM x)", ""},
+
+		// Missing WHERE
+		{"Delete everything", "DELETE FROM customers", "DELETE without a WHERE clause"},
+		{"Update everything", "UPDATE customers SET active = 0", "UPDATE without a WHERE clause"},
+		{"Limit is not a WHERE", "DELETE FROM customers LIMIT 10", "without a WHERE clause"},
+		{"WHERE only in subquery", "UPDATE customers SET n = (SELECT MAX(n) FROM t WHERE t.id = 1)", "without a WHERE clause"},
+		{"WHERE only in comment", "DELETE FROM customers -- WHERE id = 5", "without a WHERE clause"},
+		{"Join condition is not a WHERE", "UPDATE a JOIN b ON a.id = b.a_id SET a.n = b.n", "UPDATE without a WHERE clause"},
+		{"Multi-table delete without WHERE", "DELETE a, b FROM a JOIN b ON a.id = b.a_id", "DELETE without a WHERE clause"},
+		{"Comma join without WHERE", "UPDATE a, b SET a.n = b.n", "UPDATE without a WHERE clause"},
+		{"CTE delete without WHERE", "WITH x AS (SELECT 1) DELETE FROM customers", "DELETE without a WHERE clause"},
+		{"Lowercase", "delete from customers", "DELETE without a WHERE clause"},
+		{"Empty WHERE", "DELETE FROM customers WHERE LIMIT 1", "empty WHERE clause"},
+
+		// Tautologies
+		{"1=1", "DELETE FROM customers WHERE 1=1", "references no column"},
+		{"TRUE", "UPDATE customers SET n = 0 WHERE TRUE", "references no column"},
+		{"String compare", "DELETE FROM customers WHERE 'a' = 'a'", "references no column"},
+		{"Placeholders only", "DELETE FROM customers WHERE ? = ?", "references no column"},
+		{"Function only", "DELETE FROM customers WHERE NOW() > '2020-01-01'", "references no column"},
+		{"Date literal", "DELETE FROM customers WHERE DATE '2020-01-01' < NOW()", "references no column"},
+		{"Timestamp literal", "DELETE FROM customers WHERE TIMESTAMP '2020-01-01 00:00:00' < NOW()", "references no column"},
+		{"OR 1=1", "DELETE FROM customers WHERE id = 5 OR 1=1", "an OR branch references no column"},
+		{"|| 1", "DELETE FROM customers WHERE id = 5 || 1", "an OR branch references no column"},
+		{"Parenthesized OR 1=1", "DELETE FROM customers WHERE (id = 5) OR (1 = 1)", "references no column"},
+		{"Nested OR 1=1", "DELETE FROM customers WHERE (id = 5 OR (1 = 1))", "references no column"},
+		{"Self comparison", "DELETE FROM customers WHERE id = id", "compares an expression with itself"},
+		{"Qualified self comparison", "UPDATE a JOIN b ON a.id = b.id SET a.n = 1 WHERE a.id = A.ID", "compares an expression with itself"},
+		{"Self comparison AND 1", "DELETE FROM customers WHERE id = id AND 1", "compares an expression with itself"},
+		{"AND 1", "DELETE FROM customers WHERE status = 'x' AND 1", "an AND term references no column"},
+		{"&& TRUE", "DELETE FROM customers WHERE id = 5 || status = 'x' && TRUE", "an AND term references no column"},
+		{"Self comparison after AND", "DELETE FROM customers WHERE 1 = 1 AND id = id", "references no column"},
+		{"Self comparison in second term", "DELETE FROM customers WHERE status = 'x' AND id = id", "compares an expression with itself"},
+		{"Tautology before LIMIT", "DELETE FROM customers WHERE 1 ORDER BY id LIMIT 5", "references no column"},
+
+		// Unparseable statements are rejected rather than guessed at
+		{"Unbalanced parenthesis", "DELETE FROM customers WHERE (id = 5", "could not be parsed"},
+		{"WHERE in versioned comment", "DELETE FROM customers /*!99999 WHERE id = 1 */", "could not be parsed"},
+		{"WHERE in MariaDB comment", "UPDATE customers SET n = 0 /*M! WHERE id = 1 */", "could not be parsed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := mysql.CheckWhereClause(tt.sql)
+			if tt.errPart == "" {
+				if err != nil {
+					t.Errorf("CheckWhereClause(%q) = %v, want nil", tt.sql, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("CheckWhereClause(%q) = %v, want error containing %q", tt.sql, err, tt.errPart)
+			}
+		})
+	}
+}
+
+// TestRequireWherePolicy verifies REQUIRE_WHERE rejects full-table writes before connecting, and allow_full_table overrides it only with confirmation
+func TestRequireWherePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		mode    string
+		tool    string
+		args    map[string]interface{}
+		blocked bool
+	}{
+		{"Off by default", "", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers"}, false},
+		{"Execute", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers"}, true},
+		{"Execute with tautology", "true", "", "execute", map[string]interface{}{"sql": "UPDATE customers SET n = 0 WHERE 1=1"}, true},
+		{"Override without confirmation", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers", "allow_full_table": true}, true},
+		{"Override with a wrong key", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers", "allow_full_table": true, "confirm_key": "guess"}, true},
+		{"Override with SAFETY_KEY", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers", "allow_full_table": true, "confirm_key": "PRODUCTION_CONFIRMED_2025"}, false},
+		{"Override in token mode", "true", "token", "execute", map[string]interface{}{"sql": "DELETE FROM customers", "allow_full_table": true, "confirm_key": "PRODUCTION_CONFIRMED_2025"}, true},
+		{"Execute with WHERE", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers WHERE id = 1"}, false},
+		{"Inside a transaction", "true", "", "execute", map[string]interface{}{"sql": "DELETE FROM customers", "transaction": "tx_gone"}, true},
+		{"Batch", "true", "", "execute_batch", map[string]interface{}{"statements": []interface{}{"DELETE FROM a WHERE id = 1", "DELETE FROM customers"}}, true},
+		{"Batch override without confirmation", "true", "", "execute_batch", map[string]interface{}{"statements": []interface{}{"DELETE FROM customers"}, "allow_full_table": true}, true},
+		{"Batch override with SAFETY_KEY", "true", "", "execute_batch", map[string]interface{}{"statements": []interface{}{"DELETE FROM customers"}, "allow_full_table": true, "confirm_key": "PRODUCTION_CONFIRMED_2025"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REQUIRE_WHERE", tt.env)
+			t.Setenv("SAFETY_KEY", "")
+			t.Setenv("CONFIRMATION_MODE", tt.mode)
+			client := mysql.NewClient()
+
+			// Without a database, anything that passes the policy fails later.
+			_, err := callClientMethod(client, tt.tool, tt.args)
+			blocked := err != nil && strings.Contains(err.Error(), "REQUIRE_WHERE")
+			if blocked != tt.blocked {
+				t.Errorf("blocked = %v, want %v (err: %v)", blocked, tt.blocked, err)
+			}
+		})
+	}
+}
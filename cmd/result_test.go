@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestUniqueColumns verifies duplicate column names are de-duplicated
+func TestUniqueColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []string
+		expected []string
+	}{
+		{"No duplicates", []string{"id", "name"}, []string{"id", "name"}},
+		{"Join with two ids", []string{"id", "id"}, []string{"id", "id_2"}},
+		{"Three ids", []string{"id", "id", "id"}, []string{"id", "id_2", "id_3"}},
+		{"Suffix collides with real column", []string{"id", "id", "id_2"}, []string{"id", "id_3", "id_2"}},
+		{"Empty", []string{}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &mysql.QueryResult{Columns: tt.columns}
+			got := r.UniqueColumns()
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestRowMapKeepsDuplicateColumns verifies no value is lost through the map accessor
+func TestRowMapKeepsDuplicateColumns(t *testing.T) {
+	r := &mysql.QueryResult{
+		Columns:  []string{"id", "name", "id"},
+		Rows:     [][]interface{}{{int64(1), "alice", int64(7)}},
+		RowCount: 1,
+	}
+
+	row := r.RowMap(0)
+	if row["id"] != int64(1) || row["id_2"] != int64(7) || row["name"] != "alice" {
+		t.Errorf("Unexpected row map: %v", row)
+	}
+
+	if r.RowMap(1) != nil {
+		t.Errorf("Out-of-range row should be nil")
+	}
+}
+
+// TestFormatRowPreservesOrder verifies the formatter prints values in column order
+func TestFormatRowPreservesOrder(t *testing.T) {
+	got := formatRowCompact([]interface{}{int64(1), nil, "x", int64(2)})
+	if got != "1\tNULL\tx\t2" {
+		t.Errorf("Unexpected row format: %q", got)
+	}
+}
+
+// TestReadRowsLimits verifies the row and byte caps stop the read early
+func TestReadRowsLimits(t *testing.T) {
+	db := openFakeRows(t)
+
+	tests := []struct {
+		name          string
+		total         int
+		limits        mysql.ResultLimits
+		expectedRows  int
+		expectedTrunc bool
+	}{
+		{"Under both limits", 10, mysql.ResultLimits{MaxRows: 100, MaxBytes: 1 << 20}, 10, false},
+		{"Exactly at row limit", 10, mysql.ResultLimits{MaxRows: 10}, 10, false},
+		{"Row limit", 500, mysql.ResultLimits{MaxRows: 20}, 20, true},
+		{"Byte limit", 500, mysql.ResultLimits{MaxBytes: 2000}, 13, true},
+		{"No limits", 500, mysql.ResultLimits{}, 500, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := db.Query(strconv.Itoa(tt.total))
+			if err != nil {
+				t.Fatalf("query: %v", err)
+			}
+			defer rows.Close()
+
+			result, err := mysql.ReadRows(rows, tt.limits)
+			if err != nil {
+				t.Fatalf("ReadRows: %v", err)
+			}
+			if result.RowCount != tt.expectedRows || len(result.Rows) != tt.expectedRows {
+				t.Errorf("Expected %d rows, got %d", tt.expectedRows, result.RowCount)
+			}
+			if result.Truncated != tt.expectedTrunc {
+				t.Errorf("Expected truncated=%v, got %v", tt.expectedTrunc, result.Truncated)
+			}
+		})
+	}
+}
+
+// TestReadRowsValues verifies scanned values keep their order and types
+func TestReadRowsValues(t *testing.T) {
+	db := openFakeRows(t)
+	rows, err := db.Query("2")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+
+	result, err := mysql.ReadRows(rows, mysql.ResultLimits{})
+	if err != nil {
+		t.Fatalf("ReadRows: %v", err)
+	}
+
+	row := result.Rows[1]
+	if row[0] != int64(1) || row[1] != "Customer 1" || row[4] != "1234.56" {
+		t.Errorf("Unexpected row: %v", row)
+	}
+	if _, ok := row[3].(time.Time); !ok {
+		t.Errorf("Expected time.Time, got %T", row[3])
+	}
+}
+
+// legacyProcessRows is the map-per-row pipeline ReadRows replaced, kept
+// here only so the benchmarks can show the allocation difference.
+func legacyProcessRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		valuePtrs := make([]interface{}, len(columns))
+		for i := range columns {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{})
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = string(b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		out = append(out, row)
+	}
+	return out, rows.Err()
+}
+
+// BenchmarkLegacyProcessRows reads 10k rows the old way (one map per row)
+func BenchmarkLegacyProcessRows(b *testing.B) {
+	db := openFakeRows(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, _ := db.Query("10000")
+		if _, err := legacyProcessRows(rows); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkReadRows reads the same 10k rows with no limits
+func BenchmarkReadRows(b *testing.B) {
+	db := openFakeRows(b)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, _ := db.Query("10000")
+		if _, err := mysql.ReadRows(rows, mysql.ResultLimits{}); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
+
+// BenchmarkReadRowsCapped reads a 1M-row result under the default caps;
+// cost is bounded by the caps, not by the size of the result
+func BenchmarkReadRowsCapped(b *testing.B) {
+	db := openFakeRows(b)
+	limits := mysql.ResultLimits{MaxRows: mysql.DefaultMaxResultRows, MaxBytes: mysql.DefaultMaxResultBytes}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		rows, _ := db.Query("1000000")
+		if _, err := mysql.ReadRows(rows, limits); err != nil {
+			b.Fatal(err)
+		}
+		rows.Close()
+	}
+}
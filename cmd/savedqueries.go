@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// SavedQueries is the library loaded from SAVED_QUERIES_DIR (nil when unset).
+var SavedQueries *mysql.SavedQueryLibrary
+
+// loadSavedQueries loads SAVED_QUERIES_DIR at startup. A missing or
+// unreadable directory is logged and leaves the library empty.
+func loadSavedQueries() {
+	dir := os.Getenv("SAVED_QUERIES_DIR")
+	if dir == "" {
+		return
+	}
+	lib, err := mysql.LoadSavedQueries(dir)
+	if err != nil {
+		log.Printf("WARNING: %v", err)
+		return
+	}
+	SavedQueries = lib
+	log.Printf("Loaded %d saved queries from %s", len(lib.List()), dir)
+}
+
+// savedQueryTool describes run_saved_query, listing the available queries
+// so the agent can pick one without a separate discovery call.
+func savedQueryTool(queries []*mysql.SavedQuery) ToolDefinition {
+	names := make([]string, 0, len(queries))
+	var sb strings.Builder
+	sb.WriteString("Run a vetted saved query by name. Prefer these over hand-written SQL when one fits. Available:\n")
+	for _, q := range queries {
+		names = append(names, q.Name)
+		params := make([]string, 0, len(q.Params))
+		for _, p := range q.Params {
+			params = append(params, p.Name+" "+p.Type)
+		}
+		sb.WriteString(fmt.Sprintf("- %s(%s) [%s]: %s\n", q.Name, strings.Join(params, ", "), q.Mode, q.Description))
+	}
+
+	return ToolDefinition{
+		Name:        "run_saved_query",
+		Title:       "Run Saved Query",
+		Description: strings.TrimRight(sb.String(), "\n"),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"enum":        names,
+					"description": "The saved query to run",
+				},
+				"params": map[string]interface{}{
+					"type":        "object",
+					"description": "Parameter values by name",
+				},
+				"confirm_key": map[string]interface{}{
+					"type":        "string",
+					"description": "Safety confirmation key for large write operations",
+				},
+			},
+			"required": []string{"name"},
+		},
+	}
+}
+
+// handleRunSavedQuery runs a saved query with the given parameters
+func handleRunSavedQuery(client *mysql.Client, args map[string]interface{}) ([]ContentItem, error) {
+	name, err := getStringArg(args, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	q, ok := SavedQueries.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown saved query: %s", name)
+	}
+
+	params, _ := args["params"].(map[string]interface{})
+	result, err := client.RunSavedQuery(q, params, getOptionalString(args, "confirm_key", ""))
+	if err != nil {
+		return nil, err
+	}
+
+	if q.Mode == "write" {
+		return textContent(formatWriteResult(result), nil)
+	}
+	return resultContent(result, "")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+const ordersSinceSQL = `-- description: Orders a customer placed since a date
+-- mode: read
+-- param: customer_id integer Customer primary key
+-- param: since date Earliest order date
+SELECT id, total FROM orders
+WHERE customer_id = :customer_id AND created_at >= :since AND note <> ':since'
+`
+
+// TestParseSavedQuery verifies front-matter parsing and load-time checks
+func TestParseSavedQuery(t *testing.T) {
+	q, err := mysql.ParseSavedQuery("orders_since", ordersSinceSQL)
+	if err != nil {
+		t.Fatalf("Expected valid saved query, got %v", err)
+	}
+	if q.Description != "Orders a customer placed since a date" || q.Mode != "read" || len(q.Params) != 2 {
+		t.Errorf("Unexpected parse result: %+v", q)
+	}
+	if q.Params[0].Name != "customer_id" || q.Params[0].Type != "integer" || q.Params[0].Description != "Customer primary key" {
+		t.Errorf("Unexpected first param: %+v", q.Params[0])
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		errPart string
+	}{
+		{"Missing description", "SELECT 1", "missing description"},
+		{"Bad mode", "-- description: x\n-- mode: admin\nSELECT 1", "mode must be"},
+		{"Read mode with write", "-- description: x\nDELETE FROM t", "mode read"},
+		{"Write mode with DDL", "-- description: x\n-- mode: write\nDROP TABLE t", "mode write"},
+		{"Undeclared placeholder", "-- description: x\nSELECT * FROM t WHERE id = :id", "not declared"},
+		{"Unused param", "-- description: x\n-- param: id integer\nSELECT * FROM t", "not used"},
+		{"Unknown type", "-- description: x\n-- param: id uuid\nSELECT * FROM t WHERE id = :id", "unknown type"},
+		{"Positional placeholder", "-- description: x\nSELECT * FROM t WHERE id = ?", "not ?"},
+		{"Unknown key", "-- description: x\n-- owner: me\nSELECT 1", "unknown front-matter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := mysql.ParseSavedQuery("q", tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
+// TestSavedQueryBind verifies typed binding and placeholder rewriting
+func TestSavedQueryBind(t *testing.T) {
+	q, err := mysql.ParseSavedQuery("orders_since", ordersSinceSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, args, err := q.Bind(map[string]interface{}{"customer_id": float64(42), "since": "2026-01-01"})
+	if err != nil {
+		t.Fatalf("Expected bind to succeed, got %v", err)
+	}
+	if !strings.Contains(query, "customer_id = ? AND created_at >= ? AND note <> ':since'") {
+		t.Errorf("Placeholders not rewritten as expected: %s", query)
+	}
+	if !reflect.DeepEqual(args, []interface{}{int64(42), "2026-01-01"}) {
+		t.Errorf("Expected [42 2026-01-01], got %v", args)
+	}
+
+	bad := []map[string]interface{}{
+		{"customer_id": float64(42)},                                     // missing
+		{"customer_id": 4.5, "since": "2026-01-01"},                      // not an integer
+		{"customer_id": float64(1), "since": "yesterday"},                // not a date
+		{"customer_id": float64(1), "since": "2026-01-01", "extra": "x"}, // unknown
+	}
+	for _, values := range bad {
+		if _, _, err := q.Bind(values); err == nil {
+			t.Errorf("Expected bind error for %v", values)
+		}
+	}
+}
+
+// TestSavedParamConvert verifies JSON values are checked against the declared type
+func TestSavedParamConvert(t *testing.T) {
+	tests := []struct {
+		typ      string
+		in       interface{}
+		expected interface{}
+		ok       bool
+	}{
+		{"string", "x", "x", true},
+		{"string", float64(1), nil, false},
+		{"integer", "17", int64(17), true},
+		{"number", float64(1.5), 1.5, true},
+		{"boolean", true, true, true},
+		{"boolean", "true", nil, false},
+		{"datetime", "2026-03-01T10:00:00Z", "2026-03-01 10:00:00", true},
+	}
+
+	for _, tt := range tests {
+		got, err := mysql.SavedParam{Name: "p", Type: tt.typ}.Convert(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s(%v): expected ok=%v, got err %v", tt.typ, tt.in, tt.ok, err)
+			continue
+		}
+		if tt.ok && got != tt.expected {
+			t.Errorf("%s(%v): expected %v, got %v", tt.typ, tt.in, tt.expected, got)
+		}
+	}
+}
+
+// TestLoadSavedQueries verifies bad files are skipped and the tool is listed
+func TestLoadSavedQueries(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"orders_since.sql": ordersSinceSQL,
+		"broken.sql":       "SELECT 1",
+		"notes.txt":        "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lib, err := mysql.LoadSavedQueries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := lib.List(); len(list) != 1 || list[0].Name != "orders_since" {
+		t.Fatalf("Expected only orders_since to load, got %v", list)
+	}
+
+	SavedQueries = lib
+	defer func() { SavedQueries = nil }()
+
+	found := false
+	for _, tool := range getToolsList() {
+		if tool.Name == "run_saved_query" {
+			found = strings.Contains(tool.Description, "orders_since(customer_id integer, since date)")
+		}
+	}
+	if !found {
+		t.Errorf("Expected run_saved_query to list orders_since")
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Minimal JSON Schema support for config-defined tool arguments.
+//
+// Only the keywords below are understood. Schemas using anything else are
+// rejected when the tools file is loaded, so an operator never believes a
+// constraint is enforced when it is silently ignored.
+
+var schemaKeywords = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"enum": true, "minimum": true, "maximum": true, "minLength": true, "maxLength": true,
+	"pattern": true, "items": true, "minItems": true, "maxItems": true,
+	"description": true, "title": true, "default": true,
+}
+
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "integer": true,
+	"number": true, "boolean": true, "null": true,
+}
+
+// checkSchema verifies a schema only uses supported keywords and compiles
+// its patterns. path is used in error messages.
+func checkSchema(schema map[string]interface{}, path string) error {
+	for k := range schema {
+		if !schemaKeywords[k] {
+			return fmt.Errorf("%s: unsupported schema keyword %q", path, k)
+		}
+	}
+	if t, ok := schema["type"]; ok {
+		name, ok := t.(string)
+		if !ok || !schemaTypes[name] {
+			return fmt.Errorf("%s: type must be one of object, array, string, integer, number, boolean, null", path)
+		}
+	}
+	if p, ok := schema["pattern"]; ok {
+		s, ok := p.(string)
+		if !ok {
+			return fmt.Errorf("%s: pattern must be a string", path)
+		}
+		if _, err := regexp.Compile(s); err != nil {
+			return fmt.Errorf("%s: invalid pattern: %v", path, err)
+		}
+	}
+	if e, ok := schema["enum"]; ok {
+		// validateSchema compares entries with ==, which panics on arrays
+		// and objects.
+		values, ok := e.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: enum must be an array", path)
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case nil, string, float64, bool:
+			default:
+				return fmt.Errorf("%s: enum entries must be strings, numbers, booleans or null, got %s", path, jsonTypeName(v))
+			}
+		}
+	}
+	if r, ok := schema["required"]; ok {
+		if _, ok := r.([]interface{}); !ok {
+			return fmt.Errorf("%s: required must be an array", path)
+		}
+	}
+	if props, ok := schema["properties"]; ok {
+		m, ok := props.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: properties must be an object", path)
+		}
+		for name, sub := range m {
+			subSchema, ok := sub.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("%s.%s: schema must be an object", path, name)
+			}
+			if err := checkSchema(subSchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		subSchema, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.items: schema must be an object", path)
+		}
+		if err := checkSchema(subSchema, path+"[]"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSchema checks value against schema. Errors name the offending
+// argument so the caller can correct it.
+func validateSchema(schema map[string]interface{}, value interface{}, path string) error {
+	if t, ok := schema["type"].(string); ok && !schemaTypeMatches(t, value) {
+		return fmt.Errorf("%s must be %s, got %s", path, t, jsonTypeName(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := schema["minLength"].(float64); ok && n < min {
+			return fmt.Errorf("%s must be at least %v characters", path, min)
+		}
+		if max, ok := schema["maxLength"].(float64); ok && n > max {
+			return fmt.Errorf("%s must be at most %v characters", path, max)
+		}
+		if p, ok := schema["pattern"].(string); ok && !regexp.MustCompile(p).MatchString(v) {
+			return fmt.Errorf("%s must match %s", path, p)
+		}
+
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			return fmt.Errorf("%s must be >= %v", path, min)
+		}
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			return fmt.Errorf("%s must be <= %v", path, max)
+		}
+
+	case []interface{}:
+		if min, ok := schema["minItems"].(float64); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s must have at least %v items", path, min)
+		}
+		if max, ok := schema["maxItems"].(float64); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s must have at most %v items", path, max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := v[name]; !present {
+					return fmt.Errorf("missing required argument %s", joinPath(path, name))
+				}
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, known := props[name].(map[string]interface{})
+			if !known {
+				if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+					return fmt.Errorf("unknown argument %s", joinPath(path, name))
+				}
+				continue
+			}
+			if err := validateSchema(sub, v[name], joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func schemaTypeMatches(t string, value interface{}) bool {
+	switch t {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonTypeName(value) == t
+	}
+}
+
+// jsonTypeName names the JSON type of a value decoded by encoding/json.
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return strings.TrimSuffix(path, ".") + "." + name
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestReferencedTables verifies table extraction across statement shapes
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+		ok       bool
+	}{
+		{"Simple select", "SELECT * FROM users", []string{"users"}, true},
+		{"Alias", "SELECT u.id FROM users AS u WHERE u.id = 1", []string{"users"}, true},
+		{"Comma join", "SELECT * FROM a, b x, `c d`", []string{"a", "b", "c d"}, true},
+		{"Joins", "SELECT * FROM a LEFT JOIN b ON a.id=b.aid INNER JOIN c USING (id) STRAIGHT_JOIN d", []string{"a", "b", "c", "d"}, true},
+		{"Qualified", "SELECT * FROM shop.orders o JOIN `crm`.`customers` c ON o.cid = c.id", []string{"shop.orders", "crm.customers"}, true},
+		{"Subquery in WHERE", "SELECT * FROM a WHERE id IN (SELECT aid FROM b)", []string{"a", "b"}, true},
+		{"Derived table then comma", "SELECT * FROM (SELECT * FROM a) x, b", []string{"a", "b"}, true},
+		{"Parenthesized join", "SELECT * FROM (a JOIN b ON a.id = b.id) LEFT JOIN c ON c.id = a.id", []string{"a", "b", "c"}, true},
+		{"CTE excluded", "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent JOIN users ON 1", []string{"orders", "users"}, true},
+		{"Recursive CTE", "WITH RECURSIVE t(n) AS (SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < 5) SELECT * FROM t", nil, true},
+		{"Function FROM ignored", "SELECT EXTRACT(YEAR FROM created_at), TRIM(BOTH 'x' FROM name) FROM events", []string{"events"}, true},
+		{"Dual", "SELECT 1 FROM DUAL", nil, true},
+		{"No tables", "SELECT @@version, NOW()", nil, true},
+		{"Index hint", "SELECT * FROM a USE INDEX (idx_a) JOIN b FORCE INDEX FOR JOIN (idx_b) ON 1", []string{"a", "b"}, true},
+		{"Union", "SELECT id FROM a UNION SELECT id FROM b", []string{"a", "b"}, true},
+		{"Insert values", "INSERT INTO logs (msg) VALUES ('from x')", []string{"logs"}, true},
+		{"Insert without INTO", "INSERT IGNORE logs SET msg = 'x'", []string{"logs"}, true},
+		{"Insert select", "INSERT INTO archive SELECT * FROM orders WHERE year < 2020", []string{"archive", "orders"}, true},
+		{"Upsert", "INSERT INTO t (id, n) VALUES (1, 2) ON DUPLICATE KEY UPDATE n = n + 1", []string{"t"}, true},
+		{"Replace", "REPLACE INTO t VALUES (1)", []string{"t"}, true},
+		{"Replace function", "SELECT REPLACE(name, 'a', 'b') FROM t", []string{"t"}, true},
+		{"Update", "UPDATE LOW_PRIORITY users SET active = 0 WHERE id = 1", []string{"users"}, true},
+		{"Multi-table update", "UPDATE orders o JOIN customers c ON o.cid = c.id SET o.vip = 1", []string{"orders", "customers"}, true},
+		{"Multi-table delete", "DELETE o FROM orders o JOIN customers c ON o.cid = c.id WHERE c.banned", []string{"orders", "customers"}, true},
+		{"Delete using", "DELETE FROM t1, t2 USING t1 INNER JOIN t2 INNER JOIN t3 WHERE t1.id = t2.id", []string{"t1", "t2", "t3"}, true},
+		{"Select for update", "SELECT * FROM t WHERE id = 1 FOR UPDATE", []string{"t"}, true},
+		{"Comment hides nothing", "SELECT * /* FROM secret */ FROM t -- JOIN other", []string{"t"}, true},
+		{"Executable comment", "SELECT * FROM t /*! JOIN secret ON 1 */", []string{"t", "secret"}, true},
+		{"Versioned executable comment not determinable", "SELECT * FROM t /*!50000 JOIN secret ON 1 */", nil, false},
+		{"MariaDB executable comment not determinable", "SELECT * FROM t /*M! , secret */", nil, false},
+		{"Double dash without space is minus", "SELECT * FROM t WHERE 0--0 UNION SELECT * FROM secret", []string{"t", "secret"}, true},
+		{"Table statement", "TABLE users ORDER BY id LIMIT 5", []string{"users"}, true},
+		{"Table in subquery", "SELECT * FROM a WHERE EXISTS(TABLE b) AND id IN (TABLE c)", []string{"a", "b", "c"}, true},
+		{"Table in union", "SELECT * FROM a UNION TABLE b", []string{"a", "b"}, true},
+		{"Table as derived table", "SELECT * FROM (TABLE b) x", []string{"b"}, true},
+		{"Insert table", "INSERT INTO a (id) TABLE b", []string{"a", "b"}, true},
+		{"CTE then table", "WITH c AS (TABLE b) TABLE c", []string{"b"}, true},
+		{"Analyze table", "ANALYZE TABLE a, b", []string{"a", "b"}, true},
+		{"Create as table", "CREATE TABLE a AS TABLE b", []string{"a", "b"}, true},
+		{"Describe", "DESCRIBE users", []string{"users"}, true},
+		{"Explain select", "EXPLAIN SELECT * FROM users", []string{"users"}, true},
+		{"Show columns", "SHOW FULL COLUMNS FROM users", []string{"users"}, true},
+		{"Show create table", "SHOW CREATE TABLE shop.users", []string{"shop.users"}, true},
+		{"Show variables", "SHOW VARIABLES LIKE 'max%'", nil, true},
+		{"Show tables not determinable", "SHOW TABLES", nil, false},
+		{"Call not determinable", "CALL do_things()", nil, false},
+		{"Table function not determinable", "SELECT * FROM JSON_TABLE('[1]', '$[*]' COLUMNS (x INT PATH '$')) j", nil, false},
+		{"Unclosed quote", "SELECT * FROM t WHERE a = 'x", nil, false},
+		{"Drop tables", "DROP TABLE IF EXISTS a, b", []string{"a", "b"}, true},
+		{"Create like", "CREATE TABLE a LIKE b", []string{"a", "b"}, true},
+		{"Truncate", "TRUNCATE TABLE logs", []string{"logs"}, true},
+		{"Rename", "RENAME TABLE a TO b", []string{"a", "b"}, true},
+		{"Create index", "CREATE INDEX idx ON orders (cid)", []string{"orders"}, true},
+		{"Create view not determinable", "CREATE VIEW v AS SELECT * FROM t", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, ok := mysql.ReferencedTables(tt.sql)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v (tables %v)", tt.ok, ok, refs)
+			}
+			if !ok {
+				return
+			}
+			var got []string
+			for _, r := range refs {
+				got = append(got, r.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestNormalizeSQL verifies normalization ignores layout but not literals
+func TestNormalizeSQL(t *testing.T) {
+	a, _ := mysql.NormalizeSQL("select *  from users\n WHERE name = 'x' -- trailing")
+	b, _ := mysql.NormalizeSQL("SELECT * FROM users where name = 'x'")
+	if a != b {
+		t.Errorf("Expected equal normal forms:\n%q\n%q", a, b)
+	}
+
+	c, _ := mysql.NormalizeSQL("SELECT * FROM users WHERE name = '#1'")
+	d, _ := mysql.NormalizeSQL("SELECT * FROM users WHERE name = '#2'")
+	if c == d {
+		t.Errorf("Literals must not be normalized away: %q", c)
+	}
+
+	if _, ok := mysql.NormalizeSQL("SELECT 'unterminated"); ok {
+		t.Errorf("Unterminated literal should not normalize cleanly")
+	}
+}
+
+// TestSQLComments verifies which comments the tokenizer skips and which it
+// reads as code
+func TestSQLComments(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		normalized string
+		ok         bool
+	}{
+		{"Double dash and space", "SELECT 1 -- note", "SELECT 1", true},
+		{"Double dash and tab", "SELECT 1 --\tnote\n+ 1", "SELECT 1 + 1", true},
+		{"Double dash at end", "SELECT 1 --", "SELECT 1", true},
+		{"Double dash without space", "SELECT 0--0", "SELECT 0 - - 0", true},
+		{"Double dash before word", "SELECT 1 --x", "SELECT 1 - - x", true},
+		{"Hash", "SELECT 1 # note\n+ 1", "SELECT 1 + 1", true},
+		{"Block", "SELECT /* note */ 1", "SELECT 1", true},
+		{"Optimizer hint", "SELECT /*+ MAX_EXECUTION_TIME(1) */ 1", "SELECT 1", true},
+		{"Executable", "SELECT 1 /*! + 1 */", "SELECT 1 + 1", true},
+		{"Versioned executable", "SELECT 1 /*!50000 + 1 */", "SELECT 1 + 1", false},
+		{"MariaDB executable", "SELECT 1 /*M! + 1 */", "SELECT 1 + 1", false},
+		{"MariaDB versioned executable", "SELECT 1 /*M!100500 + 1 */", "SELECT 1 + 1", false},
+		{"Unterminated block", "SELECT 1 /* note", "SELECT 1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mysql.NormalizeSQL(tt.sql)
+			if got != tt.normalized || ok != tt.ok {
+				t.Errorf("Expected %q (ok=%v), got %q (ok=%v)", tt.normalized, tt.ok, got, ok)
+			}
+		})
+	}
+}
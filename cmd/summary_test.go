@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// summarizeFake runs SummarizeRows over n rows from the fake driver
+func summarizeFake(t *testing.T, n string, maxRows int64) *mysql.ResultSummary {
+	t.Helper()
+	rows, err := openFakeRows(t).Query(n)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+
+	summary, err := mysql.SummarizeRows(rows, maxRows)
+	if err != nil {
+		t.Fatalf("SummarizeRows: %v", err)
+	}
+	return summary
+}
+
+// TestSummarizeRows verifies the per-column statistics over a full result
+func TestSummarizeRows(t *testing.T) {
+	s := summarizeFake(t, "250", 0)
+	if s.RowCount != 250 || s.Truncated {
+		t.Fatalf("Expected 250 rows untruncated, got %d (truncated=%v)", s.RowCount, s.Truncated)
+	}
+
+	id, name, email, created := s.Columns[0], s.Columns[1], s.Columns[2], s.Columns[3]
+
+	if id.Kind != mysql.KindNumber || id.Min != "0" || id.Max != "249" || id.Mean != 124.5 {
+		t.Errorf("Unexpected id stats: %+v", id)
+	}
+	if want := math.Sqrt(250 * 251 / 12.0); math.Abs(id.Stddev-want) > 1e-9 {
+		t.Errorf("Expected id stddev %v, got %v", want, id.Stddev)
+	}
+	if name.Distinct != 250 || !name.DistinctExact {
+		t.Errorf("Expected 250 exact distinct names, got %d (exact=%v)", name.Distinct, name.DistinctExact)
+	}
+	if email.Kind != mysql.KindString || email.Distinct != 1 || len(email.Top) != 1 || email.Top[0].Count != 250 {
+		t.Errorf("Unexpected email stats: %+v", email)
+	}
+	if email.MinLength != 20 || email.MaxLength != 20 {
+		t.Errorf("Expected email length 20, got %d-%d", email.MinLength, email.MaxLength)
+	}
+	if created.Kind != mysql.KindDate || created.Min != "2026-01-01 00:00:00" || created.Min != created.Max {
+		t.Errorf("Unexpected created_at stats: %+v", created)
+	}
+}
+
+// TestSummarizeDistinctEstimate verifies the sketch stays close past its exact range
+func TestSummarizeDistinctEstimate(t *testing.T) {
+	s := summarizeFake(t, "50000", 0)
+	name := s.Columns[1]
+	if name.DistinctExact {
+		t.Fatal("Expected an estimate for 50000 distinct values")
+	}
+	if err := math.Abs(float64(name.Distinct)-50000) / 50000; err > 0.1 {
+		t.Errorf("Distinct estimate %d is %.0f%% off", name.Distinct, err*100)
+	}
+}
+
+// TestSummarizeMaxRows verifies SUMMARY_MAX_ROWS stops the read
+func TestSummarizeMaxRows(t *testing.T) {
+	s := summarizeFake(t, "100", 40)
+	if s.RowCount != 40 || !s.Truncated {
+		t.Fatalf("Expected 40 rows truncated, got %d (truncated=%v)", s.RowCount, s.Truncated)
+	}
+
+	out := formatResultSummary(s)
+	for _, want := range []string{"Summary of 40 rows (5 columns)", "• id (number): count 40; nulls 0; distinct 40; min 0; max 39; mean 19.5",
+		"• email (string): count 40; nulls 0; distinct 1; length 20-20; top: customer@example.com (40)", "truncated: statistics cover the first 40 rows"} {
+		if !contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
@@ -298,6 +298,84 @@ func TestTimeoutDetailsNil(t *testing.T) {
 	}
 }
 
+// TestTimeoutConfigFromEnv verifies every profile can be overridden from the environment
+func TestTimeoutConfigFromEnv(t *testing.T) {
+	t.Setenv("TIMEOUT_DEFAULT", "40")
+	t.Setenv("TIMEOUT_QUERY", "90s")
+	t.Setenv("TIMEOUT_LONG_QUERY", "10m")
+	t.Setenv("TIMEOUT_WRITE", "2m")
+	t.Setenv("TIMEOUT_ADMIN", "120")
+	t.Setenv("TIMEOUT_CONNECTION", "3s")
+
+	tc := mysql.NewTimeoutConfig()
+
+	tests := []struct {
+		profile  mysql.TimeoutProfile
+		expected time.Duration
+	}{
+		{mysql.ProfileDefault, 40 * time.Second},
+		{mysql.ProfileQuery, 90 * time.Second},
+		{mysql.ProfileLongQuery, 10 * time.Minute},
+		{mysql.ProfileWrite, 2 * time.Minute},
+		{mysql.ProfileAdmin, 2 * time.Minute},
+		{mysql.ProfileConnection, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := tc.GetTimeout(tt.profile); got != tt.expected {
+			t.Errorf("Profile %s: expected %v, got %v", tt.profile, tt.expected, got)
+		}
+	}
+}
+
+// TestTimeoutConfigInvalidEnv verifies bad values fall back to the defaults
+func TestTimeoutConfigInvalidEnv(t *testing.T) {
+	t.Setenv("TIMEOUT_QUERY", "soon")
+	t.Setenv("TIMEOUT_WRITE", "-5")
+	t.Setenv("TIMEOUT_ADMIN", "48h")
+
+	tc := mysql.NewTimeoutConfig()
+
+	if tc.Query != 30*time.Second {
+		t.Errorf("Unparseable value should keep default, got %v", tc.Query)
+	}
+	if tc.Write != 60*time.Second {
+		t.Errorf("Negative value should keep default, got %v", tc.Write)
+	}
+	if tc.Admin != 15*time.Second {
+		t.Errorf("Out-of-range value should keep default, got %v", tc.Admin)
+	}
+}
+
+// TestRequestedTimeout verifies per-call timeouts are capped by LongQuery
+func TestRequestedTimeout(t *testing.T) {
+	tc := mysql.NewTimeoutConfig()
+
+	tests := []struct {
+		name      string
+		requested time.Duration
+		expected  time.Duration
+	}{
+		{"Zero uses profile", 0, 30 * time.Second},
+		{"Negative uses profile", -1, 30 * time.Second},
+		{"Shorter than profile", 5 * time.Second, 5 * time.Second},
+		{"Longer than profile", 2 * time.Minute, 2 * time.Minute},
+		{"Capped at long query", time.Hour, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tc.RequestedTimeout(mysql.ProfileQuery, tt.requested); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if tc.Longest() != 5*time.Minute {
+		t.Errorf("Longest should be the long query timeout, got %v", tc.Longest())
+	}
+}
+
 // containsString checks if a string contains a substring (avoids duplicate helper)
 func containsString(str, substr string) bool {
 	for i := 0; i < len(str)-len(substr)+1; i++ {
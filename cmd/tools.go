@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	mysql "mcp-gp-mysql/internal"
 )
@@ -17,11 +18,23 @@ type ToolDefinition struct {
 
 // getToolsList returns the list of available tools
 func getToolsList() []ToolDefinition {
+	tools := builtinTools()
+	if queries := SavedQueries.List(); len(queries) > 0 {
+		tools = append(tools, savedQueryTool(queries))
+	}
+	for _, t := range CustomTools {
+		tools = append(tools, t.definition())
+	}
+	return tools
+}
+
+// builtinTools returns the tools that are always available
+func builtinTools() []ToolDefinition {
 	return []ToolDefinition{
 		{
 			Name:        "query",
 			Title:       "Query Database",
-			Description: "Execute a SELECT query on the MySQL database. Only SELECT queries are allowed for safety.",
+			Description: "Execute a SELECT query on the MySQL database. Only SELECT queries are allowed for safety; the query runs inside a read-only transaction that is always rolled back. A SELECT without LIMIT gets LIMIT AUTO_LIMIT automatically and the response says whether more rows exist.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -29,6 +42,26 @@ func getToolsList() []ToolDefinition {
 						"type":        "string",
 						"description": "The SELECT SQL query to execute",
 					},
+					"timeout_seconds": map[string]interface{}{
+						"type":        "integer",
+						"description": "Timeout for this query in seconds (default: TIMEOUT_QUERY, capped at TIMEOUT_LONG_QUERY)",
+					},
+					"no_limit": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Do not add the automatic LIMIT to a SELECT without one (MAX_RESULT_ROWS still applies)",
+					},
+					"binary_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Return this column's values as image or base64 resource content (PNG/JPEG/GIF BLOBs are returned as images automatically)",
+					},
+					"summarize": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return per-column statistics over the full result (count, nulls, distinct, min/max, mean/stddev, top values) instead of rows",
+					},
+					"transaction": map[string]interface{}{
+						"type":        "string",
+						"description": "Handle from begin_transaction: run inside that open transaction and see its uncommitted writes",
+					},
 				},
 				"required": []string{"sql"},
 			},
@@ -36,7 +69,7 @@ func getToolsList() []ToolDefinition {
 		{
 			Name:        "execute",
 			Title:       "Execute Statement",
-			Description: "Execute an INSERT, UPDATE, or DELETE query inside a transaction. Operations affecting more than MAX_SAFE_ROWS rows require confirm_key; without it the transaction is rolled back and no changes persist.",
+			Description: "Execute an INSERT, UPDATE, or DELETE query inside a transaction. Operations affecting more than MAX_SAFE_ROWS rows require confirm_key; without it the transaction is rolled back and no changes persist. Non-transactional tables (MyISAM, MEMORY, ...) cannot roll back, so their writes are gated before they run (NONTX_WRITE_POLICY). dry_run=true previews the change (affected rows, before/after rows) and always rolls back.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -46,7 +79,23 @@ func getToolsList() []ToolDefinition {
 					},
 					"confirm_key": map[string]interface{}{
 						"type":        "string",
-						"description": "Safety confirmation key for large operations",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+					"dry_run": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Run the statement, report affected rows and before/after images of a sample of the changed rows, then roll back",
+					},
+					"allow_full_table": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow an UPDATE/DELETE without a row-selecting WHERE when REQUIRE_WHERE=true; needs confirm_key",
+					},
+					"reason": map[string]interface{}{
+						"type":        "string",
+						"description": "Why the change is needed; shown to the human approver when writes need approval",
+					},
+					"transaction": map[string]interface{}{
+						"type":        "string",
+						"description": "Handle from begin_transaction: run inside that open transaction without committing (MAX_SAFE_ROWS is checked at commit, or up front for non-transactional tables)",
 					},
 				},
 				"required": []string{"sql"},
@@ -145,10 +194,189 @@ func getToolsList() []ToolDefinition {
 						"type":        "integer",
 						"description": "Number of rows to return (default: 10, max: 100)",
 					},
+					"binary_column": map[string]interface{}{
+						"type":        "string",
+						"description": "Return this column's values as image or base64 resource content (PNG/JPEG/GIF BLOBs are returned as images automatically)",
+					},
 				},
 				"required": []string{"table"},
 			},
 		},
+		{
+			Name:        "execute_batch",
+			Title:       "Execute Batch",
+			Description: "Execute several INSERT/UPDATE/DELETE statements atomically in one transaction. Each statement is validated separately; the first failure rolls everything back. The MAX_SAFE_ROWS rule applies to the total rows affected.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"statements": map[string]interface{}{
+						"type":        "array",
+						"description": "Statements to run in order, one per element",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"sql": map[string]interface{}{
+									"type":        "string",
+									"description": "One INSERT, UPDATE, DELETE or REPLACE statement, with ? placeholders",
+								},
+								"params": map[string]interface{}{
+									"type":        "array",
+									"description": "Values for the ? placeholders, in order",
+								},
+							},
+							"required": []string{"sql"},
+						},
+					},
+					"confirm_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+					"allow_full_table": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Allow UPDATE/DELETE statements without a row-selecting WHERE when REQUIRE_WHERE=true; needs confirm_key",
+					},
+				},
+				"required": []string{"statements"},
+			},
+		},
+		{
+			Name:        "bulk_insert",
+			Title:       "Bulk Insert",
+			Description: "Insert many rows into one table from a JSON array of rows or inline CSV. Columns are checked against the table, rows are sent as multi-row prepared INSERTs in one transaction, and MAX_SAFE_ROWS applies to the total.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"table": map[string]interface{}{
+						"type":        "string",
+						"description": "Target table in the current database",
+					},
+					"columns": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Column names, in the order of the values in each row. Optional with csv, whose first line is then the header",
+					},
+					"rows": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "array"},
+						"description": "Rows as arrays of values (null for NULL)",
+					},
+					"csv": map[string]interface{}{
+						"type":        "string",
+						"description": "Rows as CSV text instead of rows; \\N is NULL",
+					},
+					"on_duplicate": map[string]interface{}{
+						"type":        "string",
+						"enum":        []string{"error", "ignore", "update"},
+						"description": "On a duplicate key: error (default, roll back everything), ignore (skip the row) or update (overwrite the non-key columns)",
+					},
+					"confirm_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+				},
+				"required": []string{"table"},
+			},
+		},
+		{
+			Name:        "begin_transaction",
+			Title:       "Begin Transaction",
+			Description: "Start a transaction that spans several query/execute calls. Returns a handle to pass as 'transaction'; finish with commit or rollback. Idle transactions are rolled back automatically.",
+			InputSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{},
+			},
+		},
+		{
+			Name:        "commit",
+			Title:       "Commit Transaction",
+			Description: "Commit a transaction started with begin_transaction. If its writes affected more than MAX_SAFE_ROWS rows, confirm_key is required; without it the transaction stays open.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"transaction": map[string]interface{}{
+						"type":        "string",
+						"description": "Handle returned by begin_transaction",
+					},
+					"confirm_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+				},
+				"required": []string{"transaction"},
+			},
+		},
+		{
+			Name:        "rollback",
+			Title:       "Roll Back Transaction",
+			Description: "Discard every change made in a transaction started with begin_transaction.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"transaction": map[string]interface{}{
+						"type":        "string",
+						"description": "Handle returned by begin_transaction",
+					},
+				},
+				"required": []string{"transaction"},
+			},
+		},
+		{
+			Name:        "revert_operation",
+			Title:       "Revert Operation",
+			Description: "Undo a committed execute by the operation ID it returned (requires UNDO_JOURNAL_DIR). Restores the journaled before-images in one transaction, and refuses if any of the rows changed since. MAX_SAFE_ROWS applies to the restored rows.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"operation_id": map[string]interface{}{
+						"type":        "string",
+						"description": "Operation ID returned by execute (op_...)",
+					},
+					"confirm_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+				},
+				"required": []string{"operation_id"},
+			},
+		},
+		{
+			Name:        "operation_status",
+			Title:       "Operation Status",
+			Description: "Check a write queued for human approval (APPROVAL_QUEUE_FILE): pending, executed, failed or rejected, with the result or the reason. Poll it with the pending ID execute returned.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Pending ID returned by execute (ap_...)",
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
+		{
+			Name:        "call_procedure",
+			Title:       "Call Stored Procedure",
+			Description: "Call a stored procedure in the current database and return every result set plus OUT/INOUT values. Procedures declared READS SQL DATA or NO SQL run read-only; others run in a transaction under the MAX_SAFE_ROWS rule and need confirm_key for large writes.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"procedure": map[string]interface{}{
+						"type":        "string",
+						"description": "The procedure name",
+					},
+					"args": map[string]interface{}{
+						"type":        "object",
+						"description": "IN and INOUT arguments by parameter name (null for NULL); OUT parameters are returned, not passed",
+					},
+					"confirm_key": map[string]interface{}{
+						"type":        "string",
+						"description": "Safety key for large operations, or the one-time token returned by the previous attempt when CONFIRMATION_MODE=token",
+					},
+				},
+				"required": []string{"procedure"},
+			},
+		},
 		{
 			Name:        "database_info",
 			Title:       "Database Info",
@@ -162,50 +390,91 @@ func getToolsList() []ToolDefinition {
 }
 
 // callClientMethod routes tool calls to the appropriate client method
-func callClientMethod(client *mysql.Client, toolName string, args map[string]interface{}) (string, error) {
+func callClientMethod(client *mysql.Client, toolName string, args map[string]interface{}) ([]ContentItem, error) {
 	switch toolName {
 	case "query":
 		return handleQuery(client, args)
 	case "execute":
-		return handleExecute(client, args)
+		return textContent(handleExecute(client, args))
 	case "tables":
-		return handleTables(client)
+		return textContent(handleTables(client))
 	case "describe":
-		return handleDescribe(client, args)
+		return textContent(handleDescribe(client, args))
 	case "views":
-		return handleViews(client)
+		return textContent(handleViews(client))
 	case "indexes":
-		return handleIndexes(client, args)
+		return textContent(handleIndexes(client, args))
 	case "explain":
-		return handleExplain(client, args)
+		return textContent(handleExplain(client, args))
 	case "count":
-		return handleCount(client, args)
+		return textContent(handleCount(client, args))
 	case "sample":
 		return handleSample(client, args)
 	case "database_info":
-		return handleDatabaseInfo(client)
+		return textContent(handleDatabaseInfo(client))
+	case "bulk_insert":
+		return textContent(handleBulkInsert(client, args))
+	case "begin_transaction":
+		return textContent(handleBeginTransaction(client))
+	case "commit":
+		return textContent(handleCommit(client, args))
+	case "rollback":
+		return textContent(handleRollback(client, args))
+	case "execute_batch":
+		return textContent(handleExecuteBatch(client, args))
+	case "revert_operation":
+		return textContent(handleRevertOperation(client, args))
+	case "operation_status":
+		return textContent(handleOperationStatus(client, args))
+	case "call_procedure":
+		return textContent(handleCallProcedure(client, args))
+	case "run_saved_query":
+		return handleRunSavedQuery(client, args)
 	default:
-		return "", fmt.Errorf("unknown tool: %s", toolName)
+		if tool := findCustomTool(toolName); tool != nil {
+			return handleCustomTool(client, tool, args)
+		}
+		return nil, fmt.Errorf("unknown tool: %s", toolName)
 	}
 }
 
 // handleQuery executes a SELECT query
-func handleQuery(client *mysql.Client, args map[string]interface{}) (string, error) {
+func handleQuery(client *mysql.Client, args map[string]interface{}) ([]ContentItem, error) {
 	sql, err := getStringArg(args, "sql")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !isReadOnlyQuery(sql) {
-		return "", fmt.Errorf("only SELECT, WITH (CTE), and SHOW queries are allowed. Use 'execute' for modifications")
+		return nil, fmt.Errorf("only SELECT, WITH (CTE), and SHOW queries are allowed. Use 'execute' for modifications")
+	}
+
+	if getBoolArg(args, "summarize", false) {
+		summary, err := client.Summarize(sql, mysql.QueryOptions{
+			Timeout:  time.Duration(getIntArg(args, "timeout_seconds", 0)) * time.Second,
+			CostGate: true,
+			Tx:       getOptionalString(args, "transaction", ""),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return textContent(formatResultSummary(summary), nil)
+	}
+
+	opts := mysql.QueryOptions{
+		Timeout:   time.Duration(getIntArg(args, "timeout_seconds", 0)) * time.Second,
+		Cache:     mysql.CacheQuery,
+		AutoLimit: !getBoolArg(args, "no_limit", false),
+		CostGate:  true,
+		Tx:        getOptionalString(args, "transaction", ""),
 	}
 
-	result, err := client.Query(sql)
+	result, err := client.QueryWithOptions(sql, opts)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return formatQueryResultStructured(result), nil
+	return resultContent(result, getOptionalString(args, "binary_column", ""))
 }
 
 // handleExecute runs INSERT, UPDATE, DELETE queries
@@ -217,23 +486,57 @@ func handleExecute(client *mysql.Client, args map[string]interface{}) (string, e
 
 	confirmKey := getOptionalString(args, "confirm_key", "")
 
-	result, err := client.Execute(sql, confirmKey)
+	txID := getOptionalString(args, "transaction", "")
+	if txID != "" {
+		if getBoolArg(args, "dry_run", false) {
+			return "", fmt.Errorf("dry_run cannot be combined with transaction; the transaction itself is not committed until commit")
+		}
+		result, err := client.ExecuteInTransaction(txID, sql, confirmKey, execOptions(args))
+		if err != nil {
+			return "", err
+		}
+		return formatWriteResult(result), nil
+	}
+
+	if getBoolArg(args, "dry_run", false) {
+		preview, err := client.DryRun(sql)
+		if err != nil {
+			return "", err
+		}
+		return formatDryRunResult(preview), nil
+	}
+
+	if client.ApprovalMode() {
+		op, err := client.RequestApproval(sql, execOptions(args), requesterName(), getOptionalString(args, "reason", ""))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Queued for human approval as %s; nothing was executed yet. Poll operation_status with id=%s for the decision.\n\n%s",
+			op.ID, op.ID, formatPendingOperation(op)), nil
+	}
+
+	result, err := client.ExecuteWithOptions(sql, confirmKey, execOptions(args))
 	if err != nil {
 		return "", err
 	}
 
-	return result.Message, nil
+	return formatWriteResult(result), nil
+}
+
+// execOptions reads the per-call write options.
+func execOptions(args map[string]interface{}) mysql.ExecOptions {
+	return mysql.ExecOptions{AllowFullTable: getBoolArg(args, "allow_full_table", false)}
 }
 
 // handleTables lists all tables
 func handleTables(client *mysql.Client) (string, error) {
-	tables, err := client.ListTables()
+	tables, hit, err := client.ListTables()
 	if err != nil {
 		return "", err
 	}
 
 	if len(tables) == 0 {
-		return "No tables found in the database.", nil
+		return "No tables found in the database." + cacheNote(hit), nil
 	}
 
 	var sb strings.Builder
@@ -246,6 +549,7 @@ func handleTables(client *mysql.Client) (string, error) {
 			sb.WriteString(fmt.Sprintf("  Comment: %s\n", t.Comment))
 		}
 	}
+	sb.WriteString(cacheNote(hit))
 
 	return sb.String(), nil
 }
@@ -257,7 +561,7 @@ func handleDescribe(client *mysql.Client, args map[string]interface{}) (string,
 		return "", err
 	}
 
-	columns, err := client.DescribeTable(table)
+	columns, hit, err := client.DescribeTable(table)
 	if err != nil {
 		return "", err
 	}
@@ -284,18 +588,14 @@ func handleDescribe(client *mysql.Client, args map[string]interface{}) (string,
 			sb.WriteString(fmt.Sprintf("  Extra: %s\n", col.Extra))
 		}
 	}
+	sb.WriteString(cacheNote(hit))
 
 	return sb.String(), nil
 }
 
 // handleViews lists all views
 func handleViews(client *mysql.Client) (string, error) {
-	result, err := client.Query(`
-		SELECT TABLE_NAME as view_name, VIEW_DEFINITION as definition
-		FROM INFORMATION_SCHEMA.VIEWS
-		WHERE TABLE_SCHEMA = DATABASE()
-		ORDER BY TABLE_NAME
-	`)
+	result, err := client.ListViews()
 	if err != nil {
 		return "", err
 	}
@@ -314,18 +614,7 @@ func handleIndexes(client *mysql.Client, args map[string]interface{}) (string, e
 		return "", err
 	}
 
-	// Use prepared statement for safety
-	result, err := client.QueryPrepared(`
-		SELECT
-			INDEX_NAME,
-			COLUMN_NAME,
-			NON_UNIQUE,
-			SEQ_IN_INDEX,
-			CARDINALITY
-		FROM INFORMATION_SCHEMA.STATISTICS
-		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
-		ORDER BY INDEX_NAME, SEQ_IN_INDEX
-	`, table)
+	result, err := client.ListIndexes(table)
 	if err != nil {
 		return "", err
 	}
@@ -369,14 +658,14 @@ func handleCount(client *mysql.Client, args map[string]interface{}) (string, err
 	safeTable := sanitizeIdentifier(table)
 	query := "SELECT COUNT(*) as count FROM " + safeTable
 
-	result, err := client.Query(query)
+	result, err := client.QueryWithOptions(query, mysql.QueryOptions{Cache: mysql.CacheCount})
 	if err != nil {
 		return "", err
 	}
 
 	if result.RowCount > 0 && len(result.Rows) > 0 {
-		if count, ok := result.Rows[0]["count"]; ok {
-			return fmt.Sprintf("Count: %v rows", count), nil
+		if count, ok := result.RowMap(0)["count"]; ok {
+			return fmt.Sprintf("Count: %v rows", count) + cacheNote(result.Cache), nil
 		}
 	}
 
@@ -384,21 +673,166 @@ func handleCount(client *mysql.Client, args map[string]interface{}) (string, err
 }
 
 // handleSample gets sample rows from a table
-func handleSample(client *mysql.Client, args map[string]interface{}) (string, error) {
+func handleSample(client *mysql.Client, args map[string]interface{}) ([]ContentItem, error) {
 	table, err := getStringArg(args, "table")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	limit := getIntArgClamped(args, "limit", DefaultLimit, MinLimit, MaxSampleRows)
 
 	query := fmt.Sprintf("SELECT * FROM %s LIMIT %d", sanitizeIdentifier(table), limit)
 	result, err := client.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	return resultContent(result, getOptionalString(args, "binary_column", ""))
+}
+
+// handleBulkInsert loads rows from JSON or CSV into a table
+func handleBulkInsert(client *mysql.Client, args map[string]interface{}) (string, error) {
+	table, err := getStringArg(args, "table")
 	if err != nil {
 		return "", err
 	}
 
-	return formatQueryResultStructured(result), nil
+	columns, rows, err := getBulkRows(args)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := client.BulkInsert(table, columns, rows, getOptionalString(args, "on_duplicate", ""), getOptionalString(args, "confirm_key", ""))
+	if err != nil {
+		return "", err
+	}
+
+	return result.Message, nil
+}
+
+// handleBeginTransaction opens an interactive transaction
+func handleBeginTransaction(client *mysql.Client) (string, error) {
+	info, err := client.BeginTransaction()
+	if err != nil {
+		return "", err
+	}
+
+	if CompactMode {
+		return fmt.Sprintf("transaction: %s (idle timeout %s)", info.ID, info.IdleTimeout), nil
+	}
+	return fmt.Sprintf("Transaction %s started. Pass transaction=%q to query and execute to work inside it, then call commit or rollback. It is rolled back automatically after %s without activity.",
+		info.ID, info.ID, info.IdleTimeout), nil
+}
+
+// handleCommit commits an interactive transaction
+func handleCommit(client *mysql.Client, args map[string]interface{}) (string, error) {
+	id, err := getStringArg(args, "transaction")
+	if err != nil {
+		return "", err
+	}
+
+	info, err := client.Commit(id, getOptionalString(args, "confirm_key", ""))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Transaction %s committed: %d statements, %d rows affected.", info.ID, info.Statements, info.Affected), nil
+}
+
+// handleRollback rolls back an interactive transaction
+func handleRollback(client *mysql.Client, args map[string]interface{}) (string, error) {
+	id, err := getStringArg(args, "transaction")
+	if err != nil {
+		return "", err
+	}
+
+	info, err := client.Rollback(id)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Transaction %s rolled back: %d statements, %d rows discarded.", info.ID, info.Statements, info.Affected), nil
+}
+
+// handleExecuteBatch runs several write statements in one transaction
+func handleExecuteBatch(client *mysql.Client, args map[string]interface{}) (string, error) {
+	list, ok := args["statements"].([]interface{})
+	if !ok || len(list) == 0 {
+		return "", fmt.Errorf("missing or invalid 'statements' parameter")
+	}
+
+	stmts := make([]mysql.BatchStatement, len(list))
+	for i, item := range list {
+		switch v := item.(type) {
+		case string:
+			stmts[i].SQL = v
+		case map[string]interface{}:
+			sql, err := getStringArg(v, "sql")
+			if err != nil {
+				return "", fmt.Errorf("statement %d: %w", i+1, err)
+			}
+			params, _ := v["params"].([]interface{})
+			if stmts[i].Args, err = getSQLArgs(params); err != nil {
+				return "", fmt.Errorf("statement %d: %w", i+1, err)
+			}
+			stmts[i].SQL = sql
+		default:
+			return "", fmt.Errorf("statement %d must be an object with 'sql' and optional 'params'", i+1)
+		}
+	}
+
+	result, err := client.ExecuteBatch(stmts, getOptionalString(args, "confirm_key", ""), execOptions(args))
+	if err != nil {
+		return "", err
+	}
+
+	return formatBatchResult(result), nil
+}
+
+// handleRevertOperation undoes a journaled write
+func handleRevertOperation(client *mysql.Client, args map[string]interface{}) (string, error) {
+	id, err := getStringArg(args, "operation_id")
+	if err != nil {
+		return "", err
+	}
+
+	result, err := client.RevertOperation(id, getOptionalString(args, "confirm_key", ""))
+	if err != nil {
+		return "", err
+	}
+
+	return result.Message, nil
+}
+
+// handleOperationStatus reports a write queued for approval
+func handleOperationStatus(client *mysql.Client, args map[string]interface{}) (string, error) {
+	id, err := getStringArg(args, "id")
+	if err != nil {
+		return "", err
+	}
+
+	op, err := client.OperationStatus(id)
+	if err != nil {
+		return "", err
+	}
+
+	return formatPendingOperation(op), nil
+}
+
+// handleCallProcedure calls a stored procedure
+func handleCallProcedure(client *mysql.Client, args map[string]interface{}) (string, error) {
+	name, err := getStringArg(args, "procedure")
+	if err != nil {
+		return "", err
+	}
+
+	procArgs, _ := args["args"].(map[string]interface{})
+	result, err := client.CallProcedure(name, procArgs, getOptionalString(args, "confirm_key", ""))
+	if err != nil {
+		return "", err
+	}
+
+	return formatProcedureResult(result), nil
 }
 
 // handleDatabaseInfo gets database connection info
@@ -420,7 +854,7 @@ func handleDatabaseInfo(client *mysql.Client) (string, error) {
 		return "Could not retrieve database information.", nil
 	}
 
-	row := result.Rows[0]
+	row := result.RowMap(0)
 	var sb strings.Builder
 	sb.WriteString("Database Connection Info:\n\n")
 	sb.WriteString(fmt.Sprintf("• Version: %v\n", getMapValue(row, "version")))
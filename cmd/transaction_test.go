@@ -0,0 +1,173 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestTransactionHandles verifies unknown or missing handles are rejected before any SQL runs
+func TestTransactionHandles(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		args     map[string]interface{}
+		allowDDL bool
+		errPart  string
+	}{
+		{"Commit without handle", "commit", map[string]interface{}{}, false, "missing or invalid 'transaction'"},
+		{"Rollback without handle", "rollback", map[string]interface{}{}, false, "missing or invalid 'transaction'"},
+		{"Commit unknown handle", "commit", map[string]interface{}{"transaction": "tx_gone"}, false, `unknown transaction "tx_gone"`},
+		{"Rollback unknown handle", "rollback", map[string]interface{}{"transaction": "tx_gone"}, false, "expired after"},
+		{"Execute in unknown handle", "execute", map[string]interface{}{"sql": "DELETE FROM t WHERE id = 1", "transaction": "tx_gone"}, false, `unknown transaction "tx_gone"`},
+		{"Dry run inside transaction", "execute", map[string]interface{}{"sql": "DELETE FROM t WHERE id = 1", "transaction": "tx_gone", "dry_run": true}, false, "dry_run cannot be combined"},
+		{"DDL blocked", "execute", map[string]interface{}{"sql": "CREATE TABLE x (id INT)", "transaction": "tx_gone"}, false, "security validation failed"},
+		{"DDL inside transaction", "execute", map[string]interface{}{"sql": "CREATE TABLE x (id INT)", "transaction": "tx_gone"}, true, "DDL is not allowed inside a transaction"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ALLOW_DDL", strconv.FormatBool(tt.allowDDL))
+			_, err := callClientMethod(mysql.NewClient(), tt.tool, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
+// newFakeTxClient returns a client on the fake driver with the given transaction limits
+func newFakeTxClient(t *testing.T, idleTimeout, maxOpen string) *mysql.Client {
+	t.Setenv("TX_IDLE_TIMEOUT", idleTimeout)
+	t.Setenv("MAX_OPEN_TRANSACTIONS", maxOpen)
+	t.Setenv("NONTX_WRITE_POLICY", "off")
+	client := mysql.NewClient()
+	client.UseDB(openFakeRows(t))
+	return client
+}
+
+// TestTransactionLimit verifies MAX_OPEN_TRANSACTIONS caps the open handles and a closed one frees its slot
+func TestTransactionLimit(t *testing.T) {
+	client := newFakeTxClient(t, "", "2")
+
+	first, err := client.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction() error: %v", err)
+	}
+	if _, err := client.BeginTransaction(); err != nil {
+		t.Fatalf("second BeginTransaction() error: %v", err)
+	}
+	if _, err := client.BeginTransaction(); err == nil || !strings.Contains(err.Error(), "too many open transactions (2, MAX_OPEN_TRANSACTIONS)") {
+		t.Fatalf("third BeginTransaction() = %v, want the MAX_OPEN_TRANSACTIONS error", err)
+	}
+
+	if _, err := client.Rollback(first.ID); err != nil {
+		t.Fatalf("Rollback() error: %v", err)
+	}
+	if _, err := client.BeginTransaction(); err != nil {
+		t.Errorf("BeginTransaction() after a rollback = %v, want a new handle", err)
+	}
+}
+
+// TestTransactionIdleTimeout verifies an idle transaction is rolled back and its handle forgotten, and use keeps it alive
+func TestTransactionIdleTimeout(t *testing.T) {
+	client := newFakeTxClient(t, "150ms", "")
+
+	idle, err := client.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction() error: %v", err)
+	}
+	busy, err := client.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction() error: %v", err)
+	}
+	if idle.IdleTimeout != 150*time.Millisecond {
+		t.Errorf("IdleTimeout = %s, want 150ms", idle.IdleTimeout)
+	}
+
+	// Each statement re-arms the timer of busy.
+	for i := 0; i < 6; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if _, err := client.ExecuteInTransaction(busy.ID, "DELETE FROM t WHERE id = 1", "", mysql.ExecOptions{}); err != nil {
+			t.Fatalf("ExecuteInTransaction() error: %v", err)
+		}
+	}
+
+	if _, err := client.Commit(idle.ID, ""); err == nil || !strings.Contains(err.Error(), "expired after 150ms idle") {
+		t.Errorf("Commit() of the idle transaction = %v, want an expired error", err)
+	}
+	info, err := client.Commit(busy.ID, "")
+	if err != nil {
+		t.Fatalf("Commit() of the used transaction error: %v", err)
+	}
+	if info.Statements != 6 || info.Affected != 6 {
+		t.Errorf("Commit() = %+v, want 6 statements and 6 rows", info)
+	}
+}
+
+// TestTransactionBusy verifies a handle cannot be used while one of its statements runs
+func TestTransactionBusy(t *testing.T) {
+	client := newFakeTxClient(t, "", "")
+	fakeExecGate.entered = make(chan struct{})
+	fakeExecGate.release = make(chan struct{})
+
+	tx, err := client.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction() error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := client.ExecuteInTransaction(tx.ID, "DELETE FROM blocking WHERE id = 1", "", mysql.ExecOptions{})
+		done <- err
+	}()
+	<-fakeExecGate.entered
+
+	if _, err := client.Rollback(tx.ID); err == nil || !strings.Contains(err.Error(), "is busy with another statement") {
+		t.Errorf("Rollback() during a statement = %v, want a busy error", err)
+	}
+	if _, err := client.ExecuteInTransaction(tx.ID, "DELETE FROM t WHERE id = 2", "", mysql.ExecOptions{}); err == nil || !strings.Contains(err.Error(), "is busy") {
+		t.Errorf("ExecuteInTransaction() during a statement = %v, want a busy error", err)
+	}
+
+	close(fakeExecGate.release)
+	if err := <-done; err != nil {
+		t.Fatalf("blocked ExecuteInTransaction() error: %v", err)
+	}
+	if _, err := client.Rollback(tx.ID); err != nil {
+		t.Errorf("Rollback() after the statement = %v, want nil", err)
+	}
+}
+
+// TestTransactionBroken verifies an error that ends the transaction closes the handle, and other errors keep it open
+func TestTransactionBroken(t *testing.T) {
+	client := newFakeTxClient(t, "", "1")
+
+	tx, err := client.BeginTransaction()
+	if err != nil {
+		t.Fatalf("BeginTransaction() error: %v", err)
+	}
+
+	_, err = client.ExecuteInTransaction(tx.ID, "DELETE FROM bad_syntax WHERE id = 1", "", mysql.ExecOptions{})
+	if err == nil || strings.Contains(err.Error(), "rolled back and closed") {
+		t.Fatalf("ExecuteInTransaction() with a syntax error = %v, want an error that keeps the transaction", err)
+	}
+	if _, err := client.ExecuteInTransaction(tx.ID, "DELETE FROM t WHERE id = 1", "", mysql.ExecOptions{}); err != nil {
+		t.Fatalf("ExecuteInTransaction() after a syntax error = %v, want the transaction still open", err)
+	}
+
+	_, err = client.ExecuteInTransaction(tx.ID, "DELETE FROM deadlock WHERE id = 1", "", mysql.ExecOptions{})
+	if err == nil || !strings.Contains(err.Error(), "Transaction "+tx.ID+" was rolled back and closed") {
+		t.Fatalf("ExecuteInTransaction() with a deadlock = %v, want the transaction closed", err)
+	}
+	if _, err := client.Commit(tx.ID, ""); err == nil || !strings.Contains(err.Error(), "unknown transaction") {
+		t.Errorf("Commit() after a deadlock = %v, want an unknown transaction error", err)
+	}
+	// The closed handle no longer counts towards MAX_OPEN_TRANSACTIONS.
+	if _, err := client.BeginTransaction(); err != nil {
+		t.Errorf("BeginTransaction() after a deadlock = %v, want a new handle", err)
+	}
+}
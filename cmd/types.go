@@ -1,5 +1,7 @@
 package main
 
+import "encoding/json"
+
 // MCPMessage estructura MCP 2.0 compliant
 type MCPMessage struct {
 	JSONRpc string      `json:"jsonrpc"`
@@ -23,7 +25,32 @@ type ToolResponse struct {
 	IsError bool          `json:"isError,omitempty"` // MCP spec: tool execution errors use isError, not protocol errors
 }
 
+// ContentItem is one MCP content block: "text", "image" (base64 Data plus
+// MimeType) or "resource" (an embedded resource).
 type ContentItem struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Data     string           `json:"data,omitempty"`
+	MimeType string           `json:"mimeType,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// MarshalJSON always writes "text" for text items, even when empty, as
+// the MCP schema requires it.
+func (c ContentItem) MarshalJSON() ([]byte, error) {
+	if c.Type == "text" {
+		return json.Marshal(struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}{c.Type, c.Text})
+	}
+	type item ContentItem
+	return json.Marshal(item(c))
+}
+
+// ResourceContent is an embedded binary resource; Blob is base64.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Blob     string `json:"blob"`
 }
\ No newline at end of file
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestUndoRowRoundTrip verifies row images keep their value types through the JSON journal
+func TestUndoRowRoundTrip(t *testing.T) {
+	at := time.Date(2026, 1, 1, 12, 30, 0, 500, time.UTC)
+	row := mysql.UndoRow{int64(42), "Customer 1", nil, []byte{0x89, 'P', 'N', 'G'}, at, 1234.5, int64(9007199254740993)}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var got mysql.UndoRow
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, row) {
+		t.Errorf("Round trip changed the row:\n got %#v\nwant %#v", got, row)
+	}
+}
+
+// TestUndoJournalConfig verifies the journal is off by default and rejects malformed IDs
+func TestUndoJournalConfig(t *testing.T) {
+	t.Setenv("UNDO_JOURNAL_DIR", "")
+	if j := mysql.NewUndoJournal(); j != nil {
+		t.Fatalf("Expected no journal without UNDO_JOURNAL_DIR")
+	}
+
+	t.Setenv("UNDO_JOURNAL_DIR", t.TempDir())
+	j := mysql.NewUndoJournal()
+	if j == nil {
+		t.Fatalf("Expected a journal with UNDO_JOURNAL_DIR set")
+	}
+
+	tests := []struct {
+		id      string
+		errPart string
+	}{
+		{"../etc/passwd", "invalid operation ID"},
+		{"op_123", "invalid operation ID"},
+		{"op_0123456789abcdef01234567", "unknown operation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			_, err := j.Load(tt.id)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
+// TestRevertOperationChecks verifies revert_operation refusals that need no database
+func TestRevertOperationChecks(t *testing.T) {
+	dir := t.TempDir()
+	reverted := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []mysql.UndoEntry{
+		{ID: "op_aaaaaaaaaaaaaaaaaaaaaaaa", Verb: "UPDATE", Schema: "shop", Table: "customers", Columns: []string{"id", "name"}, Key: []string{"id"},
+			Before: []mysql.UndoRow{{int64(1), "old"}}, After: []mysql.UndoRow{{int64(1), "new"}}, RevertedAt: &reverted},
+		{ID: "op_bbbbbbbbbbbbbbbbbbbbbbbb", Verb: "DELETE", Schema: "shop", Table: "customers", Columns: []string{"id", "name"}, Key: []string{"id"}},
+	}
+	for _, e := range entries {
+		data, _ := json.Marshal(e)
+		if err := os.WriteFile(filepath.Join(dir, e.ID+".json"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Setenv("UNDO_JOURNAL_DIR", "")
+	disabled := mysql.NewClient()
+	t.Setenv("UNDO_JOURNAL_DIR", dir)
+	client := mysql.NewClient()
+
+	tests := []struct {
+		name    string
+		client  *mysql.Client
+		args    map[string]interface{}
+		errPart string
+	}{
+		{"Missing ID", client, map[string]interface{}{}, "missing or invalid 'operation_id'"},
+		{"Journal disabled", disabled, map[string]interface{}{"operation_id": "op_aaaaaaaaaaaaaaaaaaaaaaaa"}, "set UNDO_JOURNAL_DIR"},
+		{"Already reverted", client, map[string]interface{}{"operation_id": "op_aaaaaaaaaaaaaaaaaaaaaaaa"}, "already reverted at 2026-01-02"},
+		{"Nothing to revert", client, map[string]interface{}{"operation_id": "op_bbbbbbbbbbbbbbbbbbbbbbbb"}, "has nothing to revert"},
+		{"Unknown operation", client, map[string]interface{}{"operation_id": "op_cccccccccccccccccccccccc"}, "UNDO_MAX_OPERATIONS"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := callClientMethod(tt.client, "revert_operation", tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Errorf("Expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	mysql "mcp-gp-mysql/internal"
+)
+
+// TestFormatWriteResult verifies matched/changed rows, insert ID, elapsed time and warnings are shown
+func TestFormatWriteResult(t *testing.T) {
+	matched, changed := int64(3), int64(2)
+	warnings := make([]mysql.SQLWarning, mysql.MaxWriteWarnings)
+	for i := range warnings {
+		warnings[i] = mysql.SQLWarning{Level: "Warning", Code: 1265, Message: "Data truncated for column 'code' at row 1"}
+	}
+
+	tests := []struct {
+		name    string
+		compact bool
+		result  *QueryResult
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "No details",
+			result:  &QueryResult{Message: "Rows affected: 1"},
+			want:    []string{"Rows affected: 1"},
+			notWant: []string{"elapsed"},
+		},
+		{
+			name: "Update",
+			result: &QueryResult{Message: "Query executed successfully. Rows affected: 2",
+				Write: &mysql.WriteInfo{Affected: 2, Matched: &matched, Changed: &changed, Elapsed: 12345 * time.Microsecond}},
+			want:    []string{"Rows matched 3, changed 2; 12ms elapsed"},
+			notWant: []string{"insert ID", "warnings"},
+		},
+		{
+			name: "Insert with truncation",
+			result: &QueryResult{Message: "Query executed successfully. Rows affected: 1",
+				Write: &mysql.WriteInfo{Affected: 1, LastInsertID: 42, Elapsed: time.Millisecond, WarningCount: 1,
+					Warnings: []mysql.SQLWarning{{Level: "Warning", Code: 1265, Message: "Data truncated for column 'code' at row 1"}}}},
+			want: []string{"last insert ID 42", "warnings: 1", "Warning 1265: Data truncated for column 'code' at row 1"},
+		},
+		{
+			name: "Warnings beyond the cap",
+			result: &QueryResult{Message: "Query executed successfully. Rows affected: 30",
+				Write: &mysql.WriteInfo{Affected: 30, WarningCount: 30, Warnings: warnings}},
+			want: []string{"warnings: 30", "... 10 more not shown"},
+		},
+		{
+			name:    "Compact",
+			compact: true,
+			result: &QueryResult{Message: "Rows affected: 2",
+				Write: &mysql.WriteInfo{Affected: 2, Matched: &matched, Changed: &changed, LastInsertID: 7, Elapsed: 3 * time.Millisecond}},
+			want: []string{"Rows affected: 2 (matched 3, changed 2, last insert ID 7, 3ms elapsed)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			CompactMode = tt.compact
+			defer func() { CompactMode = false }()
+
+			got := formatWriteResult(tt.result)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Expected %q in %q", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("Did not expect %q in %q", w, got)
+				}
+			}
+		})
+	}
+}
+
+// TestRedactWarnings verifies warning messages are dropped, keeping level and code, when a masking rule applies
+func TestRedactWarnings(t *testing.T) {
+	t.Setenv("MASKING_RULES", "customers.email=partial")
+	policy := mysql.NewMaskingPolicy()
+	warnings := func() []mysql.SQLWarning {
+		return []mysql.SQLWarning{{Level: "Warning", Code: 1292, Message: "Truncated incorrect DOUBLE value: 'john@x.com'"}}
+	}
+
+	w := warnings()
+	if !policy.RedactWarnings("UPDATE customers SET id = id WHERE email + 0 = 1", w) {
+		t.Fatal("RedactWarnings() on customers = false, want true")
+	}
+	if w[0].Message != "" || w[0].Level != "Warning" || w[0].Code != 1292 {
+		t.Errorf("redacted warning = %+v, want level and code only", w[0])
+	}
+
+	w = warnings()
+	if policy.RedactWarnings("UPDATE orders SET n = n WHERE code + 0 = 1", w) || w[0].Message == "" {
+		t.Errorf("RedactWarnings() on orders dropped %+v, want it kept", w[0])
+	}
+	var none *mysql.MaskingPolicy
+	if w = warnings(); none.RedactWarnings("UPDATE customers SET id = id", w) || w[0].Message == "" {
+		t.Errorf("RedactWarnings() without a policy dropped the message")
+	}
+
+	result := &QueryResult{Message: "Rows affected: 1",
+		Write: &mysql.WriteInfo{Affected: 1, WarningCount: 1, WarningsWithheld: true,
+			Warnings: []mysql.SQLWarning{{Level: "Warning", Code: 1292}}}}
+	got := formatWriteResult(result)
+	if !strings.Contains(got, "warnings: 1 (messages withheld: MASKING_RULES)\n  Warning 1292") || strings.Contains(got, "1292:") {
+		t.Errorf("formatWriteResult() = %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Table allowlist.
+//
+// With ALLOWED_TABLES set, ValidateQuery extracts every table a statement
+// reads or writes with ReferencedTables (FROM and JOIN lists, subqueries at
+// any depth, UPDATE/INSERT/DELETE targets, DESCRIBE/SHOW targets, DDL
+// tables; CTE names excluded) and refuses the statement when one of them is
+// not listed. Since every tool sends caller SQL through ValidateQuery, this
+// covers query, execute, explain, count, sample, dry runs, batches,
+// transactions, saved queries and custom tools. Statements whose tables
+// cannot be determined are refused rather than guessed at: CALL, SHOW
+// TABLES and other listing forms, table functions, USE (it would change
+// what unqualified names resolve to) and anything the tokenizer cannot
+// read.
+//
+// Entries are table names in the connection's database, or schema.table
+// for another schema; qualifying a table with the current database
+// matches the plain entry. Matching is case-insensitive. The introspection
+// methods (ListTables, ListViews, ListIndexes, DescribeTable) filter or
+// refuse the same way.
+//
+// Stored functions called from a statement are not seen; keep them out of
+// the MCP user's EXECUTE grants when the allowlist matters.
+
+// tableAllowed reports whether ALLOWED_TABLES admits ref.
+func (c *Client) tableAllowed(ref TableRef) bool {
+	if len(c.securityConfig.AllowedTables) == 0 {
+		return true
+	}
+	name := strings.ToLower(ref.Name)
+	if ref.Schema != "" && !strings.EqualFold(ref.Schema, c.config.Database) {
+		name = strings.ToLower(ref.Schema) + "." + name
+	}
+	for _, allowed := range c.securityConfig.AllowedTables {
+		if strings.ToLower(allowed) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTableAccess applies ALLOWED_TABLES to a statement that passed the
+// verb classifier.
+func (c *Client) checkTableAccess(query string) error {
+	if len(c.securityConfig.AllowedTables) == 0 {
+		return nil
+	}
+	switch verb := firstVerb(StripComments(query)); verb {
+	case "USE":
+		return fmt.Errorf("USE is not allowed with ALLOWED_TABLES: it would change the schema unqualified table names refer to")
+	case "CALL", "EXEC", "EXECUTE":
+		return fmt.Errorf("%s is not allowed with ALLOWED_TABLES: the tables a procedure touches cannot be determined", verb)
+	}
+
+	tables, ok := ReferencedTables(query)
+	if !ok {
+		return fmt.Errorf("ALLOWED_TABLES is set and the tables this statement uses cannot be determined; name each table directly (use the tables tool to list them)")
+	}
+	for _, ref := range tables {
+		if !c.tableAllowed(ref) {
+			return fmt.Errorf("access to table '%s' is not allowed (ALLOWED_TABLES)", ref)
+		}
+	}
+	return nil
+}
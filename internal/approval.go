@@ -0,0 +1,425 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Approval queue.
+//
+// With APPROVAL_QUEUE_FILE set, execute does not run writes. It stores the
+// statement, the impact reported by a dry run and the requester in the
+// queue file and returns a pending ID (ap_...). A human decides with the
+// server binary's CLI subcommands (list, approve, reject); approve runs
+// the statement in that process through the normal Execute path, with its
+// MAX_SAFE_ROWS gate, REQUIRE_WHERE and non-transactional table checks.
+// The agent follows the decision with operation_status.
+//
+// Every other way to write (execute_batch, bulk_insert, interactive
+// transactions, revert_operation, call_procedure of routines that may
+// write, write saved queries and custom tools) is refused while the queue
+// is on, so nothing bypasses it.
+//
+// The server and the CLI share the file. Each change rewrites it
+// atomically under a lock file created next to it.
+//
+// An approved operation is "running" until the approving process records
+// the outcome. If that process dies first, the entry would stay running
+// for good, so it records when the run must be over (StaleAt, from its
+// own timeouts); after that, reject marks the entry failed with an
+// unknown outcome.
+
+// Operation states in the approval queue.
+const (
+	ApprovalPending  = "pending"
+	ApprovalRunning  = "running" // approved, being executed
+	ApprovalExecuted = "executed"
+	ApprovalFailed   = "failed"
+	ApprovalRejected = "rejected"
+)
+
+// MaxDecidedOperations is how many decided operations the queue keeps;
+// pending ones are never dropped.
+const MaxDecidedOperations = 100
+
+// lock file timing
+const (
+	queueLockWait  = 5 * time.Second
+	queueLockStale = 30 * time.Second
+)
+
+// PendingOperation is one write in the approval queue.
+type PendingOperation struct {
+	ID             string        `json:"id"`
+	Status         string        `json:"status"`
+	SQL            string        `json:"sql"`
+	AllowFullTable bool          `json:"allow_full_table,omitempty"`
+	Requester      string        `json:"requester"`
+	Reason         string        `json:"reason,omitempty"`
+	RequestedAt    time.Time     `json:"requested_at"`
+	Impact         *DryRunResult `json:"impact,omitempty"`
+	ImpactError    string        `json:"impact_error,omitempty"`
+	StartedAt      *time.Time    `json:"started_at,omitempty"`
+	StaleAt        *time.Time    `json:"stale_at,omitempty"`
+	DecidedBy      string        `json:"decided_by,omitempty"`
+	DecidedAt      *time.Time    `json:"decided_at,omitempty"`
+	// Note is the rejection reason.
+	Note string `json:"note,omitempty"`
+	// Result is Execute's message, or its error for a failed operation;
+	// OperationID its undo journal entry.
+	Result      string `json:"result,omitempty"`
+	OperationID string `json:"operation_id,omitempty"`
+}
+
+// ApprovalQueue is the queue file.
+type ApprovalQueue struct {
+	path string
+}
+
+// NewApprovalQueue reads APPROVAL_QUEUE_FILE. It returns nil when it is
+// unset, which turns approval mode off.
+func NewApprovalQueue() *ApprovalQueue {
+	path := os.Getenv("APPROVAL_QUEUE_FILE")
+	if path == "" {
+		return nil
+	}
+	return &ApprovalQueue{path: path}
+}
+
+// Path returns the queue file.
+func (q *ApprovalQueue) Path() string {
+	return q.path
+}
+
+var approvalIDPattern = regexp.MustCompile(`^ap_[0-9a-f]{16}$`)
+
+func newApprovalID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "ap_" + hex.EncodeToString(b)
+}
+
+// load reads every operation; a missing file is an empty queue.
+func (q *ApprovalQueue) load() ([]*PendingOperation, error) {
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ops []*PendingOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("approval queue %s is corrupt: %w", q.path, err)
+	}
+	return ops, nil
+}
+
+// save writes ops atomically, dropping the oldest decided operations
+// beyond MaxDecidedOperations.
+func (q *ApprovalQueue) save(ops []*PendingOperation) error {
+	decided := 0
+	for i := len(ops) - 1; i >= 0; i-- {
+		if ops[i].Status == ApprovalPending || ops[i].Status == ApprovalRunning {
+			continue
+		}
+		if decided++; decided > MaxDecidedOperations {
+			ops = append(ops[:i], ops[i+1:]...)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// update runs fn on the queue under the lock and saves the result.
+func (q *ApprovalQueue) update(fn func(ops []*PendingOperation) ([]*PendingOperation, error)) error {
+	unlock, err := q.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ops, err := q.load()
+	if err != nil {
+		return err
+	}
+	if ops, err = fn(ops); err != nil {
+		return err
+	}
+	return q.save(ops)
+}
+
+// lock takes the lock file, breaking one left behind by a dead process.
+func (q *ApprovalQueue) lock() (func(), error) {
+	path := q.path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(queueLockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if st, err := os.Stat(path); err == nil && time.Since(st.ModTime()) > queueLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("approval queue %s is locked (%s exists)", q.path, path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// Add appends op with a new ID and pending status.
+func (q *ApprovalQueue) Add(op *PendingOperation) error {
+	op.ID = newApprovalID()
+	op.Status = ApprovalPending
+	return q.update(func(ops []*PendingOperation) ([]*PendingOperation, error) {
+		return append(ops, op), nil
+	})
+}
+
+// Get returns one operation.
+func (q *ApprovalQueue) Get(id string) (*PendingOperation, error) {
+	if !approvalIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid pending operation ID %q", id)
+	}
+	ops, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if op.ID == id {
+			return op, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown pending operation %q", id)
+}
+
+// List returns the operations in any of the given states (all when
+// none is given), oldest first.
+func (q *ApprovalQueue) List(statuses ...string) ([]*PendingOperation, error) {
+	ops, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	want := map[string]bool{}
+	for _, s := range statuses {
+		want[s] = true
+	}
+	var out []*PendingOperation
+	for _, op := range ops {
+		if len(statuses) == 0 || want[op.Status] {
+			out = append(out, op)
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool { return out[a].RequestedAt.Before(out[b].RequestedAt) })
+	return out, nil
+}
+
+// transition changes operation id with fn if it is in state from, and
+// returns a copy of the result.
+func (q *ApprovalQueue) transition(id, from string, fn func(op *PendingOperation)) (*PendingOperation, error) {
+	if !approvalIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid pending operation ID %q", id)
+	}
+	var out PendingOperation
+	err := q.update(func(ops []*PendingOperation) ([]*PendingOperation, error) {
+		for _, op := range ops {
+			if op.ID != id {
+				continue
+			}
+			if op.Status != from {
+				return nil, fmt.Errorf("operation %s is %s, not %s", id, op.Status, from)
+			}
+			fn(op)
+			out = *op
+			return ops, nil
+		}
+		return nil, fmt.Errorf("unknown pending operation %q", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ApprovalMode reports whether writes go through the approval queue.
+func (c *Client) ApprovalMode() bool {
+	return c.approvals != nil
+}
+
+// Approvals returns the approval queue, or nil when approval mode is off.
+func (c *Client) Approvals() *ApprovalQueue {
+	return c.approvals
+}
+
+// refuseInApprovalMode rejects a write path the approval queue does not
+// cover.
+func (c *Client) refuseInApprovalMode(what string) error {
+	if c.approvals == nil {
+		return nil
+	}
+	return fmt.Errorf("%s is not available in approval mode (APPROVAL_QUEUE_FILE): writes wait for a human, submit them with execute", what)
+}
+
+// RequestApproval queues a write for a human decision. The statement is
+// validated and dry-run first; a dry run that fails is recorded, not
+// fatal, since not every statement can be dry-run.
+func (c *Client) RequestApproval(query string, opts ExecOptions, requester, reason string) (*PendingOperation, error) {
+	if c.approvals == nil {
+		return nil, fmt.Errorf("approval mode is off; set APPROVAL_QUEUE_FILE to queue writes")
+	}
+	// An allow_full_table override is confirmed by the approver, when the
+	// write runs.
+	if !opts.AllowFullTable {
+		if _, err := c.checkWherePolicy([]string{query}, opts, "", ""); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	if err := c.ValidateQuery(query); err != nil {
+		return nil, fmt.Errorf("security validation failed: %w", err)
+	}
+
+	var err error
+	op := &PendingOperation{
+		SQL:            query,
+		AllowFullTable: opts.AllowFullTable,
+		Requester:      requester,
+		Reason:         reason,
+		RequestedAt:    time.Now().UTC(),
+	}
+	if op.Impact, err = c.DryRun(query); err != nil {
+		op.ImpactError = err.Error()
+	}
+	if err := c.approvals.Add(op); err != nil {
+		return nil, fmt.Errorf("failed to queue the operation: %w", err)
+	}
+	return op, nil
+}
+
+// OperationStatus returns a queued operation.
+func (c *Client) OperationStatus(id string) (*PendingOperation, error) {
+	if c.approvals == nil {
+		return nil, fmt.Errorf("approval mode is off; set APPROVAL_QUEUE_FILE to queue writes")
+	}
+	return c.approvals.Get(id)
+}
+
+// ApproveOperation runs a pending operation through Execute on behalf of
+// approver. A write stopped by the MAX_SAFE_ROWS gate stays pending and
+// the ConfirmationError is returned, so the approver can retry with
+// confirmKey; any other error marks the operation failed.
+func (c *Client) ApproveOperation(id, approver, confirmKey string) (*PendingOperation, error) {
+	if c.approvals == nil {
+		return nil, fmt.Errorf("approval mode is off; set APPROVAL_QUEUE_FILE to queue writes")
+	}
+	started := time.Now().UTC()
+	staleAt := started.Add(c.approvalRunLimit())
+	op, err := c.approvals.transition(id, ApprovalPending, func(op *PendingOperation) {
+		op.Status = ApprovalRunning
+		op.StartedAt, op.StaleAt = &started, &staleAt
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, execErr := c.execute(op.SQL, confirmKey, ExecOptions{AllowFullTable: op.AllowFullTable})
+
+	var confirmErr *ConfirmationError
+	if errors.As(execErr, &confirmErr) {
+		if _, err := c.approvals.transition(id, ApprovalRunning, func(op *PendingOperation) {
+			op.Status = ApprovalPending
+			op.StartedAt, op.StaleAt = nil, nil
+		}); err != nil {
+			return nil, err
+		}
+		return nil, execErr
+	}
+
+	now := time.Now().UTC()
+	return c.approvals.transition(id, ApprovalRunning, func(op *PendingOperation) {
+		op.DecidedBy = approver
+		op.DecidedAt = &now
+		if execErr != nil {
+			op.Status = ApprovalFailed
+			op.Result = execErr.Error()
+			return
+		}
+		op.Status = ApprovalExecuted
+		op.Result = res.Message
+		op.OperationID = res.OperationID
+	})
+}
+
+// approvalRunLimit bounds how long ApproveOperation can take: connecting,
+// the table checks and the statement, each under its own timeout, plus a
+// margin.
+func (c *Client) approvalRunLimit() time.Duration {
+	tc := c.timeoutConfig
+	run := tc.GetTimeout(ProfileWrite)
+	if admin := tc.GetTimeout(ProfileAdmin); admin > run {
+		run = admin
+	}
+	return tc.GetTimeout(ProfileConnection) + 2*tc.GetTimeout(ProfileQuery) + run + time.Minute
+}
+
+// RejectOperation marks a pending operation rejected. A running operation
+// past its StaleAt, whose approving process died before recording the
+// outcome, is marked failed instead, since the write may have committed.
+func (c *Client) RejectOperation(id, approver, note string) (*PendingOperation, error) {
+	if c.approvals == nil {
+		return nil, fmt.Errorf("approval mode is off; set APPROVAL_QUEUE_FILE to queue writes")
+	}
+	now := time.Now().UTC()
+	if op, err := c.approvals.Get(id); err == nil && op.Status == ApprovalRunning {
+		if op.StaleAt != nil && now.Before(*op.StaleAt) {
+			return nil, fmt.Errorf("operation %s is running; if the approving process is gone, it can be marked failed after %s", id, op.StaleAt.Format(time.RFC3339))
+		}
+		return c.approvals.transition(id, ApprovalRunning, func(op *PendingOperation) {
+			op.Status = ApprovalFailed
+			op.DecidedBy = approver
+			op.DecidedAt = &now
+			op.Note = note
+			op.Result = "the approving process stopped before recording the outcome; the write may or may not have been committed, so check the data before resubmitting it"
+		})
+	}
+	return c.approvals.transition(id, ApprovalPending, func(op *PendingOperation) {
+		op.Status = ApprovalRejected
+		op.DecidedBy = approver
+		op.DecidedAt = &now
+		op.Note = note
+	})
+}
@@ -0,0 +1,89 @@
+package internal
+
+import "fmt"
+
+// Automatic LIMIT.
+//
+// A SELECT without a LIMIT makes the server scan and send everything it
+// matches, even though only MAX_RESULT_ROWS rows are kept. When AUTO_LIMIT
+// is set (default 1000), the query tool appends LIMIT N+1 to top-level
+// SELECTs that have none: N rows are returned and the extra row only tells
+// whether more exist. The clause is appended rather than wrapping the
+// statement in a derived table, so ORDER BY, UNION and locking clauses keep
+// their meaning.
+
+// DefaultAutoLimit is the AUTO_LIMIT default.
+const DefaultAutoLimit = 1000
+
+// ApplyAutoLimit appends "LIMIT limit" to a top-level SELECT (or WITH ...
+// SELECT, or a parenthesized SELECT/UNION) that has no LIMIT of its own.
+// It inserts the clause before a trailing FOR UPDATE / FOR SHARE / LOCK IN
+// SHARE MODE and before any trailing comment or semicolon. applied is false
+// when the statement is left unchanged: it already has a top-level LIMIT,
+// it is not a SELECT, it assigns INTO variables, or it does not tokenize
+// cleanly.
+func ApplyAutoLimit(query string, limit int) (limited string, applied bool) {
+	if limit <= 0 {
+		return query, false
+	}
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return query, false
+	}
+	last := len(tokens) - 1
+	for last >= 0 && tokens[last].isPunct(";") {
+		last--
+	}
+	if last < 0 {
+		return query, false
+	}
+
+	first := 0
+	for first < last && tokens[first].isPunct("(") {
+		first++
+	}
+	if !tokens[first].is("SELECT") && !tokens[first].is("WITH") {
+		return query, false
+	}
+
+	insertAt := tokens[last].end
+	before := false
+scan:
+	for i := 0; i <= last; i++ {
+		t := tokens[i]
+		if t.depth != 0 || t.kind != tokWord {
+			continue
+		}
+		next := ""
+		if i < last {
+			next = tokens[i+1].upper
+			if tokens[i+1].isPunct("(") {
+				next = "("
+			}
+		}
+		switch t.upper {
+		case "LIMIT", "INTO":
+			return query, false
+		case "INSERT", "UPDATE", "DELETE", "REPLACE":
+			if next != "(" { // not the string function of the same name
+				return query, false
+			}
+		case "FOR":
+			if next == "UPDATE" || next == "SHARE" {
+				insertAt, before = t.pos, true
+				break scan
+			}
+		case "LOCK":
+			if next == "IN" {
+				insertAt, before = t.pos, true
+				break scan
+			}
+		}
+	}
+
+	clause := fmt.Sprintf("LIMIT %d", limit)
+	if before {
+		return query[:insertAt] + clause + " " + query[insertAt:], true
+	}
+	return query[:insertAt] + " " + clause + query[insertAt:], true
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Atomic batches.
+//
+// ExecuteBatch runs several write statements in one transaction: all of
+// them commit or none does. Every statement is validated with ValidateQuery
+// before anything runs, so the stacked-statement rule still holds for each
+// element. Only INSERT/UPDATE/DELETE/REPLACE are accepted; DDL would commit
+// the transaction implicitly and break atomicity. The MAX_SAFE_ROWS gate
+// applies to the affected rows summed over the batch.
+
+// MaxBatchStatements caps the number of statements in one batch.
+const MaxBatchStatements = 100
+
+// BatchStatement is one element of a batch.
+type BatchStatement struct {
+	SQL  string        `json:"sql"`
+	Args []interface{} `json:"params,omitempty"`
+}
+
+// BatchStepResult is the outcome of one statement.
+type BatchStepResult struct {
+	SQL      string `json:"sql"`
+	Affected int64  `json:"affected"`
+	Error    string `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of a batch.
+type BatchResult struct {
+	Steps         []BatchStepResult `json:"steps"`
+	TotalAffected int64             `json:"total_affected"`
+	Committed     bool              `json:"committed"`
+	Message       string            `json:"message"`
+}
+
+// BatchError is returned when a batch was rolled back. Result holds the
+// statements that ran, the last one carrying the error when a statement
+// failed.
+type BatchError struct {
+	Result *BatchResult
+	Err    error
+}
+
+func (e *BatchError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(e.Err.Error())
+	for i, s := range e.Result.Steps {
+		if s.Error != "" {
+			fmt.Fprintf(&sb, "\n  %d. FAILED: %s", i+1, s.Error)
+		} else {
+			fmt.Fprintf(&sb, "\n  %d. ok, %d rows (rolled back)", i+1, s.Affected)
+		}
+	}
+	return sb.String()
+}
+
+func (e *BatchError) Unwrap() error { return e.Err }
+
+// ExecuteBatch validates every statement, runs them in order in a single
+// transaction, and commits only if all succeed and the summed affected rows
+// pass the MAX_SAFE_ROWS gate. On the first failure everything is rolled
+// back and a *BatchError is returned.
+func (c *Client) ExecuteBatch(stmts []BatchStatement, confirmKey string, opts ExecOptions) (*BatchResult, error) {
+	if err := c.refuseInApprovalMode("execute_batch"); err != nil {
+		return nil, err
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("batch is empty")
+	}
+	if len(stmts) > MaxBatchStatements {
+		return nil, fmt.Errorf("batch has %d statements; the limit is %d", len(stmts), MaxBatchStatements)
+	}
+	for i, s := range stmts {
+		if err := c.ValidateQuery(s.SQL); err != nil {
+			return nil, fmt.Errorf("statement %d: security validation failed: %w", i+1, err)
+		}
+		if verb := firstVerb(StripComments(s.SQL)); !containsVerb(verb, writeVerbs) {
+			return nil, fmt.Errorf("statement %d: %s is not allowed in a batch; only INSERT, UPDATE, DELETE and REPLACE run atomically", i+1, verb)
+		}
+	}
+	queries := make([]string, len(stmts))
+	for i, s := range stmts {
+		queries[i] = s.SQL
+	}
+	confirmed, err := c.checkWherePolicy(queries, opts, bindBatch(stmts), confirmKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	nonTx, err := c.nonTxTargets(queries...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// With a non-transactional table involved, the rows of the whole batch
+	// are counted and gated up front instead. A confirmed allow_full_table
+	// override covers the batch already. The count locks rows, so the kill
+	// watch covers it as well as the statements.
+	watch := c.watchForKill(ctx, conn)
+	if !confirmed || c.nonTxPolicy == NonTxBlock {
+		if err := c.guardNonTx(nonTx, bindBatch(stmts), confirmKey, func() (int64, bool, error) {
+			return countStatements(ctx, tx, stmts)
+		}); err != nil {
+			return nil, watch.stop(err)
+		}
+	}
+
+	res := &BatchResult{}
+	for i, s := range stmts {
+		result, err := tx.ExecContext(ctx, s.SQL, s.Args...)
+		if err != nil {
+			err = c.withholdError(s.SQL, watch.stop(err))
+			tx.Rollback()
+			res.Steps = append(res.Steps, BatchStepResult{SQL: s.SQL, Error: err.Error()})
+			return nil, &BatchError{Result: res, Err: fmt.Errorf("statement %d of %d failed. %s", i+1, len(stmts), rolledBack(nonTx, "All statements have been rolled back"))}
+		}
+		affected, _ := result.RowsAffected()
+		res.Steps = append(res.Steps, BatchStepResult{SQL: s.SQL, Affected: affected})
+		res.TotalAffected += affected
+	}
+	if err := watch.stop(nil); err != nil {
+		return nil, err
+	}
+
+	if len(nonTx) == 0 && !confirmed {
+		if err := c.confirmLargeWrite(bindBatch(stmts), res.TotalAffected, confirmKey,
+			fmt.Sprintf("batch affects %d rows in total", res.TotalAffected), "All statements have been rolled back"); err != nil {
+			tx.Rollback()
+			return nil, &BatchError{Result: res, Err: err}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	for _, s := range stmts {
+		c.invalidateCache(s.SQL)
+	}
+
+	res.Committed = true
+	res.Message = fmt.Sprintf("Batch of %d statements committed. Rows affected: %d", len(stmts), res.TotalAffected)
+	return res, nil
+}
+
+// bindBatch renders a whole batch for confirmation binding.
+func bindBatch(stmts []BatchStatement) string {
+	parts := make([]string, len(stmts))
+	for i, s := range stmts {
+		parts[i] = bindStatement(s.SQL, s.Args)
+	}
+	return strings.Join(parts, "\x02")
+}
+
+// rolledBack words what a failed write left behind: outcome, or which
+// non-transactional tables may have kept changes.
+func rolledBack(nonTx []string, outcome string) string {
+	if len(nonTx) == 0 {
+		return outcome
+	}
+	return fmt.Sprintf("Rows already written to %s cannot be rolled back; everything else has been rolled back", strings.Join(nonTx, ", "))
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Bulk insert.
+//
+// BulkInsert loads rows into one table without the caller writing SQL.
+// Columns are checked against DescribeTable (which also applies
+// ALLOWED_TABLES), and the rows are sent as multi-row prepared INSERTs of
+// up to BulkInsertBatchRows rows, all in one transaction under the
+// MAX_SAFE_ROWS gate. Values are bound as placeholders, never spliced into
+// the statement. onDuplicate picks what happens on a duplicate key:
+//
+//   - "error" (default): the insert fails and everything is rolled back
+//   - "ignore": INSERT IGNORE skips the duplicate rows
+//   - "update": ON DUPLICATE KEY UPDATE overwrites the non-key columns
+
+// Bulk insert limits.
+const (
+	BulkInsertBatchRows = 500
+	MaxBulkInsertRows   = 10000
+)
+
+// maxPlaceholders is the server's limit on ? markers in one statement.
+const maxPlaceholders = 65535
+
+// Duplicate-key policies for BulkInsert.
+const (
+	OnDuplicateError  = "error"
+	OnDuplicateIgnore = "ignore"
+	OnDuplicateUpdate = "update"
+)
+
+// BulkInsertResult is the outcome of a bulk insert.
+type BulkInsertResult struct {
+	Table      string `json:"table"`
+	Rows       int    `json:"rows"`
+	Statements int    `json:"statements"`
+	// Affected is the server's count: with "ignore" skipped rows are not
+	// counted, with "update" an updated row counts twice.
+	Affected int64  `json:"affected"`
+	Message  string `json:"message"`
+}
+
+// BulkInsert inserts rows (one value per column, nil for NULL) into table.
+func (c *Client) BulkInsert(table string, columns []string, rows [][]interface{}, onDuplicate, confirmKey string) (*BulkInsertResult, error) {
+	if err := c.refuseInApprovalMode("bulk_insert"); err != nil {
+		return nil, err
+	}
+	if onDuplicate == "" {
+		onDuplicate = OnDuplicateError
+	}
+	if onDuplicate != OnDuplicateError && onDuplicate != OnDuplicateIgnore && onDuplicate != OnDuplicateUpdate {
+		return nil, fmt.Errorf("invalid on_duplicate %q: use error, ignore or update", onDuplicate)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns given")
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no rows given")
+	}
+	if len(rows) > MaxBulkInsertRows {
+		return nil, fmt.Errorf("%d rows given; the limit is %d per call", len(rows), MaxBulkInsertRows)
+	}
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return nil, fmt.Errorf("row %d has %d values, expected %d (one per column)", i+1, len(row), len(columns))
+		}
+	}
+
+	info, _, err := c.DescribeTable(table)
+	if err != nil {
+		return nil, err
+	}
+	if len(info) == 0 {
+		return nil, fmt.Errorf("table %q not found in the current database", table)
+	}
+	cols, updates, err := bulkColumns(info, columns, onDuplicate)
+	if err != nil {
+		return nil, err
+	}
+	var nonTx []string
+	if c.nonTxPolicy != NonTxOff {
+		if nonTx, err = c.nonTxTables([]TableRef{{Name: table}}); err != nil {
+			return nil, err
+		}
+	}
+
+	batch := min(BulkInsertBatchRows, maxPlaceholders/len(cols))
+	var stmts []BatchStatement
+	for start := 0; start < len(rows); start += batch {
+		chunk := rows[start:min(start+batch, len(rows))]
+		args := make([]interface{}, 0, len(chunk)*len(cols))
+		for _, row := range chunk {
+			args = append(args, row...)
+		}
+		stmts = append(stmts, BatchStatement{SQL: bulkStatement(table, cols, updates, onDuplicate, len(chunk)), Args: args})
+	}
+	binding := bindBatch(stmts)
+
+	// A non-transactional table is gated on the rows given, before any
+	// of them is sent; an updated duplicate counts twice.
+	if err := c.guardNonTx(nonTx, binding, confirmKey, func() (int64, bool, error) {
+		if onDuplicate == OnDuplicateUpdate {
+			return int64(2 * len(rows)), true, nil
+		}
+		return int64(len(rows)), true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res := &BulkInsertResult{Table: table, Rows: len(rows)}
+	watch := c.watchForKill(ctx, conn)
+	for i, s := range stmts {
+		result, err := tx.ExecContext(ctx, s.SQL, s.Args...)
+		if err != nil {
+			start := i * batch
+			return nil, fmt.Errorf("rows %d-%d failed: %w. %s", start+1, min(start+batch, len(rows)), watch.stop(err), rolledBack(nonTx, "Nothing was inserted"))
+		}
+		affected, _ := result.RowsAffected()
+		res.Affected += affected
+		res.Statements++
+	}
+	if err := watch.stop(nil); err != nil {
+		return nil, err
+	}
+
+	if len(nonTx) == 0 {
+		if err := c.confirmLargeWrite(binding, res.Affected, confirmKey,
+			fmt.Sprintf("bulk insert affects %d rows", res.Affected), "Nothing was inserted"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	c.cache.Invalidate([]TableRef{{Name: table}}, true)
+
+	res.Message = fmt.Sprintf("Bulk insert into %s committed: %d rows sent in %d statements (on_duplicate=%s). Rows affected: %d",
+		table, res.Rows, res.Statements, onDuplicate, res.Affected)
+	return res, nil
+}
+
+// bulkColumns matches the requested columns to the table's, returning
+// them with the table's spelling, plus the columns an "update" overwrites.
+func bulkColumns(info []ColumnInfo, columns []string, onDuplicate string) (cols, updates []string, err error) {
+	byName := make(map[string]ColumnInfo, len(info))
+	for _, ci := range info {
+		byName[strings.ToLower(ci.Name)] = ci
+	}
+
+	seen := make(map[string]bool, len(columns))
+	for _, name := range columns {
+		ci, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown column %q", name)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, nil, fmt.Errorf("column %q is listed twice", name)
+		}
+		seen[strings.ToLower(name)] = true
+		extra := strings.ToUpper(ci.Extra)
+		if strings.Contains(extra, "VIRTUAL") || strings.Contains(extra, "STORED") || strings.Contains(extra, "PERSISTENT") {
+			return nil, nil, fmt.Errorf("column %q is generated and cannot be inserted", ci.Name)
+		}
+		cols = append(cols, ci.Name)
+		if ci.Key != "PRI" && ci.Key != "UNI" {
+			updates = append(updates, ci.Name)
+		}
+	}
+	if onDuplicate == OnDuplicateUpdate && len(updates) == 0 {
+		return nil, nil, fmt.Errorf("on_duplicate=update needs at least one column outside the primary and unique keys")
+	}
+	return cols, updates, nil
+}
+
+// bulkStatement builds a multi-row INSERT of n rows.
+func bulkStatement(table string, cols, updates []string, onDuplicate string, n int) string {
+	quoted := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = quoteIdent(c)
+	}
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ") + ")"
+
+	var sb strings.Builder
+	sb.WriteString("INSERT ")
+	if onDuplicate == OnDuplicateIgnore {
+		sb.WriteString("IGNORE ")
+	}
+	fmt.Fprintf(&sb, "INTO %s (%s) VALUES ", quoteIdent(table), strings.Join(quoted, ", "))
+	sb.WriteString(strings.TrimSuffix(strings.Repeat(row+", ", n), ", "))
+	if onDuplicate == OnDuplicateUpdate {
+		set := make([]string, len(updates))
+		for i, c := range updates {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", quoteIdent(c), quoteIdent(c))
+		}
+		sb.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "))
+	}
+	return sb.String()
+}
+
+// BulkInsertStatement returns the statement BulkInsert sends for n rows.
+func BulkInsertStatement(table string, columns []ColumnInfo, names []string, onDuplicate string, n int) (string, error) {
+	cols, updates, err := bulkColumns(columns, names, onDuplicate)
+	if err != nil {
+		return "", err
+	}
+	return bulkStatement(table, cols, updates, onDuplicate, n), nil
+}
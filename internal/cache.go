@@ -0,0 +1,217 @@
+package internal
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result cache.
+//
+// Agents tend to re-run the same tables/describe/count/SELECT calls many
+// times in one session. When RESULT_CACHE=true, the client keeps recent
+// answers in memory, keyed by the normalized statement, its parameters and
+// the database, with a TTL per kind of call and a bound on entries (least
+// recently used entries are evicted first).
+//
+// Invalidation: each entry remembers the tables its statement referenced.
+// When Execute commits a write, entries touching the written tables are
+// dropped; DDL, or a write whose tables cannot be determined, drops
+// everything. Writes made outside this server are not seen — the TTL bounds
+// how stale an answer can get.
+
+// CacheKind selects the TTL bucket for a cached result.
+type CacheKind string
+
+const (
+	CacheNone     CacheKind = ""
+	CacheQuery    CacheKind = "query"
+	CacheTables   CacheKind = "tables"
+	CacheDescribe CacheKind = "describe"
+	CacheCount    CacheKind = "count"
+)
+
+// CacheConfig configures the result cache.
+type CacheConfig struct {
+	Enabled    bool
+	MaxEntries int
+	TTL        map[CacheKind]time.Duration
+}
+
+// CacheHit describes a cached answer returned instead of a fresh one.
+type CacheHit struct {
+	Age time.Duration `json:"age_ms"`
+}
+
+// String renders the hit for tool output.
+func (h *CacheHit) String() string {
+	return fmt.Sprintf("cached result, %s old", h.Age.Round(time.Second))
+}
+
+// NewCacheConfig reads the cache configuration from the environment:
+// RESULT_CACHE=true enables it, RESULT_CACHE_MAX_ENTRIES bounds its size,
+// and RESULT_CACHE_TTL_{QUERY,TABLES,DESCRIBE,COUNT} set the TTLs.
+func NewCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		Enabled:    os.Getenv("RESULT_CACHE") == "true",
+		MaxEntries: getEnvIntOrDefault("RESULT_CACHE_MAX_ENTRIES", 256),
+		TTL: map[CacheKind]time.Duration{
+			CacheQuery:    getEnvTimeout("RESULT_CACHE_TTL_QUERY", 30*time.Second),
+			CacheTables:   getEnvTimeout("RESULT_CACHE_TTL_TABLES", 5*time.Minute),
+			CacheDescribe: getEnvTimeout("RESULT_CACHE_TTL_DESCRIBE", 5*time.Minute),
+			CacheCount:    getEnvTimeout("RESULT_CACHE_TTL_COUNT", 60*time.Second),
+		},
+	}
+}
+
+// ResultCache is a size-bounded LRU with per-entry expiry. It is safe for
+// concurrent use.
+type ResultCache struct {
+	mu      sync.Mutex
+	config  *CacheConfig
+	entries map[string]*list.Element
+	lru     *list.List // front = most recently used
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	key      string
+	value    interface{}
+	tables   map[string]bool // lowercased table names the statement touched
+	anyTable bool            // depends on every table (unknown or global)
+	stored   time.Time
+	expires  time.Time
+}
+
+// NewResultCache creates an empty cache.
+func NewResultCache(config *CacheConfig) *ResultCache {
+	return &ResultCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Key builds the key for a statement. ok is false when the statement
+// should not be cached (cache disabled, kind without TTL, or a statement
+// that does not tokenize cleanly).
+func (rc *ResultCache) Key(kind CacheKind, database, query string, args []interface{}) (string, bool) {
+	if rc == nil || !rc.config.Enabled || rc.config.TTL[kind] <= 0 {
+		return "", false
+	}
+	normalized, ok := NormalizeSQL(query)
+	if !ok {
+		return "", false
+	}
+	var sb strings.Builder
+	sb.WriteString(string(kind))
+	sb.WriteByte(0)
+	sb.WriteString(database)
+	sb.WriteByte(0)
+	sb.WriteString(normalized)
+	for _, a := range args {
+		sb.WriteByte(0)
+		fmt.Fprintf(&sb, "%T:%v", a, a)
+	}
+	return sb.String(), true
+}
+
+// Get returns a live entry and how old it is.
+func (rc *ResultCache) Get(key string) (interface{}, *CacheHit, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	el, ok := rc.entries[key]
+	if !ok {
+		return nil, nil, false
+	}
+	e := el.Value.(*cacheEntry)
+	now := rc.now()
+	if now.After(e.expires) {
+		rc.removeElement(el)
+		return nil, nil, false
+	}
+	rc.lru.MoveToFront(el)
+	return e.value, &CacheHit{Age: now.Sub(e.stored)}, true
+}
+
+// Put stores value under key. tables lists what the statement touched;
+// known=false makes the entry depend on every table.
+func (rc *ResultCache) Put(key string, kind CacheKind, value interface{}, tables []TableRef, known bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	now := rc.now()
+	e := &cacheEntry{
+		key:      key,
+		value:    value,
+		tables:   make(map[string]bool, len(tables)),
+		anyTable: !known,
+		stored:   now,
+		expires:  now.Add(rc.config.TTL[kind]),
+	}
+	for _, t := range tables {
+		e.tables[strings.ToLower(t.Name)] = true
+	}
+
+	if el, ok := rc.entries[key]; ok {
+		rc.removeElement(el)
+	}
+	rc.entries[key] = rc.lru.PushFront(e)
+
+	for rc.config.MaxEntries > 0 && rc.lru.Len() > rc.config.MaxEntries {
+		rc.removeElement(rc.lru.Back())
+	}
+}
+
+// Invalidate drops entries affected by a write to tables. known=false
+// (the written tables could not be determined) drops everything.
+func (rc *ResultCache) Invalidate(tables []TableRef, known bool) {
+	if rc == nil {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if !known {
+		rc.entries = make(map[string]*list.Element)
+		rc.lru.Init()
+		return
+	}
+
+	written := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		written[strings.ToLower(t.Name)] = true
+	}
+	for el := rc.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*cacheEntry)
+		if e.anyTable {
+			rc.removeElement(el)
+		} else {
+			for name := range e.tables {
+				if written[name] {
+					rc.removeElement(el)
+					break
+				}
+			}
+		}
+		el = next
+	}
+}
+
+func (rc *ResultCache) removeElement(el *list.Element) {
+	delete(rc.entries, el.Value.(*cacheEntry).key)
+	rc.lru.Remove(el)
+}
+
+// Len reports the number of live and expired-but-unreaped entries.
+func (rc *ResultCache) Len() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.lru.Len()
+}
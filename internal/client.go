@@ -28,9 +28,11 @@ import (
 type SecurityConfig struct {
 	SafetyKey      string
 	MaxSafeRows    int
-	AllowedTables  []string // Whitelist of allowed tables (empty = all allowed)
-	BlockDDL       bool     // Block DDL operations (CREATE, DROP, ALTER, TRUNCATE, RENAME)
-	RequireConfirm bool     // Require confirmation for large operations
+	AllowedTables  []string       // Whitelist of allowed tables (empty = all allowed)
+	BlockDDL       bool           // Block DDL operations (CREATE, DROP, ALTER, TRUNCATE, RENAME)
+	RequireConfirm bool           // Require confirmation for large operations
+	RequireWhere   bool           // Reject UPDATE/DELETE without a row-selecting WHERE
+	Masking        *MaskingPolicy // Column masking (MASKING_RULES); nil when off
 }
 
 // Client represents a secure MySQL/MariaDB database client.
@@ -42,6 +44,17 @@ type Client struct {
 	securityConfig *SecurityConfig
 	compatConfig   *DBCompatibilityConfig
 	timeoutConfig  *TimeoutConfig
+	resultLimits   ResultLimits
+	autoLimit      int
+	summaryMaxRows int64
+	explainGate    *ExplainGateConfig
+	cache          *ResultCache
+	txns           *txManager
+	confirmConfig  *ConfirmConfig
+	confirms       *confirmTokens
+	undo           *UndoJournal
+	nonTxPolicy    string
+	approvals      *ApprovalQueue
 	detectedDBType DatabaseType
 	connected      bool
 }
@@ -57,14 +70,6 @@ type DatabaseConfig struct {
 	DBType   DatabaseType // Explicit database type (mysql or mariadb)
 }
 
-// QueryResult holds the result of a database query (rows + metadata).
-type QueryResult struct {
-	Columns  []string                 `json:"columns"`
-	Rows     []map[string]interface{} `json:"rows"`
-	RowCount int                      `json:"row_count"`
-	Message  string                   `json:"message,omitempty"`
-}
-
 // TableInfo holds table metadata
 type TableInfo struct {
 	Name    string `json:"name"`
@@ -212,13 +217,15 @@ func NewClient() *Client {
 	dbType := GetDBTypeFromEnv()
 	compatConfig := GetDBCompatibilityConfig(string(dbType))
 
+	timeoutConfig := NewTimeoutConfig()
+
 	config := &DatabaseConfig{
 		Host:     getEnvOrDefault("MYSQL_HOST", "localhost"),
 		Port:     getEnvOrDefault("MYSQL_PORT", "3306"),
 		User:     getEnvOrDefault("MYSQL_USER", ""),
 		Password: getEnvOrDefault("MYSQL_PASSWORD", ""),
 		Database: getEnvOrDefault("MYSQL_DATABASE", ""),
-		Timeout:  30 * time.Second,
+		Timeout:  timeoutConfig.GetTimeout(ProfileConnection),
 		DBType:   dbType,
 	}
 
@@ -234,15 +241,29 @@ func NewClient() *Client {
 		AllowedTables:  parseAllowedTables(os.Getenv("ALLOWED_TABLES")),
 		BlockDDL:       os.Getenv("ALLOW_DDL") != "true",
 		RequireConfirm: true,
+		RequireWhere:   os.Getenv("REQUIRE_WHERE") == "true",
+		Masking:        NewMaskingPolicy(),
 	}
 
-	timeoutConfig := NewTimeoutConfig()
-
 	client := &Client{
 		config:         config,
 		securityConfig: securityConfig,
 		compatConfig:   compatConfig,
 		timeoutConfig:  timeoutConfig,
+		resultLimits: ResultLimits{
+			MaxRows:  getEnvIntOrDefault("MAX_RESULT_ROWS", DefaultMaxResultRows),
+			MaxBytes: getEnvIntOrDefault("MAX_RESULT_BYTES", DefaultMaxResultBytes),
+		},
+		autoLimit:      getEnvIntOrDefault("AUTO_LIMIT", DefaultAutoLimit),
+		summaryMaxRows: int64(getEnvIntOrDefault("SUMMARY_MAX_ROWS", DefaultSummaryMaxRows)),
+		explainGate:    NewExplainGateConfig(),
+		cache:          NewResultCache(NewCacheConfig()),
+		txns:           newTxManager(),
+		confirmConfig:  NewConfirmConfig(),
+		confirms:       &confirmTokens{tokens: make(map[string]confirmToken)},
+		undo:           NewUndoJournal(),
+		nonTxPolicy:    NewNonTxPolicy(),
+		approvals:      NewApprovalQueue(),
 		connected:      false,
 	}
 
@@ -267,14 +288,17 @@ func (c *Client) Connect() error {
 		c.config.Port,
 		c.config.Database)
 
-	// Add timeout parameters
+	// Add timeout parameters. The dial timeout is the connection profile;
+	// socket read/write timeouts are the longest profile so that per-call
+	// contexts, not the driver, decide when a statement has run too long.
 	if !strings.Contains(dsn, "?") {
 		dsn += "?"
 	} else {
 		dsn += "&"
 	}
+	ioTimeout := c.timeoutConfig.Longest()
 	dsn += fmt.Sprintf("timeout=%s&readTimeout=%s&writeTimeout=%s",
-		c.config.Timeout, c.config.Timeout, c.config.Timeout)
+		c.config.Timeout, ioTimeout, ioTimeout)
 
 	db, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -297,7 +321,7 @@ func (c *Client) Connect() error {
 	}
 
 	// Detect actual database type
-	detectedType, version, err := DetectDatabaseType(db)
+	detectedType, version, err := DetectDatabaseType(ctx, db)
 	if err == nil {
 		c.detectedDBType = detectedType
 		log.Printf("Connected to: %s", version)
@@ -313,6 +337,13 @@ func (c *Client) Connect() error {
 	return nil
 }
 
+// UseDB makes the client use db, an open handle, instead of connecting
+// with its own configuration.
+func (c *Client) UseDB(db *sql.DB) {
+	c.db = db
+	c.connected = true
+}
+
 // Close closes the database connection
 func (c *Client) Close() error {
 	if c.db != nil {
@@ -333,6 +364,10 @@ func (c *Client) Close() error {
 //  5. Reject DDL verbs unless BlockDDL is false.
 //  6. Reject INTO OUTFILE / INTO DUMPFILE clauses inside otherwise-legal SELECTs.
 //  7. Reject unknown verbs (whitelist by default).
+//  8. Reject statements touching tables outside ALLOWED_TABLES, or whose
+//     tables cannot be determined while it is set (see allowedtables.go).
+//  9. Reject statements that would return masked columns under another
+//     name (see masking.go).
 //
 // Note: this function does NOT enforce row-count thresholds. The threshold
 // (MaxSafeRows + SafetyKey) is enforced inside Execute() using an explicit
@@ -364,7 +399,10 @@ func (c *Client) ValidateQuery(query string) error {
 		if c.securityConfig.BlockDDL {
 			return fmt.Errorf("DDL operations are blocked. Set ALLOW_DDL=true to enable")
 		}
-		return nil
+		if err := c.checkTableAccess(query); err != nil {
+			return err
+		}
+		return c.securityConfig.Masking.CheckQuery(query)
 	}
 
 	// SELECT/INSERT can still smuggle filesystem access through INTO OUTFILE /
@@ -375,7 +413,10 @@ func (c *Client) ValidateQuery(query string) error {
 	}
 
 	if containsVerb(verb, readOnlyVerbs) || containsVerb(verb, writeVerbs) || containsVerb(verb, callVerbs) {
-		return nil
+		if err := c.checkTableAccess(query); err != nil {
+			return err
+		}
+		return c.securityConfig.Masking.CheckQuery(query)
 	}
 
 	return fmt.Errorf("statement starts with unknown verb %q; only SELECT/WITH/SHOW/DESCRIBE/EXPLAIN/USE and INSERT/UPDATE/DELETE/REPLACE are accepted", verb)
@@ -383,22 +424,41 @@ func (c *Client) ValidateQuery(query string) error {
 
 // ValidateTableAccess checks if access to a table is allowed
 func (c *Client) ValidateTableAccess(tableName string) error {
-	if len(c.securityConfig.AllowedTables) == 0 {
-		return nil // No whitelist, all tables allowed
+	tableName = strings.TrimSpace(tableName)
+	if c.tableAllowed(TableRef{Name: tableName}) {
+		return nil // No whitelist, or a listed table
 	}
 
-	tableName = strings.ToLower(strings.TrimSpace(tableName))
-	for _, allowed := range c.securityConfig.AllowedTables {
-		if strings.ToLower(allowed) == tableName {
-			return nil
-		}
-	}
+	return fmt.Errorf("access to table '%s' is not allowed", strings.ToLower(tableName))
+}
 
-	return fmt.Errorf("access to table '%s' is not allowed", tableName)
+// QueryOptions tunes a single read through QueryWithOptions.
+type QueryOptions struct {
+	// Args are bound to '?' placeholders in the statement.
+	Args []interface{}
+	// Timeout overrides the ProfileQuery timeout for this call. It is
+	// capped at the LongQuery timeout; zero keeps the profile default.
+	Timeout time.Duration
+	// Cache selects the result-cache TTL bucket; CacheNone bypasses it.
+	Cache CacheKind
+	// AutoLimit appends LIMIT AUTO_LIMIT+1 to a SELECT that has no LIMIT
+	// (see autolimit.go) and reports LimitApplied/HasMore on the result.
+	AutoLimit bool
+	// CostGate runs the EXPLAIN_GATE check (see explaingate.go) first.
+	CostGate bool
+	// Tx runs the read inside an open interactive transaction (see
+	// transaction.go) instead of a read-only one; the cache is bypassed.
+	Tx string
 }
 
 // Query executes a SELECT query with security validation
 func (c *Client) Query(query string) (*QueryResult, error) {
+	return c.QueryWithOptions(query, QueryOptions{})
+}
+
+// QueryWithOptions executes a read query with security validation and the
+// per-call options in opts.
+func (c *Client) QueryWithOptions(query string, opts QueryOptions) (*QueryResult, error) {
 	if err := c.Connect(); err != nil {
 		return nil, err
 	}
@@ -408,43 +468,137 @@ func (c *Client) Query(query string) (*QueryResult, error) {
 		return nil, fmt.Errorf("security validation failed: %w", err)
 	}
 
-	// Use timeout configuration for query operations
-	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
-	defer cancel()
+	if opts.Tx != "" {
+		// Uncommitted data must not be cached or served from the cache.
+		opts.Cache = CacheNone
+	}
+
+	limits := c.resultLimits
+	limit := 0
+	if opts.AutoLimit {
+		limit = c.autoLimit
+		if limits.MaxRows > 0 && limit > limits.MaxRows {
+			limit = limits.MaxRows
+		}
+		var applied bool
+		if query, applied = ApplyAutoLimit(query, limit+1); applied {
+			// Room for the probe row that tells whether more rows exist.
+			limits.MaxRows = limit + 1
+		} else {
+			limit = 0
+		}
+	}
+
+	key, cacheable := c.cache.Key(opts.Cache, c.config.Database, query, opts.Args)
+	if cacheable {
+		if v, hit, ok := c.cache.Get(key); ok {
+			cached := *v.(*QueryResult)
+			cached.Cache = hit
+			return &cached, nil
+		}
+	}
 
-	rows, err := c.db.QueryContext(ctx, query)
+	result, err := c.runQuery(query, opts, limits)
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return nil, err
+	}
+	if limit > 0 {
+		result.LimitApplied = limit
+		if len(result.Rows) > limit {
+			result.Rows = result.Rows[:limit]
+			result.RowCount = limit
+			result.HasMore = true
+		}
 	}
-	defer rows.Close()
 
-	return c.processRows(rows)
+	if cacheable {
+		tables, known := ReferencedTables(query)
+		c.cache.Put(key, opts.Cache, result, tables, known)
+	}
+	return result, nil
 }
 
-// QueryPrepared executes a parameterized query (safe from SQL injection)
+// QueryPrepared executes a parameterized query (safe from SQL injection).
+// It is meant for server-built statements and does not run ValidateQuery;
+// anything derived from caller SQL goes through QueryWithOptions instead.
 func (c *Client) QueryPrepared(query string, args ...interface{}) (*QueryResult, error) {
 	if err := c.Connect(); err != nil {
 		return nil, err
 	}
 
-	// Use timeout configuration for query operations
-	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
+	return c.runQuery(query, QueryOptions{Args: args}, c.resultLimits)
+}
+
+// runQuery executes an already-validated read and streams the rows into
+// a QueryResult bounded by limits.
+func (c *Client) runQuery(query string, opts QueryOptions, limits ResultLimits) (*QueryResult, error) {
+	var result *QueryResult
+	costWarning, err := c.runRead(query, opts, func(rows *sql.Rows, cancel context.CancelFunc) (err error) {
+		result, err = c.processRows(rows, cancel, limits)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.maskResult(query, result)
+	result.CostWarning = costWarning
+	return result, nil
+}
+
+// runRead executes an already-validated read under the query timeout
+// profile (or the caller's capped override) and hands the rows to read,
+// which may call cancel to stop the statement early. The statement runs
+// on a pinned connection so it can be killed server-side if the timeout
+// fires, inside a read-only transaction (see readonly.go), or inside the
+// caller's open transaction when opts.Tx is set. It returns the
+// EXPLAIN_GATE=warn finding, if any.
+func (c *Client) runRead(query string, opts QueryOptions, read func(rows *sql.Rows, cancel context.CancelFunc) error) (string, error) {
+	ctx, cancel := c.timeoutConfig.TimeoutContextWithin(context.Background(), ProfileQuery, opts.Timeout)
 	defer cancel()
 
-	// Use prepared statement for safety
-	stmt, err := c.db.PrepareContext(ctx, query)
+	if opts.Tx != "" {
+		return c.readInTransaction(ctx, query, opts, read)
+	}
+
+	conn, err := c.pinConn(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		return "", err
 	}
-	defer stmt.Close()
+	defer conn.Close()
 
-	rows, err := stmt.QueryContext(ctx, args...)
+	// Reads always run in a read-only transaction that is rolled back,
+	// so the server rejects any write the verb classifier missed.
+	tx, err := conn.BeginTx(ctx, readOnlyTxOptions)
 	if err != nil {
-		return nil, fmt.Errorf("query execution failed: %w", err)
+		return "", fmt.Errorf("failed to begin read-only transaction: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	return c.processRows(rows)
+	return c.readIn(ctx, conn, tx, query, opts, func(rows *sql.Rows, _ context.CancelFunc) error {
+		return read(rows, cancel)
+	})
+}
+
+// readIn runs the cost gate and the statement on tx under a kill watch.
+func (c *Client) readIn(ctx context.Context, conn *pinnedConn, tx *sql.Tx, query string, opts QueryOptions, read func(rows *sql.Rows, cancel context.CancelFunc) error) (string, error) {
+	watch := c.watchForKill(ctx, conn)
+	costWarning := ""
+	var err error
+	if opts.CostGate {
+		if costWarning, err = c.checkQueryCost(ctx, tx, query, opts.Args); err != nil {
+			return "", watch.stop(err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, opts.Args...)
+	if err != nil {
+		return "", watch.stop(fmt.Errorf("query execution failed: %w", ReadOnlyViolation(c.withholdError(query, err))))
+	}
+
+	if err = watch.stop(ReadOnlyViolation(read(rows, nil))); err != nil {
+		return "", c.withholdError(query, err)
+	}
+	return costWarning, nil
 }
 
 // Execute runs a non-SELECT query with security validation.
@@ -454,68 +608,142 @@ func (c *Client) QueryPrepared(query string, args ...interface{}) (*QueryResult,
 // is exceeded and no valid confirmKey is provided, the transaction is rolled back
 // so the changes are never committed.
 func (c *Client) Execute(query string, confirmKey string) (*QueryResult, error) {
+	return c.ExecuteWithArgs(query, confirmKey)
+}
+
+// ExecuteWithArgs is Execute with ? placeholders bound to args.
+func (c *Client) ExecuteWithArgs(query string, confirmKey string, args ...interface{}) (*QueryResult, error) {
+	return c.ExecuteWithOptions(query, confirmKey, ExecOptions{}, args...)
+}
+
+// ExecuteWithOptions is ExecuteWithArgs with per-call options. In
+// approval mode writes are queued with RequestApproval instead.
+func (c *Client) ExecuteWithOptions(query string, confirmKey string, opts ExecOptions, args ...interface{}) (*QueryResult, error) {
+	if err := c.refuseInApprovalMode("running a write directly"); err != nil {
+		return nil, err
+	}
+	return c.execute(query, confirmKey, opts, args...)
+}
+
+// execute implements ExecuteWithOptions; ApproveOperation calls it too.
+func (c *Client) execute(query string, confirmKey string, opts ExecOptions, args ...interface{}) (*QueryResult, error) {
+	binding := bindStatement(query, args)
+	confirmed, err := c.checkWherePolicy([]string{query}, opts, binding, confirmKey)
+	if err != nil {
+		return nil, err
+	}
 	if err := c.Connect(); err != nil {
 		return nil, err
 	}
 
 	// Security validation
-	if err := c.ValidateQuery(query); err != nil {
+	err = c.ValidateQuery(query)
+	if err != nil {
 		return nil, fmt.Errorf("security validation failed: %w", err)
 	}
 
-	// Use timeout configuration for write operations
-	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	// DDL gets the admin profile; everything else is a write. DDL commits
+	// implicitly whatever the engine, so only writes look for
+	// non-transactional tables.
+	profile := ProfileWrite
+	var nonTx []string
+	if containsVerb(firstVerb(StripComments(query)), ddlVerbs) {
+		profile = ProfileAdmin
+	} else if nonTx, err = c.nonTxTargets(query); err != nil {
+		return nil, err
+	}
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), profile)
 	defer cancel()
 
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
 	// Execute inside an explicit transaction so we can roll back large
 	// unconfirmed writes before they become visible. This is the actual
 	// implementation of the MAX_SAFE_ROWS safety gate.
-	tx, err := c.db.BeginTx(ctx, nil)
+	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	result, err := tx.ExecContext(ctx, query)
+	// A rollback cannot undo writes to non-transactional tables, so their
+	// gate runs before the statement and replaces the one after it. A
+	// confirmed allow_full_table override already covers both. The row
+	// counts and the undo capture lock rows with FOR UPDATE, so they run
+	// under a kill watch like the statement.
+	stmts := []BatchStatement{{SQL: query, Args: args}}
+	watch := c.watchForKill(ctx, conn)
+	if !confirmed || c.nonTxPolicy == NonTxBlock {
+		err = c.guardNonTx(nonTx, binding, confirmKey, func() (int64, bool, error) {
+			return countStatements(ctx, tx, stmts)
+		})
+	}
+	var undo *undoCapture
+	if err == nil {
+		undo = c.beginUndo(ctx, tx, query, args)
+	}
+	if err = watch.stop(err); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	result, info, err := c.execWrite(ctx, conn, tx, query, args)
 	if err != nil {
 		tx.Rollback()
+		if len(nonTx) > 0 {
+			return nil, fmt.Errorf("execution failed: %w. %s", err, rolledBack(nonTx, ""))
+		}
 		return nil, fmt.Errorf("execution failed: %w", err)
 	}
-
-	affected, _ := result.RowsAffected()
+	affected := info.Affected
 
 	// Row-count safety gate: if the operation touched more rows than allowed,
-	// require the safety key. Without it we roll back so no changes persist.
-	if c.securityConfig.RequireConfirm && affected > int64(c.securityConfig.MaxSafeRows) {
-		if confirmKey != c.securityConfig.SafetyKey {
+	// require confirmation. Without it we roll back so no changes persist.
+	if len(nonTx) == 0 && !confirmed {
+		if err := c.ConfirmWrite(query, args, affected, confirmKey); err != nil {
 			tx.Rollback()
-			return nil, fmt.Errorf(
-				"operation affects %d rows (>%d). Provide safety key to confirm. Changes have been rolled back",
-				affected, c.securityConfig.MaxSafeRows,
-			)
+			return nil, err
 		}
 	}
 
+	undo.finish(ctx, tx, result, affected)
+	undo.save()
+
 	if err := tx.Commit(); err != nil {
+		undo.discard()
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	c.invalidateCache(query)
 
-	return &QueryResult{
+	res := &QueryResult{
 		RowCount: int(affected),
 		Message:  fmt.Sprintf("Query executed successfully. Rows affected: %d", affected),
-	}, nil
+		Write:    info,
+	}
+	undo.describe(res)
+	return res, nil
 }
 
-// ListTablesSimple returns a list of table names
+// ListTablesSimple returns a list of table names, limited to ALLOWED_TABLES
 func (c *Client) ListTablesSimple() ([]string, error) {
 	if err := c.Connect(); err != nil {
 		return nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
 	defer cancel()
 
 	query := "SHOW TABLES"
-	rows, err := c.db.QueryContext(ctx, query)
+	tx, err := c.beginReadOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -527,19 +755,23 @@ func (c *Client) ListTablesSimple() ([]string, error) {
 		if err := rows.Scan(&tableName); err != nil {
 			return nil, err
 		}
-		tables = append(tables, tableName)
+		if c.tableAllowed(TableRef{Name: tableName}) {
+			tables = append(tables, tableName)
+		}
 	}
 
 	return tables, rows.Err()
 }
 
-// ListTables returns detailed table information
-func (c *Client) ListTables() ([]TableInfo, error) {
+// ListTables returns detailed table information, limited to ALLOWED_TABLES
+// when it is set. The CacheHit is non-nil when the answer came from the
+// result cache.
+func (c *Client) ListTables() ([]TableInfo, *CacheHit, error) {
 	if err := c.Connect(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
 	defer cancel()
 
 	query := `
@@ -553,9 +785,23 @@ func (c *Client) ListTables() ([]TableInfo, error) {
 		WHERE TABLE_SCHEMA = DATABASE()
 		ORDER BY TABLE_NAME`
 
-	rows, err := c.db.QueryContext(ctx, query)
+	// TABLE_ROWS changes with every write, so the entry depends on all tables.
+	key, cacheable := c.cache.Key(CacheTables, c.config.Database, query, nil)
+	if cacheable {
+		if v, hit, ok := c.cache.Get(key); ok {
+			return v.([]TableInfo), hit, nil
+		}
+	}
+
+	tx, err := c.beginReadOnly(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -563,31 +809,40 @@ func (c *Client) ListTables() ([]TableInfo, error) {
 	for rows.Next() {
 		var t TableInfo
 		if err := rows.Scan(&t.Name, &t.Type, &t.Engine, &t.Rows, &t.Comment); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		tables = append(tables, t)
+		if c.tableAllowed(TableRef{Name: t.Name}) {
+			tables = append(tables, t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
 	}
 
-	return tables, rows.Err()
+	if cacheable {
+		c.cache.Put(key, CacheTables, tables, nil, false)
+	}
+	return tables, nil, nil
 }
 
-// DescribeTable returns column information for a table
-func (c *Client) DescribeTable(tableName string) ([]ColumnInfo, error) {
+// DescribeTable returns column information for a table. The CacheHit is
+// non-nil when the answer came from the result cache.
+func (c *Client) DescribeTable(tableName string) ([]ColumnInfo, *CacheHit, error) {
 	if err := c.Connect(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Validate table access
 	if err := c.ValidateTableAccess(tableName); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Validate table name to prevent injection
 	if !isValidIdentifier(tableName) {
-		return nil, fmt.Errorf("invalid table name")
+		return nil, nil, fmt.Errorf("invalid table name")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), c.config.Timeout)
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
 	defer cancel()
 
 	query := `
@@ -603,9 +858,22 @@ func (c *Client) DescribeTable(tableName string) ([]ColumnInfo, error) {
 		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
 		ORDER BY ORDINAL_POSITION`
 
-	rows, err := c.db.QueryContext(ctx, query, tableName)
+	key, cacheable := c.cache.Key(CacheDescribe, c.config.Database, query, []interface{}{tableName})
+	if cacheable {
+		if v, hit, ok := c.cache.Get(key); ok {
+			return v.([]ColumnInfo), hit, nil
+		}
+	}
+
+	tx, err := c.beginReadOnly(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, tableName)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer rows.Close()
 
@@ -613,52 +881,76 @@ func (c *Client) DescribeTable(tableName string) ([]ColumnInfo, error) {
 	for rows.Next() {
 		var col ColumnInfo
 		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.Key, &col.Default, &col.Extra, &col.Comment); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		columns = append(columns, col)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
 
-	return columns, rows.Err()
+	if cacheable {
+		c.cache.Put(key, CacheDescribe, columns, []TableRef{{Name: tableName}}, true)
+	}
+	return columns, nil, nil
 }
 
-// processRows converts database rows to QueryResult
-func (c *Client) processRows(rows *sql.Rows) (*QueryResult, error) {
-	columns, err := rows.Columns()
-	if err != nil {
+// ListViews returns the views of the current database with their
+// definitions, limited to ALLOWED_TABLES when it is set.
+func (c *Client) ListViews() (*QueryResult, error) {
+	if err := c.Connect(); err != nil {
 		return nil, err
 	}
 
-	result := &QueryResult{
-		Columns: columns,
-		Rows:    make([]map[string]interface{}, 0),
+	result, err := c.runQuery(`
+		SELECT TABLE_NAME as view_name, VIEW_DEFINITION as definition
+		FROM INFORMATION_SCHEMA.VIEWS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME`, QueryOptions{}, c.resultLimits)
+	if err != nil {
+		return nil, err
 	}
 
-	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePtrs[i] = &values[i]
-		}
-
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return nil, err
+	rows := result.Rows[:0]
+	for _, row := range result.Rows {
+		if c.tableAllowed(TableRef{Name: fmt.Sprintf("%s", row[0])}) {
+			rows = append(rows, row)
 		}
+	}
+	result.Rows, result.RowCount = rows, len(rows)
+	return result, nil
+}
 
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			// Convert []byte to string for JSON serialization
-			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
-			}
-		}
-		result.Rows = append(result.Rows, row)
+// ListIndexes returns the index columns of a table in the current
+// database, in index order.
+func (c *Client) ListIndexes(tableName string) (*QueryResult, error) {
+	if err := c.ValidateTableAccess(tableName); err != nil {
+		return nil, err
 	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	return c.runQuery(`
+		SELECT
+			INDEX_NAME,
+			COLUMN_NAME,
+			NON_UNIQUE,
+			SEQ_IN_INDEX,
+			CARDINALITY
+		FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`, QueryOptions{Args: []interface{}{tableName}}, c.resultLimits)
+}
 
-	result.RowCount = len(result.Rows)
-	return result, rows.Err()
+// invalidateCache drops cached results a committed write may have changed.
+// DDL, and writes whose tables cannot be determined, clear the whole cache.
+func (c *Client) invalidateCache(query string) {
+	if containsVerb(firstVerb(StripComments(query)), ddlVerbs) {
+		c.cache.Invalidate(nil, false)
+		return
+	}
+	c.cache.Invalidate(ReferencedTables(query))
 }
 
 // Helper functions
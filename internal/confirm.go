@@ -0,0 +1,199 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Large-write confirmation.
+//
+// Every write path (Execute, ExecuteBatch, CallProcedure, Commit) ends in
+// confirmLargeWrite once it knows how many rows it changed. Past
+// MAX_SAFE_ROWS the change is held back unless confirmed:
+//
+//   - CONFIRMATION_MODE=key (default): confirm_key must equal SAFETY_KEY.
+//     The key never changes, so once known it unlocks any large write.
+//   - CONFIRMATION_MODE=token: the write is rolled back and the error
+//     carries a one-time token bound to a SHA-256 of the exact statement,
+//     its parameters and the affected-row count. Repeating the same call
+//     with confirm_key set to that token commits, once, within
+//     CONFIRM_TOKEN_TTL (default 5m), and only if the row count is still
+//     the same. SAFETY_KEY is not accepted in this mode.
+//
+// Writes to non-transactional tables cannot be rolled back, so they are
+// confirmed before they run instead (see nontx.go).
+
+// Confirmation modes.
+const (
+	ConfirmModeKey   = "key"
+	ConfirmModeToken = "token"
+)
+
+// DefaultConfirmTokenTTL is the CONFIRM_TOKEN_TTL default.
+const DefaultConfirmTokenTTL = 5 * time.Minute
+
+// ConfirmConfig configures large-write confirmation.
+type ConfirmConfig struct {
+	Mode     string
+	TokenTTL time.Duration
+}
+
+// NewConfirmConfig reads CONFIRMATION_MODE and CONFIRM_TOKEN_TTL.
+func NewConfirmConfig() *ConfirmConfig {
+	mode := strings.ToLower(getEnvOrDefault("CONFIRMATION_MODE", ConfirmModeKey))
+	if mode != ConfirmModeKey && mode != ConfirmModeToken {
+		log.Printf("WARNING: invalid CONFIRMATION_MODE %q, using key", mode)
+		mode = ConfirmModeKey
+	}
+	ttl := getEnvTimeout("CONFIRM_TOKEN_TTL", DefaultConfirmTokenTTL)
+	if ttl <= 0 {
+		log.Printf("WARNING: invalid CONFIRM_TOKEN_TTL, using %s", DefaultConfirmTokenTTL)
+		ttl = DefaultConfirmTokenTTL
+	}
+	return &ConfirmConfig{Mode: mode, TokenTTL: ttl}
+}
+
+// ConfirmationError is returned when a write exceeded MAX_SAFE_ROWS
+// without a valid confirmation.
+type ConfirmationError struct {
+	Subject string // "operation affects 150 rows"
+	Limit   int    // MAX_SAFE_ROWS, or -1 when confirmation is needed regardless of rows
+	Outcome string // what happened to the changes
+	// Token mode only: the token to repeat the call with, and why the
+	// confirm_key given (if any) was not accepted.
+	Token    string
+	TokenTTL time.Duration
+	Rejected bool
+}
+
+func (e *ConfirmationError) Error() string {
+	subject := e.Subject
+	if e.Limit >= 0 {
+		subject = fmt.Sprintf("%s (>%d)", e.Subject, e.Limit)
+	}
+	if e.Token == "" {
+		return fmt.Sprintf("%s. Provide safety key to confirm. %s", subject, e.Outcome)
+	}
+	var sb strings.Builder
+	if e.Rejected {
+		sb.WriteString("confirm_key is not valid for this call (expired, already used, or issued for different SQL, parameters or row count); a new token was issued. ")
+	}
+	fmt.Fprintf(&sb, "%s. %s. To confirm, repeat the same call with confirm_key=%q within %s; the token works once",
+		subject, e.Outcome, e.Token, e.TokenTTL)
+	if e.Limit >= 0 {
+		sb.WriteString(" and only while the row count is unchanged")
+	}
+	sb.WriteString(".")
+	return sb.String()
+}
+
+// confirmTokens holds the outstanding one-time tokens.
+type confirmTokens struct {
+	mu     sync.Mutex
+	tokens map[string]confirmToken
+}
+
+type confirmToken struct {
+	binding string
+	expires time.Time
+}
+
+// confirmLargeWrite decides whether a write that changed affected rows may
+// be committed. binding identifies the call (see bindStatement); subject
+// and outcome word the error.
+func (c *Client) confirmLargeWrite(binding string, affected int64, confirmKey, subject, outcome string) error {
+	limit := c.securityConfig.MaxSafeRows
+	if affected <= int64(limit) {
+		return nil
+	}
+	return c.requireConfirmation(binding, affected, limit, confirmKey, subject, outcome)
+}
+
+// ConfirmWrite is the gate Execute applies after running query with args:
+// it returns a *ConfirmationError when the write changed more than
+// MAX_SAFE_ROWS rows and confirmKey does not confirm it.
+func (c *Client) ConfirmWrite(query string, args []interface{}, affected int64, confirmKey string) error {
+	return c.confirmLargeWrite(bindStatement(query, args), affected, confirmKey,
+		fmt.Sprintf("operation affects %d rows", affected), "Changes have been rolled back")
+}
+
+// requireConfirmation checks confirmKey for a call that needs confirmation
+// whatever its row count; limit is only reported (-1 for none).
+func (c *Client) requireConfirmation(binding string, affected int64, limit int, confirmKey, subject, outcome string) error {
+	if !c.securityConfig.RequireConfirm {
+		return nil
+	}
+
+	if c.confirmConfig.Mode != ConfirmModeToken {
+		if confirmKey == c.securityConfig.SafetyKey {
+			return nil
+		}
+		return &ConfirmationError{Subject: subject, Limit: limit, Outcome: outcome}
+	}
+
+	digest := confirmDigest(binding, affected)
+	if confirmKey != "" && c.confirms.redeem(confirmKey, digest) {
+		return nil
+	}
+	return &ConfirmationError{
+		Subject:  subject,
+		Limit:    limit,
+		Outcome:  outcome,
+		Token:    c.confirms.issue(digest, c.confirmConfig.TokenTTL),
+		TokenTTL: c.confirmConfig.TokenTTL,
+		Rejected: confirmKey != "",
+	}
+}
+
+// bindStatement renders a statement and its arguments for binding. The
+// argument types are included so that 1 and "1" bind differently.
+func bindStatement(query string, args []interface{}) string {
+	var sb strings.Builder
+	sb.WriteString(query)
+	for _, a := range args {
+		fmt.Fprintf(&sb, "\x00%T:%v", a, a)
+	}
+	return sb.String()
+}
+
+func confirmDigest(binding string, affected int64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x01%d", binding, affected)))
+	return hex.EncodeToString(sum[:])
+}
+
+// issue stores a new token for digest and returns it.
+func (t *confirmTokens) issue(digest string, ttl time.Duration) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := "ct_" + hex.EncodeToString(b)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for k, v := range t.tokens {
+		if now.After(v.expires) {
+			delete(t.tokens, k)
+		}
+	}
+	t.tokens[token] = confirmToken{binding: digest, expires: now.Add(ttl)}
+	return token
+}
+
+// redeem consumes token if it is live and bound to digest. A token
+// presented for a different call is consumed too, so it cannot be retried.
+func (t *confirmTokens) redeem(token, digest string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tok, ok := t.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(t.tokens, token)
+	return tok.binding == digest && time.Now().Before(tok.expires)
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"os"
@@ -83,13 +84,13 @@ func GetDBCompatibilityConfig(dbType string) *DBCompatibilityConfig {
 }
 
 // DetectDatabaseType attempts to detect database type from connection
-func DetectDatabaseType(db *sql.DB) (DatabaseType, string, error) {
+func DetectDatabaseType(ctx context.Context, db *sql.DB) (DatabaseType, string, error) {
 	if db == nil {
 		return DBTypeMySQL, "", fmt.Errorf("database connection is nil")
 	}
 
 	var version string
-	err := db.QueryRow("SELECT VERSION()").Scan(&version)
+	err := db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version)
 	if err != nil {
 		return DBTypeMySQL, "", fmt.Errorf("failed to detect database type: %w", err)
 	}
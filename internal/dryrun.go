@@ -0,0 +1,409 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Dry runs.
+//
+// DryRun executes a write inside the usual transaction, captures what it
+// did, and always rolls back. For a single-table UPDATE or DELETE the rows
+// it will touch are read first with a SELECT built from the statement's own
+// table reference and WHERE/ORDER BY/LIMIT tail, in the same transaction.
+// After the write, UPDATE rows are read again by primary key, so the after
+// image shows the same rows even when the update changed the columns the
+// WHERE filters on. For an INSERT into a table with an AUTO_INCREMENT key
+// the after image is read from LAST_INSERT_ID(). Other statements (joins,
+// INSERT ... SELECT without an auto-increment key, REPLACE) report the
+// affected-row count only. At most DryRunSampleRows rows are shown.
+
+// DryRunSampleRows is how many before/after rows a dry run shows.
+const DryRunSampleRows = 10
+
+// RowChange pairs the before and after image of one row; either side is
+// nil for an inserted or deleted row.
+type RowChange struct {
+	Before  []interface{} `json:"before,omitempty"`
+	After   []interface{} `json:"after,omitempty"`
+	Changed []string      `json:"changed,omitempty"`
+}
+
+// DryRunResult is what a rolled-back write would have done.
+type DryRunResult struct {
+	Verb     string      `json:"verb"`
+	Table    string      `json:"table,omitempty"`
+	Affected int64       `json:"affected"`
+	Columns  []string    `json:"columns,omitempty"`
+	Changes  []RowChange `json:"changes,omitempty"`
+	// Matched is the number of rows the before SELECT found, capped at
+	// the sample size.
+	Matched int `json:"matched"`
+	// NeedsConfirm reports that the real run would exceed MAX_SAFE_ROWS.
+	NeedsConfirm bool   `json:"needs_confirm"`
+	Note         string `json:"note,omitempty"`
+	// Masked lists the columns MASKING_RULES masked in the row images.
+	Masked []MaskedColumn `json:"masked,omitempty"`
+}
+
+// dmlShape is the part of a write statement a dry run needs.
+type dmlShape struct {
+	verb   string
+	table  TableRef
+	factor string // table reference as written, alias included
+	tail   string // WHERE / ORDER BY / LIMIT, as written
+	skip   int    // placeholders before the tail
+}
+
+// DryRun runs query with args in a transaction, reports the affected rows
+// and a sample of before/after row images, and rolls back.
+func (c *Client) DryRun(query string, args ...interface{}) (*DryRunResult, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	if err := c.ValidateQuery(query); err != nil {
+		return nil, fmt.Errorf("security validation failed: %w", err)
+	}
+	verb := firstVerb(StripComments(query))
+	if containsVerb(verb, ddlVerbs) {
+		return nil, fmt.Errorf("dry_run is not supported for DDL: MySQL commits DDL implicitly, so it cannot be rolled back")
+	}
+	nonTx, err := c.lookupNonTx(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonTx) > 0 {
+		return nil, fmt.Errorf("dry_run is not supported for %s: the rollback would not undo the write", strings.Join(nonTx, ", "))
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Nothing a dry run does is ever committed.
+	defer tx.Rollback()
+
+	res := &DryRunResult{Verb: verb}
+	shape, ok := parseDMLShape(query)
+	if !ok {
+		res.Note = "no row preview for this statement shape (multi-table, CTE or unparsed); affected rows only"
+	}
+
+	var key []string
+	autoIncrement := false
+	var before *QueryResult
+	if ok {
+		res.Table = shape.table.String()
+		if key, autoIncrement, err = primaryKey(ctx, tx, shape.table); err != nil {
+			return nil, err
+		}
+		if shape.verb == "UPDATE" || shape.verb == "DELETE" {
+			if before, err = readTx(ctx, tx, shape.beforeQuery(), args[min(shape.skip, len(args)):]...); err != nil {
+				return nil, fmt.Errorf("failed to read rows before the write: %w", err)
+			}
+			res.Columns = before.Columns
+			res.Matched = before.RowCount
+		}
+	}
+
+	watch := c.watchForKill(ctx, conn)
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err = watch.stop(err); err != nil {
+		return nil, fmt.Errorf("execution failed: %w", c.withholdError(query, err))
+	}
+	res.Affected, _ = result.RowsAffected()
+	res.NeedsConfirm = c.securityConfig.RequireConfirm && res.Affected > int64(c.securityConfig.MaxSafeRows)
+
+	if ok {
+		switch shape.verb {
+		case "DELETE":
+			for _, row := range before.Rows {
+				res.Changes = append(res.Changes, RowChange{Before: row})
+			}
+		case "UPDATE":
+			if err := updateAfterImage(ctx, tx, shape.table, key, before, res); err != nil {
+				return nil, err
+			}
+		case "INSERT":
+			if err := insertAfterImage(ctx, tx, shape.table, key, autoIncrement, result, res); err != nil {
+				return nil, err
+			}
+		}
+		c.maskDryRun(res, shape.table)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		return nil, fmt.Errorf("failed to roll back dry run: %w", err)
+	}
+	return res, nil
+}
+
+// updateAfterImage re-reads the before rows by primary key and pairs them.
+func updateAfterImage(ctx context.Context, tx *sql.Tx, table TableRef, key []string, before *QueryResult, res *DryRunResult) error {
+	if len(before.Rows) == 0 {
+		return nil
+	}
+	idx := columnIndexes(before.Columns, key)
+	if idx == nil {
+		res.Note = "table has no primary key; showing the before image only"
+		for _, row := range before.Rows {
+			res.Changes = append(res.Changes, RowChange{Before: row})
+		}
+		return nil
+	}
+
+	sel, keyArgs := selectByKey(table, key, idx, before.Rows)
+	after, err := readTx(ctx, tx, sel, keyArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to read rows after the write: %w", err)
+	}
+
+	byKey := make(map[string][]interface{}, len(after.Rows))
+	for _, row := range after.Rows {
+		byKey[rowKey(row, idx)] = row
+	}
+	for _, row := range before.Rows {
+		change := RowChange{Before: row, After: byKey[rowKey(row, idx)]}
+		for i, col := range before.Columns {
+			if change.After != nil && fmt.Sprint(row[i]) != fmt.Sprint(change.After[i]) {
+				change.Changed = append(change.Changed, col)
+			}
+		}
+		res.Changes = append(res.Changes, change)
+	}
+	return nil
+}
+
+// selectByKey builds a SELECT of the rows of table whose primary key
+// (key, at positions idx of each row) matches one of rows.
+func selectByKey(table TableRef, key []string, idx []int, rows [][]interface{}) (string, []interface{}) {
+	var cond []string
+	var keyArgs []interface{}
+	for _, row := range rows {
+		parts := make([]string, len(key))
+		for i, col := range key {
+			parts[i] = quoteIdent(col) + " <=> ?"
+			keyArgs = append(keyArgs, row[idx[i]])
+		}
+		cond = append(cond, "("+strings.Join(parts, " AND ")+")")
+	}
+	return fmt.Sprintf("SELECT * FROM %s WHERE %s", quoteTable(table), strings.Join(cond, " OR ")), keyArgs
+}
+
+// insertAfterImage reads the inserted rows back from LAST_INSERT_ID().
+func insertAfterImage(ctx context.Context, tx *sql.Tx, table TableRef, key []string, autoIncrement bool, result sql.Result, res *DryRunResult) error {
+	first, _ := result.LastInsertId()
+	if !autoIncrement || len(key) != 1 || first == 0 {
+		res.Note = "inserted rows are only previewed for tables with an AUTO_INCREMENT primary key"
+		return nil
+	}
+	after, err := readTx(ctx, tx, fmt.Sprintf("SELECT * FROM %s WHERE %s >= ? ORDER BY %s LIMIT %d",
+		quoteTable(table), quoteIdent(key[0]), quoteIdent(key[0]), DryRunSampleRows), first)
+	if err != nil {
+		return fmt.Errorf("failed to read inserted rows: %w", err)
+	}
+	res.Columns = after.Columns
+	for _, row := range after.Rows {
+		res.Changes = append(res.Changes, RowChange{After: row})
+	}
+	return nil
+}
+
+// beforeQuery selects a sample of the rows an UPDATE or DELETE matches.
+func (s *dmlShape) beforeQuery() string {
+	return fmt.Sprintf("SELECT * FROM (SELECT * FROM %s) AS dry_run LIMIT %d", strings.TrimSpace(s.factor+" "+s.tail), DryRunSampleRows)
+}
+
+// BeforeImageQuery returns the SELECT a dry run uses to read the rows a
+// single-table UPDATE or DELETE will touch, and how many leading
+// arguments of the statement it skips. ok is false for other statements.
+func BeforeImageQuery(query string) (sel string, skip int, ok bool) {
+	shape, ok := parseDMLShape(query)
+	if !ok || shape.verb == "INSERT" {
+		return "", 0, false
+	}
+	return shape.beforeQuery(), shape.skip, true
+}
+
+// parseDMLShape finds the target table and the WHERE/ORDER BY/LIMIT tail
+// of a single-table UPDATE or DELETE, or the target of an INSERT.
+func parseDMLShape(query string) (*dmlShape, bool) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok || len(tokens) == 0 {
+		return nil, false
+	}
+	last := len(tokens) - 1
+	for last > 0 && tokens[last].isPunct(";") {
+		last--
+	}
+	tokens = tokens[:last+1]
+
+	shape := &dmlShape{verb: tokens[0].upper}
+	i := 1
+	for i < len(tokens) && tokens[i].kind == tokWord && dmlModifiers[tokens[i].upper] {
+		i++
+	}
+
+	// listEnd is the SET of an UPDATE: a depth-0 comma before it means
+	// several tables. A DELETE has no assignments, so any comma does.
+	listEnd := len(tokens)
+	switch shape.verb {
+	case "INSERT":
+		if i < len(tokens) && tokens[i].is("INTO") {
+			i++
+		}
+		ref, _, ok := parseTableName(tokens, i)
+		if !ok {
+			return nil, false
+		}
+		shape.table = ref
+		return shape, true
+	case "UPDATE":
+		listEnd = -1
+		for j := i; j < len(tokens); j++ {
+			if tokens[j].depth == 0 && tokens[j].is("SET") {
+				listEnd = j
+				break
+			}
+		}
+		if listEnd <= i {
+			return nil, false
+		}
+		shape.factor = query[tokens[i].pos:tokens[listEnd-1].end]
+	case "DELETE":
+		if i >= len(tokens) || !tokens[i].is("FROM") {
+			return nil, false // multi-table DELETE t1, t2 FROM ...
+		}
+		i++
+	default:
+		return nil, false
+	}
+
+	ref, next, ok := parseTableName(tokens, i)
+	if !ok {
+		return nil, false
+	}
+	shape.table = ref
+
+	tail := len(tokens)
+scan:
+	for j := next; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.depth != 0 {
+			continue
+		}
+		inList := shape.verb == "DELETE" || j < listEnd
+		switch {
+		case t.is("JOIN"), t.is("USING"), t.isPunct(",") && inList:
+			return nil, false
+		case (t.is("WHERE") || t.is("ORDER") || t.is("LIMIT")) && (shape.verb == "DELETE" || j > listEnd):
+			tail = j
+			break scan
+		}
+	}
+	if shape.verb == "DELETE" {
+		shape.factor = query[tokens[i].pos:tokens[tail-1].end]
+	}
+	if tail < len(tokens) {
+		shape.tail = query[tokens[tail].pos:tokens[len(tokens)-1].end]
+	}
+	for _, t := range tokens[:tail] {
+		if t.kind == tokParam {
+			shape.skip++
+		}
+	}
+	return shape, true
+}
+
+// dmlModifiers may follow the verb of an INSERT, UPDATE or DELETE.
+var dmlModifiers = map[string]bool{
+	"LOW_PRIORITY": true, "HIGH_PRIORITY": true, "DELAYED": true, "QUICK": true, "IGNORE": true,
+}
+
+// primaryKey returns the primary key columns of a table and whether the
+// key is AUTO_INCREMENT.
+func primaryKey(ctx context.Context, tx *sql.Tx, table TableRef) (key []string, autoIncrement bool, err error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT COLUMN_NAME, EXTRA
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ? AND COLUMN_KEY = 'PRI'
+		ORDER BY ORDINAL_POSITION
+	`, table.Schema, table.Name)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read primary key: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, extra string
+		if err := rows.Scan(&name, &extra); err != nil {
+			return nil, false, err
+		}
+		key = append(key, name)
+		autoIncrement = autoIncrement || strings.Contains(strings.ToLower(extra), "auto_increment")
+	}
+	return key, autoIncrement, rows.Err()
+}
+
+// readTx runs a server-built SELECT inside tx and reads it whole.
+func readTx(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (*QueryResult, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return ReadRows(rows, ResultLimits{})
+}
+
+// columnIndexes maps names to their positions in columns, or nil if any
+// is missing or names is empty.
+func columnIndexes(columns, names []string) []int {
+	if len(names) == 0 {
+		return nil
+	}
+	idx := make([]int, len(names))
+	for i, n := range names {
+		idx[i] = -1
+		for j, c := range columns {
+			if strings.EqualFold(c, n) {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] < 0 {
+			return nil
+		}
+	}
+	return idx
+}
+
+func rowKey(row []interface{}, idx []int) string {
+	parts := make([]string, len(idx))
+	for i, j := range idx {
+		parts[i] = fmt.Sprint(row[j])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteTable(t TableRef) string {
+	if t.Schema != "" {
+		return quoteIdent(t.Schema) + "." + quoteIdent(t.Name)
+	}
+	return quoteIdent(t.Name)
+}
@@ -0,0 +1,197 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// EXPLAIN cost gate.
+//
+// With EXPLAIN_GATE=warn or reject, the query tool runs EXPLAIN on the
+// statement first, in the same read-only transaction, and checks the
+// optimizer's estimates:
+//
+//   - estimated rows examined: for each SELECT in the plan, the product of
+//     the rows column of its tables (a nested-loop join examines every
+//     combination); the SELECTs are summed. Over EXPLAIN_MAX_ROWS fails.
+//   - full scans: a table accessed with type=ALL whose rows estimate is at
+//     least EXPLAIN_FULL_SCAN_ROWS fails.
+//
+// reject returns a QueryCostError carrying the plan summary; warn runs the
+// query and attaches the same text to the result. Estimates come from
+// table statistics and ignore LIMIT, so they err on the high side for
+// queries that stop early.
+
+// Explain gate modes.
+const (
+	ExplainGateOff    = "off"
+	ExplainGateWarn   = "warn"
+	ExplainGateReject = "reject"
+)
+
+// ExplainGateConfig configures the EXPLAIN cost gate.
+type ExplainGateConfig struct {
+	Mode         string
+	MaxRows      int64
+	FullScanRows int64
+}
+
+// NewExplainGateConfig reads EXPLAIN_GATE, EXPLAIN_MAX_ROWS and
+// EXPLAIN_FULL_SCAN_ROWS from the environment.
+func NewExplainGateConfig() *ExplainGateConfig {
+	mode := strings.ToLower(getEnvOrDefault("EXPLAIN_GATE", ExplainGateOff))
+	switch mode {
+	case ExplainGateOff, ExplainGateWarn, ExplainGateReject:
+	default:
+		log.Printf("WARNING: invalid EXPLAIN_GATE %q, using off", mode)
+		mode = ExplainGateOff
+	}
+	return &ExplainGateConfig{
+		Mode:         mode,
+		MaxRows:      int64(getEnvIntOrDefault("EXPLAIN_MAX_ROWS", 1000000)),
+		FullScanRows: int64(getEnvIntOrDefault("EXPLAIN_FULL_SCAN_ROWS", 100000)),
+	}
+}
+
+// PlanStep is one row of EXPLAIN output.
+type PlanStep struct {
+	ID         string
+	SelectType string
+	Table      string
+	Type       string
+	Key        string
+	Rows       int64
+	Extra      string
+}
+
+// PlanCost is the gate's reading of a plan.
+type PlanCost struct {
+	Steps         []PlanStep
+	EstimatedRows float64
+	Violations    []string
+}
+
+// Summary renders the plan one step per line.
+func (p *PlanCost) Summary() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "estimated rows examined: %.0f", p.EstimatedRows)
+	for _, s := range p.Steps {
+		key := s.Key
+		if key == "" {
+			key = "-"
+		}
+		fmt.Fprintf(&sb, "\n  %s %s table=%s type=%s key=%s rows=%d", s.ID, s.SelectType, s.Table, s.Type, key, s.Rows)
+		if s.Extra != "" {
+			fmt.Fprintf(&sb, " (%s)", s.Extra)
+		}
+	}
+	return sb.String()
+}
+
+// QueryCostError is returned when EXPLAIN_GATE=reject stops a query.
+type QueryCostError struct {
+	Plan *PlanCost
+}
+
+func (e *QueryCostError) Error() string {
+	return fmt.Sprintf("query rejected by EXPLAIN cost gate: %s. Add a selective WHERE on an indexed column or narrow the joins.\n%s",
+		strings.Join(e.Plan.Violations, "; "), e.Plan.Summary())
+}
+
+// EvaluatePlan computes the estimate and the violations for a plan.
+func (g *ExplainGateConfig) EvaluatePlan(steps []PlanStep) *PlanCost {
+	cost := &PlanCost{Steps: steps}
+
+	perSelect := make(map[string]float64)
+	var order []string
+	for _, s := range steps {
+		rows := float64(s.Rows)
+		if rows < 1 {
+			rows = 1
+		}
+		if prev, ok := perSelect[s.ID]; ok {
+			perSelect[s.ID] = prev * rows
+		} else {
+			perSelect[s.ID] = rows
+			order = append(order, s.ID)
+		}
+		if strings.EqualFold(s.Type, "ALL") && g.FullScanRows > 0 && s.Rows >= g.FullScanRows {
+			cost.Violations = append(cost.Violations, fmt.Sprintf("full scan of %s (~%d rows, threshold %d)", s.Table, s.Rows, g.FullScanRows))
+		}
+	}
+	for _, id := range order {
+		cost.EstimatedRows += perSelect[id]
+	}
+	if g.MaxRows > 0 && cost.EstimatedRows > float64(g.MaxRows) {
+		cost.Violations = append([]string{fmt.Sprintf("estimated %.0f rows examined (budget %d)", cost.EstimatedRows, g.MaxRows)}, cost.Violations...)
+	}
+	return cost
+}
+
+// checkQueryCost runs EXPLAIN for query inside tx and applies the gate.
+// It returns a warning for warn mode, or a *QueryCostError for reject.
+func (c *Client) checkQueryCost(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (string, error) {
+	g := c.explainGate
+	verb := firstVerb(StripComments(query))
+	if g.Mode == ExplainGateOff || (verb != "SELECT" && verb != "WITH") {
+		return "", nil
+	}
+
+	steps, err := explainPlan(ctx, tx, query, args)
+	if err != nil {
+		if g.Mode == ExplainGateReject {
+			return "", fmt.Errorf("EXPLAIN cost gate could not read the plan: %w", err)
+		}
+		return fmt.Sprintf("EXPLAIN cost gate skipped: %v", err), nil
+	}
+
+	cost := g.EvaluatePlan(steps)
+	if len(cost.Violations) == 0 {
+		return "", nil
+	}
+	if g.Mode == ExplainGateReject {
+		return "", &QueryCostError{Plan: cost}
+	}
+	return fmt.Sprintf("expensive query: %s\n%s", strings.Join(cost.Violations, "; "), cost.Summary()), nil
+}
+
+// explainPlan runs EXPLAIN and reads the columns the gate needs. Column
+// sets differ between MySQL and MariaDB, so they are looked up by name.
+func explainPlan(ctx context.Context, tx *sql.Tx, query string, args []interface{}) ([]PlanStep, error) {
+	rows, err := tx.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plan, err := ReadRows(rows, ResultLimits{MaxRows: 1000})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]PlanStep, 0, len(plan.Rows))
+	for i := range plan.Rows {
+		m := plan.RowMap(i)
+		str := func(k string) string {
+			if v, ok := m[k]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+			return ""
+		}
+		n, _ := strconv.ParseInt(str("rows"), 10, 64)
+		steps = append(steps, PlanStep{
+			ID:         str("id"),
+			SelectType: str("select_type"),
+			Table:      str("table"),
+			Type:       str("type"),
+			Key:        str("key"),
+			Rows:       n,
+			Extra:      str("Extra"),
+		})
+	}
+	return steps, nil
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Server-side cancellation.
+//
+// When a statement's context expires, database/sql and the driver give up
+// on the client side, but MySQL keeps executing the statement and holding
+// its locks and memory. To stop it for real, every user statement runs on a
+// pinned connection whose server thread ID is known, and a watchdog issues
+// KILL QUERY <id> from a separate control connection when the context ends
+// first.
+//
+// KILL remains a forbidden verb for user SQL (see forbiddenVerbs); only this
+// file issues it, and only against connection IDs it obtained itself.
+
+// pinnedConn is a pool connection reserved for one statement, together with
+// the server-side connection ID needed to kill it from elsewhere.
+type pinnedConn struct {
+	*sql.Conn
+	id int64
+}
+
+// QueryKilledError reports a statement that outlived its context and was
+// stopped on the server with KILL QUERY.
+type QueryKilledError struct {
+	Elapsed time.Duration
+	Reason  error // context.DeadlineExceeded or context.Canceled
+	Err     error // the error the driver returned for the statement
+	KillErr error // non-nil when KILL QUERY itself failed
+}
+
+func (e *QueryKilledError) Error() string {
+	reason := "cancelled"
+	if errors.Is(e.Reason, context.DeadlineExceeded) {
+		reason = "timeout"
+	}
+	secs := int(e.Elapsed.Round(time.Second) / time.Second)
+	if e.KillErr != nil {
+		return fmt.Sprintf("query stopped after %ds (%s) but KILL QUERY failed, it may still be running on the server: %v", secs, reason, e.KillErr)
+	}
+	return fmt.Sprintf("query killed after %ds (%s)", secs, reason)
+}
+
+func (e *QueryKilledError) Unwrap() error { return e.Err }
+
+// pinConn reserves a connection from the pool and reads its CONNECTION_ID().
+func (c *Client) pinConn(ctx context.Context) (*pinnedConn, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var id int64
+	if err := conn.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&id); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read connection id: %w", err)
+	}
+	return &pinnedConn{Conn: conn, id: id}, nil
+}
+
+// killWatch is the watchdog armed for one statement.
+type killWatch struct {
+	start time.Time
+	ctx   context.Context
+	done  chan struct{}
+	res   chan error // nil error = killed; errNotKilled = statement finished first
+}
+
+var errNotKilled = errors.New("not killed")
+
+// watchForKill issues KILL QUERY against pc if ctx ends before stop is
+// called. Call stop exactly once, after the statement's rows are consumed.
+func (c *Client) watchForKill(ctx context.Context, pc *pinnedConn) *killWatch {
+	w := &killWatch{
+		start: time.Now(),
+		ctx:   ctx,
+		done:  make(chan struct{}),
+		res:   make(chan error, 1),
+	}
+	go func() {
+		select {
+		case <-w.done:
+			w.res <- errNotKilled
+		case <-ctx.Done():
+			w.res <- c.killQuery(pc.id)
+		}
+	}()
+	return w
+}
+
+// stop disarms the watchdog. If the statement failed (err != nil) and the
+// watchdog killed it, the error is replaced with a QueryKilledError.
+func (w *killWatch) stop(err error) error {
+	close(w.done)
+	killErr := <-w.res
+	if err == nil || killErr == errNotKilled {
+		return err
+	}
+	if killErr != nil {
+		log.Printf("WARNING: KILL QUERY failed: %v", killErr)
+	}
+	return &QueryKilledError{
+		Elapsed: time.Since(w.start),
+		Reason:  w.ctx.Err(),
+		Err:     err,
+		KillErr: killErr,
+	}
+}
+
+// killQuery runs KILL QUERY on a separate control connection. The ID is an
+// integer we read from the server, so it is formatted directly (KILL does
+// not accept placeholders).
+func (c *Client) killQuery(id int64) error {
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileConnection)
+	defer cancel()
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", id))
+	return err
+}
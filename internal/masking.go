@@ -0,0 +1,569 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Column masking.
+//
+// MASKING_RULES keeps PII out of what the LLM sees. It is a comma-separated
+// list of pattern=strategy entries:
+//
+//	customers.email=partial, *phone*=partial, tax_id=hash, notes=fixed:[removed]
+//
+// A pattern with a dot is table.column and applies when the statement
+// references a matching table; one without is a column-name pattern for
+// every table. Both parts take path.Match wildcards and match
+// case-insensitively; the first matching rule wins. Strategies:
+//
+//   - hash: "hash:" and 16 hex digits of an HMAC-SHA256 keyed with
+//     MASKING_HASH_KEY (random per process when unset), so equal values
+//     stay equal and can still be compared or counted
+//   - partial: e-mail addresses keep their first character and the domain
+//     (j***@x.com); other values keep a quarter of their characters, at
+//     most the last four
+//   - null: NULL
+//   - fixed: the text after "fixed:", or [REDACTED]
+//
+// NULL stays NULL under every strategy.
+//
+// The driver reports the name of a result column but not the table column
+// it came from, so the source is taken from the statement: the result
+// column's name and the tables ReferencedTables finds. Every rule applies
+// when the tables are unknown, as for CALL. runQuery masks rows right after
+// processRows, before the result cache sees them; DryRun masks its row
+// images, CallProcedure its result sets, and summarize mode drops the value
+// statistics of masked columns.
+//
+// To keep result column names meaningful, ValidateQuery refuses statements
+// that select a masked column other than as a plain column (under an alias
+// or inside an expression), and statements that rename result columns
+// (UNION, EXCEPT, INTERSECT, column lists on CTEs and derived tables) or
+// store them in user variables (SELECT ... INTO @v, @v := col) while a
+// select list holds a masked column or *. Masked columns may not be
+// function arguments anywhere in the statement, since functions such as
+// EXTRACTVALUE put their argument in an error message, and server errors
+// from statements a rule applies to are replaced by their error number
+// (WithholdError) unless the error cannot carry row values.
+//
+// Masking covers what is read. A masked column can still be compared in a
+// WHERE clause (WHERE email LIKE 'a%'), copied into an unmasked column by a
+// write, or read through a view that table.column rules do not name; use
+// grants where that matters.
+
+// Masking strategies.
+const (
+	MaskHash    = "hash"
+	MaskPartial = "partial"
+	MaskNull    = "null"
+	MaskFixed   = "fixed"
+)
+
+// DefaultMaskText replaces values under the fixed strategy when no text
+// is given.
+const DefaultMaskText = "[REDACTED]"
+
+// MaskRule masks the result columns matching Column in statements that
+// reference a table matching Table (any statement when Table is empty).
+// Both are lowercase path.Match patterns.
+type MaskRule struct {
+	Table    string
+	Column   string
+	Strategy string
+	// Text is the replacement for MaskFixed.
+	Text string
+}
+
+// MaskedColumn names a result column whose values were masked.
+type MaskedColumn struct {
+	Column   string `json:"column"`
+	Strategy string `json:"strategy"`
+}
+
+// MaskingPolicy is the MASKING_RULES configuration.
+type MaskingPolicy struct {
+	Rules   []MaskRule
+	hashKey []byte
+}
+
+// NewMaskingPolicy reads MASKING_RULES and MASKING_HASH_KEY. It returns nil
+// when no rule is configured. Invalid entries are logged and skipped.
+func NewMaskingPolicy() *MaskingPolicy {
+	p := &MaskingPolicy{}
+	for _, entry := range strings.Split(os.Getenv("MASKING_RULES"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		rule, err := ParseMaskRule(entry)
+		if err != nil {
+			log.Printf("WARNING: invalid MASKING_RULES entry %q skipped: %v", entry, err)
+			continue
+		}
+		p.Rules = append(p.Rules, rule)
+	}
+	if len(p.Rules) == 0 {
+		return nil
+	}
+
+	if key := os.Getenv("MASKING_HASH_KEY"); key != "" {
+		p.hashKey = []byte(key)
+	} else {
+		p.hashKey = make([]byte, 32)
+		rand.Read(p.hashKey)
+		log.Printf("MASKING_HASH_KEY not set: hashed values change when the server restarts")
+	}
+	return p
+}
+
+// ParseMaskRule parses one "pattern=strategy" entry of MASKING_RULES.
+func ParseMaskRule(entry string) (MaskRule, error) {
+	pattern, strategy, ok := strings.Cut(entry, "=")
+	if !ok {
+		return MaskRule{}, fmt.Errorf("expected pattern=strategy")
+	}
+
+	var rule MaskRule
+	rule.Column = strings.ToLower(strings.TrimSpace(pattern))
+	if table, column, ok := strings.Cut(rule.Column, "."); ok {
+		rule.Table, rule.Column = table, column
+	}
+	if rule.Table == "*" {
+		rule.Table = ""
+	}
+	if rule.Column == "" {
+		return MaskRule{}, fmt.Errorf("missing column pattern")
+	}
+	for _, p := range []string{rule.Table, rule.Column} {
+		if _, err := path.Match(p, ""); err != nil {
+			return MaskRule{}, fmt.Errorf("bad pattern %q: %w", p, err)
+		}
+	}
+
+	name, text, hasText := strings.Cut(strings.TrimSpace(strategy), ":")
+	rule.Strategy = strings.ToLower(name)
+	switch rule.Strategy {
+	case MaskHash, MaskPartial, MaskNull:
+		if hasText {
+			return MaskRule{}, fmt.Errorf("only fixed takes a text")
+		}
+	case MaskFixed:
+		rule.Text = text
+		if !hasText {
+			rule.Text = DefaultMaskText
+		}
+	default:
+		return MaskRule{}, fmt.Errorf("unknown strategy %q (hash, partial, null or fixed)", name)
+	}
+	return rule, nil
+}
+
+// applies reports whether any rule can apply to a statement over tables.
+func (p *MaskingPolicy) applies(tables []TableRef, known bool) bool {
+	for _, r := range p.Rules {
+		if r.Table == "" || !known || referencesTable(r.Table, tables) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleFor returns the rule masking the result column named column of a
+// statement over tables, or nil. Every rule applies when known is false.
+func (p *MaskingPolicy) ruleFor(column string, tables []TableRef, known bool) *MaskRule {
+	column = strings.ToLower(column)
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if ok, _ := path.Match(r.Column, column); !ok {
+			continue
+		}
+		if r.Table == "" || !known || referencesTable(r.Table, tables) {
+			return r
+		}
+	}
+	return nil
+}
+
+func referencesTable(pattern string, tables []TableRef) bool {
+	for _, t := range tables {
+		if ok, _ := path.Match(pattern, strings.ToLower(t.Name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskRows masks the values of rows (laid out as columns) in place for a
+// statement over tables, and returns the masked columns.
+func (p *MaskingPolicy) MaskRows(columns []string, rows [][]interface{}, tables []TableRef, known bool) []MaskedColumn {
+	if p == nil {
+		return nil
+	}
+	var masked []MaskedColumn
+	for i, name := range columns {
+		r := p.ruleFor(name, tables, known)
+		if r == nil {
+			continue
+		}
+		masked = append(masked, MaskedColumn{Column: name, Strategy: r.Strategy})
+		for _, row := range rows {
+			if i < len(row) {
+				row[i] = p.mask(r, row[i])
+			}
+		}
+	}
+	return masked
+}
+
+// mask returns v under r's strategy.
+func (p *MaskingPolicy) mask(r *MaskRule, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	switch r.Strategy {
+	case MaskNull:
+		return nil
+	case MaskFixed:
+		return r.Text
+	case MaskHash:
+		mac := hmac.New(sha256.New, p.hashKey)
+		if b, ok := v.([]byte); ok {
+			mac.Write(b)
+		} else {
+			fmt.Fprint(mac, v)
+		}
+		return "hash:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	}
+	if b, ok := v.([]byte); ok {
+		return PartialMask(string(b))
+	}
+	return PartialMask(fmt.Sprint(v))
+}
+
+// PartialMask applies the partial strategy to s.
+func PartialMask(s string) string {
+	if at := strings.LastIndexByte(s, '@'); at > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		return s[:size] + "***" + s[at:]
+	}
+	runes := []rune(s)
+	keep := min(len(runes)/4, 4)
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+// maskResult applies MASKING_RULES to the rows query returned.
+func (c *Client) maskResult(query string, result *QueryResult) {
+	p := c.securityConfig.Masking
+	if p == nil || result == nil {
+		return
+	}
+	tables, known := ReferencedTables(query)
+	result.Masked = p.MaskRows(result.Columns, result.Rows, tables, known)
+}
+
+// maskDryRun applies MASKING_RULES to the row images of a dry run on table.
+func (c *Client) maskDryRun(res *DryRunResult, table TableRef) {
+	p := c.securityConfig.Masking
+	if p == nil || len(res.Changes) == 0 {
+		return
+	}
+	var rows [][]interface{}
+	for _, change := range res.Changes {
+		if change.Before != nil {
+			rows = append(rows, change.Before)
+		}
+		if change.After != nil {
+			rows = append(rows, change.After)
+		}
+	}
+	res.Masked = p.MaskRows(res.Columns, rows, []TableRef{table}, true)
+}
+
+// maskSummary drops the value statistics (min, max, mean, lengths, top
+// values) of masked columns; counts and the distinct estimate stay.
+func (c *Client) maskSummary(query string, summary *ResultSummary) {
+	p := c.securityConfig.Masking
+	if p == nil || summary == nil {
+		return
+	}
+	tables, known := ReferencedTables(query)
+	for _, col := range summary.Columns {
+		r := p.ruleFor(col.Name, tables, known)
+		if r == nil {
+			continue
+		}
+		*col = ColumnSummary{
+			Name: col.Name, Type: col.Type, Kind: col.Kind, Count: col.Count, Nulls: col.Nulls,
+			Distinct: col.Distinct, DistinctExact: col.DistinctExact, Masked: r.Strategy,
+		}
+	}
+}
+
+// CheckQuery refuses a statement whose result column names would not show
+// which masked columns it returns: a masked column selected under an
+// alias or inside an expression, result columns renamed by a set
+// operation or a column list, or values stored in user variables (SELECT
+// ... INTO @v, @v := col) while a select list holds a masked column or *.
+func (p *MaskingPolicy) CheckQuery(query string) error {
+	if p == nil {
+		return nil
+	}
+	tables, known := ReferencedTables(query)
+	if !p.applies(tables, known) {
+		return nil
+	}
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return fmt.Errorf("the statement could not be parsed to apply MASKING_RULES")
+	}
+
+	selectsMasked := false
+	var exprErr error
+	for i, t := range tokens {
+		if t.is("TABLE") {
+			selectsMasked = true // TABLE t is SELECT * FROM t
+			continue
+		}
+		if !t.is("SELECT") {
+			continue
+		}
+		for _, item := range selectItems(tokens, i) {
+			if col := p.maskedName(item, tables, known); col != "" {
+				if !plainColumn(item) && exprErr == nil {
+					exprErr = fmt.Errorf("column %s is masked (MASKING_RULES) and can only be selected as a plain column, without an alias or expression", col)
+				}
+				selectsMasked = true
+			}
+			if len(item) > 0 && item[len(item)-1].isPunct("*") {
+				selectsMasked = true
+			}
+		}
+	}
+	if selectsMasked && assignsVariable(tokens) {
+		return fmt.Errorf("SELECT ... INTO @var and @var := cannot be used on masked columns (MASKING_RULES) or *: the variable would hold the unmasked value")
+	}
+	if exprErr != nil {
+		return exprErr
+	}
+	if selectsMasked && renamesColumns(tokens) {
+		return fmt.Errorf("UNION, EXCEPT, INTERSECT and CTE or derived table column lists rename result columns, so they cannot be used on masked columns (MASKING_RULES) or *")
+	}
+	if col := p.maskedArgument(tokens, tables, known); col != "" {
+		return fmt.Errorf("column %s is masked (MASKING_RULES) and cannot be passed to a function, whose result or error message could reveal it", col)
+	}
+	return nil
+}
+
+// notFunctions are words that can precede "(" without calling a function.
+var notFunctions = map[string]bool{
+	"IN": true, "VALUES": true, "VALUE": true, "AS": true, "ON": true, "USING": true, "WHERE": true,
+	"AND": true, "OR": true, "NOT": true, "XOR": true, "SELECT": true, "FROM": true, "JOIN": true,
+	"EXISTS": true, "ANY": true, "ALL": true, "SOME": true, "OVER": true, "WINDOW": true, "INDEX": true,
+	"KEY": true, "INTO": true, "TABLE": true, "SET": true, "BY": true, "WHEN": true, "THEN": true,
+	"ELSE": true, "CASE": true, "IS": true, "LIKE": true, "BETWEEN": true, "HAVING": true, "LIMIT": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "LATERAL": true, "WITH": true, "RECURSIVE": true,
+	"PARTITION": true, "RETURN": true,
+}
+
+// tableBefore are words after which "name (" is a table with a column
+// list, not a function call.
+var tableBefore = map[string]bool{
+	"INTO": true, "TABLE": true, "INSERT": true, "REPLACE": true, "UPDATE": true, "JOIN": true,
+	"FROM": true, "AS": true,
+}
+
+// maskedArgument returns the first masked column passed, at any depth, to
+// a function call in tokens, or "".
+func (p *MaskingPolicy) maskedArgument(tokens []sqlToken, tables []TableRef, known bool) string {
+	for i := 1; i < len(tokens); i++ {
+		name := tokens[i-1]
+		if !tokens[i].isPunct("(") || name.kind != tokWord || notFunctions[name.upper] {
+			continue
+		}
+		if i >= 2 && (tokens[i-2].isPunct(")") || tokens[i-2].kind == tokWord && tableBefore[tokens[i-2].upper]) {
+			continue
+		}
+		end := matchingParen(tokens, i)
+		if end < 0 {
+			end = len(tokens)
+		}
+		if end+2 < len(tokens) && tokens[end+1].is("AS") && tokens[end+2].isPunct("(") {
+			continue // CTE column list
+		}
+		for k := i + 1; k < end; k++ {
+			if k+1 < end && (tokens[k+1].isPunct("(") || tokens[k+1].isPunct(".")) {
+				continue // function name or qualifier
+			}
+			if col := p.maskedName(tokens[k:k+1], tables, known); col != "" {
+				return col
+			}
+		}
+	}
+	return ""
+}
+
+// assignsVariable reports a user variable assignment: INTO @v or @v := x.
+func assignsVariable(tokens []sqlToken) bool {
+	for i, t := range tokens {
+		if t.isPunct(":=") || t.is("INTO") && i+1 < len(tokens) && tokens[i+1].kind == tokVariable {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedName returns the first column name in item a rule masks, or "".
+func (p *MaskingPolicy) maskedName(item []sqlToken, tables []TableRef, known bool) string {
+	for k, t := range item {
+		if !t.isName() || (t.kind == tokWord && (sqlKeywords[t.upper] || constantWords[t.upper])) {
+			continue
+		}
+		if k+1 < len(item) && item[k+1].isPunct("(") {
+			continue // function call
+		}
+		if p.ruleFor(t.text, tables, known) != nil {
+			return t.text
+		}
+	}
+	return ""
+}
+
+// selectModifiers may follow SELECT before the select list.
+var selectModifiers = map[string]bool{
+	"ALL": true, "DISTINCT": true, "DISTINCTROW": true, "HIGH_PRIORITY": true, "STRAIGHT_JOIN": true,
+	"SQL_SMALL_RESULT": true, "SQL_BIG_RESULT": true, "SQL_BUFFER_RESULT": true, "SQL_NO_CACHE": true,
+	"SQL_CACHE": true, "SQL_CALC_FOUND_ROWS": true,
+}
+
+// selectListEnd are the words that end a select list at its own depth.
+var selectListEnd = map[string]bool{
+	"FROM": true, "INTO": true, "WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "FOR": true, "LOCK": true,
+}
+
+// selectItems splits the select list of the SELECT at tokens[i] into its
+// comma-separated items.
+func selectItems(tokens []sqlToken, i int) [][]sqlToken {
+	d := tokens[i].depth
+	j := i + 1
+	for j < len(tokens) && tokens[j].kind == tokWord && selectModifiers[tokens[j].upper] {
+		j++
+	}
+	var items [][]sqlToken
+	start := j
+	for ; j < len(tokens); j++ {
+		t := tokens[j]
+		if t.depth < d || (t.depth == d && (t.isPunct(";") || (t.kind == tokWord && selectListEnd[t.upper]))) {
+			break
+		}
+		if t.depth == d && t.isPunct(",") {
+			items = append(items, tokens[start:j])
+			start = j + 1
+		}
+	}
+	return append(items, tokens[start:j])
+}
+
+// plainColumn reports whether item is a bare column: col, t.col or
+// db.t.col, with no alias.
+func plainColumn(item []sqlToken) bool {
+	for k, t := range item {
+		if k%2 == 0 && !t.isName() || k%2 == 1 && !t.isPunct(".") {
+			return false
+		}
+	}
+	return len(item)%2 == 1 && len(item) <= 5
+}
+
+// renamesColumns reports a set operation or a CTE or derived table column
+// list, any of which gives result columns names other than their source.
+func renamesColumns(tokens []sqlToken) bool {
+	for i, t := range tokens {
+		switch {
+		case t.is("UNION") || t.is("EXCEPT") || t.is("INTERSECT"):
+			return true
+		case t.isPunct("(") && i > 0 && tokens[i-1].isName():
+			// WITH name (col, ...) AS (...)
+			if end := matchingParen(tokens, i); end > 0 && end+2 < len(tokens) && tokens[end+1].is("AS") && tokens[end+2].isPunct("(") {
+				return true
+			}
+		case t.isPunct(")"):
+			// (subquery) [AS] alias (col, ...)
+			j := i + 1
+			if j < len(tokens) && tokens[j].is("AS") {
+				j++
+			}
+			if j+1 < len(tokens) && tokens[j].isName() && tokens[j+1].isPunct("(") &&
+				!(tokens[j].kind == tokWord && (sqlKeywords[tokens[j].upper] || constantWords[tokens[j].upper] || tokens[j].is("OVER"))) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RedactWarnings drops the messages of warnings raised by query, keeping
+// level and code, when a masking rule applies to the statement: messages
+// such as "Truncated incorrect DOUBLE value: 'john@x.com'" quote row
+// values. It reports whether anything was dropped.
+func (p *MaskingPolicy) RedactWarnings(query string, warnings []SQLWarning) bool {
+	if p == nil || len(warnings) == 0 {
+		return false
+	}
+	if tables, known := ReferencedTables(query); !p.applies(tables, known) {
+		return false
+	}
+	for i := range warnings {
+		warnings[i].Message = ""
+	}
+	return true
+}
+
+// safeErrorNumbers are server errors whose messages name statement text,
+// schema objects or limits but never row values.
+var safeErrorNumbers = map[uint16]bool{
+	1044: true, 1045: true, 1049: true, 1051: true, 1052: true, 1054: true, 1064: true, 1109: true,
+	1142: true, 1143: true, 1146: true, 1205: true, 1213: true, 1317: true, 1792: true, 3024: true,
+}
+
+// withheldError is a server error whose message was withheld because it
+// could contain masked values. Unwrap keeps the original for errors.As.
+type withheldError struct {
+	number uint16
+	err    error
+}
+
+func (e *withheldError) Error() string {
+	return fmt.Sprintf("MySQL error %d (message withheld: MASKING_RULES applies to this statement and the message could contain masked values)", e.number)
+}
+
+func (e *withheldError) Unwrap() error { return e.err }
+
+// WithholdError replaces the message of a server error raised by query
+// when a masking rule applies to the statement.
+func (p *MaskingPolicy) WithholdError(query string, err error) error {
+	var me *mysql.MySQLError
+	if p == nil || !errors.As(err, &me) || safeErrorNumbers[me.Number] {
+		return err
+	}
+	if tables, known := ReferencedTables(query); !p.applies(tables, known) {
+		return err
+	}
+	return &withheldError{number: me.Number, err: err}
+}
+
+// withholdError applies WithholdError under the client's policy.
+func (c *Client) withholdError(query string, err error) error {
+	return c.securityConfig.Masking.WithholdError(query, err)
+}
@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Non-transactional tables.
+//
+// The row-count gate holds a large write back by rolling it back, and
+// MyISAM, MEMORY, ARCHIVE, CSV and the other non-transactional engines
+// ignore a rollback: their rows change as the statement runs. Before a
+// write, the engines of its target tables are looked up in
+// information_schema.TABLES and NONTX_WRITE_POLICY decides what happens
+// when one of them is not transactional:
+//
+//   - "precount" (default): count the rows the write will affect with a
+//     SELECT (or, for INSERT ... VALUES, from the row list) and apply the
+//     MAX_SAFE_ROWS gate to that count before anything runs. Writes that
+//     cannot be counted this way need confirm_key up front.
+//   - "confirm": every such write needs confirm_key up front.
+//   - "block": such writes are refused.
+//   - "off": no check.
+//
+// The target is the table of a single-table statement, or every table a
+// multi-table statement references, in whichever schema it names. A
+// target whose engine cannot be found counts as non-transactional. Views
+// have no engine and are not checked. dry_run refuses non-transactional
+// targets whatever the policy, since it relies on its rollback.
+
+// NONTX_WRITE_POLICY values.
+const (
+	NonTxPrecount = "precount"
+	NonTxConfirm  = "confirm"
+	NonTxBlock    = "block"
+	NonTxOff      = "off"
+)
+
+// NewNonTxPolicy reads NONTX_WRITE_POLICY.
+func NewNonTxPolicy() string {
+	policy := strings.ToLower(getEnvOrDefault("NONTX_WRITE_POLICY", NonTxPrecount))
+	switch policy {
+	case NonTxPrecount, NonTxConfirm, NonTxBlock, NonTxOff:
+		return policy
+	}
+	log.Printf("WARNING: invalid NONTX_WRITE_POLICY %q, using %s", policy, NonTxPrecount)
+	return NonTxPrecount
+}
+
+// transactionalEngines are the engines whose writes a rollback undoes.
+var transactionalEngines = map[string]bool{
+	"INNODB": true, "XTRADB": true, "NDB": true, "NDBCLUSTER": true, "TOKUDB": true, "ROCKSDB": true,
+}
+
+// IsTransactionalEngine reports whether a rollback undoes writes to a
+// table with this engine. An empty engine (a view) counts as
+// transactional, since it says nothing about the base tables.
+func IsTransactionalEngine(engine string) bool {
+	return engine == "" || transactionalEngines[strings.ToUpper(engine)]
+}
+
+// nonTxTargets returns the non-transactional tables the given writes
+// change, as "name (ENGINE)". It is nil when NONTX_WRITE_POLICY=off.
+func (c *Client) nonTxTargets(queries ...string) ([]string, error) {
+	if c.nonTxPolicy == NonTxOff {
+		return nil, nil
+	}
+	return c.lookupNonTx(queries...)
+}
+
+// lookupNonTx is nonTxTargets whatever the policy; DryRun uses it, since
+// its rollback is the whole point.
+func (c *Client) lookupNonTx(queries ...string) ([]string, error) {
+	var refs []TableRef
+	for _, q := range queries {
+		if shape, ok := parseDMLShape(q); ok {
+			refs = append(refs, shape.table)
+		} else {
+			tables, _ := ReferencedTables(q)
+			refs = append(refs, tables...)
+		}
+	}
+	return c.nonTxTables(refs)
+}
+
+// nonTxTables returns the tables in refs whose engine is not
+// transactional, as "name (ENGINE)". Engines come from
+// information_schema.TABLES in each table's own schema; a table that is
+// not found there (a temporary table, or one the statement will fail on)
+// counts as non-transactional, as "name (engine unknown)".
+func (c *Client) nonTxTables(refs []TableRef) ([]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileQuery)
+	defer cancel()
+
+	tx, err := c.beginReadOnly(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var out []string
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		key := strings.ToLower(ref.String())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var engine sql.NullString
+		err := tx.QueryRowContext(ctx,
+			"SELECT ENGINE FROM information_schema.TABLES WHERE TABLE_SCHEMA = COALESCE(NULLIF(?, ''), DATABASE()) AND TABLE_NAME = ?",
+			ref.Schema, ref.Name).Scan(&engine)
+		switch {
+		case err == sql.ErrNoRows:
+			out = append(out, fmt.Sprintf("%s (engine unknown)", ref))
+		case err != nil:
+			return nil, fmt.Errorf("failed to read table engines: %w", err)
+		case !IsTransactionalEngine(engine.String):
+			out = append(out, fmt.Sprintf("%s (%s)", ref, engine.String))
+		}
+	}
+	return out, nil
+}
+
+// guardNonTx applies NONTX_WRITE_POLICY to a write whose non-transactional
+// targets are tables, before it runs. count returns the rows the write
+// will affect, or false when it cannot tell; binding identifies the call
+// as for confirmLargeWrite.
+func (c *Client) guardNonTx(tables []string, binding, confirmKey string, count func() (int64, bool, error)) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	list := strings.Join(tables, ", ")
+	if c.nonTxPolicy == NonTxBlock {
+		return fmt.Errorf("write refused: %s cannot be rolled back, and NONTX_WRITE_POLICY=block forbids writing to non-transactional tables", list)
+	}
+
+	if c.nonTxPolicy == NonTxPrecount && count != nil {
+		n, ok, err := count()
+		if err != nil {
+			return fmt.Errorf("failed to count the rows of the write: %w", err)
+		}
+		if ok {
+			return c.confirmLargeWrite(binding, n, confirmKey,
+				fmt.Sprintf("operation would affect %d rows of %s, which cannot be rolled back", n, list), "Nothing was executed")
+		}
+	}
+	return c.requireConfirmation(binding, -1, -1, confirmKey,
+		fmt.Sprintf("%s cannot be rolled back, so the write must be confirmed before it runs", list), "Nothing was executed")
+}
+
+// countStatements counts the rows stmts will affect on tx, before any of
+// them runs. ok is false when one of them cannot be counted.
+func countStatements(ctx context.Context, tx *sql.Tx, stmts []BatchStatement) (int64, bool, error) {
+	var total int64
+	shapes := make([]*dmlShape, len(stmts))
+	for i, s := range stmts {
+		if n, ok := CountInsertRows(s.SQL); ok {
+			total += n
+			continue
+		}
+		shape, ok := parseDMLShape(s.SQL)
+		if !ok || shape.verb == "INSERT" {
+			return 0, false, nil
+		}
+		shapes[i] = shape
+	}
+	for i, shape := range shapes {
+		if shape == nil {
+			continue
+		}
+		n, err := countMatched(ctx, tx, shape, stmts[i].Args)
+		if err != nil {
+			return 0, false, err
+		}
+		total += n
+	}
+	return total, true, nil
+}
+
+// CountInsertRows returns the rows an INSERT or REPLACE with a VALUES list
+// (or SET assignments) affects at most. REPLACE and ON DUPLICATE KEY
+// UPDATE count a replaced row twice, as the server does. ok is false for
+// other statements, including INSERT ... SELECT.
+func CountInsertRows(query string) (rows int64, ok bool) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok || len(tokens) == 0 || !(tokens[0].is("INSERT") || tokens[0].is("REPLACE")) {
+		return 0, false
+	}
+	perRow := int64(1)
+	if tokens[0].is("REPLACE") {
+		perRow = 2
+	}
+
+	values := -1
+	for i, t := range tokens {
+		if t.depth != 0 {
+			continue
+		}
+		switch {
+		case t.is("SELECT") || t.is("TABLE") || t.is("WITH"):
+			if values < 0 {
+				return 0, false
+			}
+		case t.is("DUPLICATE"):
+			perRow = 2
+		case (t.is("VALUES") || t.is("VALUE")) && values < 0:
+			values = i
+		case t.is("SET") && values < 0:
+			rows, values = 1, len(tokens)
+		}
+	}
+	if values < 0 {
+		return 0, false
+	}
+
+	for i := values + 1; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.depth != 0 {
+			continue
+		}
+		if t.isPunct("(") {
+			rows++
+		} else if t.kind == tokWord && !t.is("ROW") {
+			break
+		}
+	}
+	if rows == 0 {
+		return 0, false
+	}
+	return rows * perRow, true
+}
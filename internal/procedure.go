@@ -0,0 +1,419 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Stored procedure calls.
+//
+// CallProcedure looks the routine up in INFORMATION_SCHEMA, binds IN
+// arguments as placeholders and INOUT/OUT arguments through session user
+// variables, reads every result set the procedure returns, and then reads
+// the OUT values back.
+//
+// Routines declared READS SQL DATA or NO SQL run in a READ ONLY transaction
+// that is always rolled back, like the query tool. Everything else runs
+// under the Execute rules: one transaction, the MAX_SAFE_ROWS gate, commit
+// only when allowed. A CALL has no affected-row count of its own, so the
+// gate counts rows through the session Handler_write/_update/_delete
+// counters. Those also count writes to internal temporary tables, so the
+// number can only err on the high side.
+//
+// The tables a routine writes cannot be determined either, so unless
+// NONTX_WRITE_POLICY=off a routine that may write needs confirmation up
+// front (or is refused under block): it may write to a non-transactional
+// table the rollback cannot undo.
+//
+// With ALLOWED_TABLES set, procedures are refused: the tables they touch
+// cannot be determined. With MASKING_RULES set, result sets are masked by
+// column name, and procedures with OUT or INOUT parameters are refused:
+// nothing ties a parameter to the column its value came from.
+
+// ProcedureParam is one declared parameter of a stored procedure.
+type ProcedureParam struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode"` // IN, OUT or INOUT
+	DataType string `json:"data_type"`
+}
+
+// OutParam is the value an OUT or INOUT parameter held after the call.
+type OutParam struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// ProcedureResult is everything a CALL produced.
+type ProcedureResult struct {
+	ResultSets  []*QueryResult `json:"result_sets"`
+	OutParams   []OutParam     `json:"out_params,omitempty"`
+	ReadOnly    bool           `json:"read_only"`
+	RowsWritten int64          `json:"rows_written"`
+	Message     string         `json:"message"`
+}
+
+// routine is the metadata needed to call a procedure.
+type routine struct {
+	name       string
+	dataAccess string // CONTAINS SQL, NO SQL, READS SQL DATA, MODIFIES SQL DATA
+	params     []ProcedureParam
+}
+
+// readOnly reports whether the routine declares it does not modify data.
+func (r *routine) readOnly() bool {
+	return r.dataAccess == "READS SQL DATA" || r.dataAccess == "NO SQL"
+}
+
+// CallProcedure calls a stored procedure in the current database. args maps
+// IN and INOUT parameter names to JSON-decoded values (nil binds NULL).
+func (c *Client) CallProcedure(name string, args map[string]interface{}, confirmKey string) (*ProcedureResult, error) {
+	if err := c.checkTableAccess("CALL " + name); err != nil {
+		return nil, err
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	if !isValidIdentifier(name) {
+		return nil, fmt.Errorf("invalid procedure name")
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	r, err := lookupRoutine(ctx, conn, name)
+	if err != nil {
+		return nil, err
+	}
+	if c.securityConfig.Masking != nil {
+		for _, p := range r.params {
+			if p.Mode != "IN" {
+				return nil, fmt.Errorf("procedure %s has %s parameter %q, which MASKING_RULES cannot mask: its value does not come from a result column", name, p.Mode, p.Name)
+			}
+		}
+	}
+
+	binding := bindStatement("CALL "+name, []interface{}{args})
+	confirmed := false
+	if !r.readOnly() {
+		if err := c.refuseInApprovalMode(fmt.Sprintf("calling %s (%s)", name, r.dataAccess)); err != nil {
+			return nil, err
+		}
+		if confirmed, err = c.guardProcedureNonTx(r, binding, confirmKey); err != nil {
+			return nil, err
+		}
+	}
+
+	call, callArgs, setup, outVars, err := buildCall(r, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.ValidateQuery(call); err != nil {
+		return nil, fmt.Errorf("security validation failed: %w", err)
+	}
+
+	txOpts := (*sql.TxOptions)(nil)
+	if r.readOnly() {
+		txOpts = readOnlyTxOptions
+	}
+	tx, err := conn.BeginTx(ctx, txOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// INOUT values are seeded into their session variables first. These
+	// are server-built statements on our own connection, not user SQL.
+	for _, s := range setup {
+		if _, err := tx.ExecContext(ctx, s.query, s.args...); err != nil {
+			return nil, fmt.Errorf("failed to bind %s: %w", s.param, err)
+		}
+	}
+
+	// Reading the counters twice measures what one read adds by itself.
+	var before, overhead int64
+	if !r.readOnly() {
+		first, err := handlerRowCount(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		if before, err = handlerRowCount(ctx, tx); err != nil {
+			return nil, err
+		}
+		overhead = before - first
+	}
+
+	res := &ProcedureResult{ReadOnly: r.readOnly()}
+	watch := c.watchForKill(ctx, conn)
+	err = c.readResultSets(ctx, tx, call, callArgs, res)
+	if err = watch.stop(err); err != nil {
+		return nil, fmt.Errorf("procedure call failed: %w", ReadOnlyViolation(c.withholdError(call, err)))
+	}
+
+	if len(outVars) > 0 {
+		if err := readOutParams(ctx, tx, outVars, res); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.readOnly() {
+		res.Message = fmt.Sprintf("Procedure %s (%s) returned %d result set(s); read-only transaction rolled back.", name, r.dataAccess, len(res.ResultSets))
+		return res, nil
+	}
+
+	after, err := handlerRowCount(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	res.RowsWritten = after - before - overhead
+	if res.RowsWritten < 0 {
+		res.RowsWritten = 0
+	}
+
+	if !confirmed {
+		if err := c.confirmLargeWrite(binding, res.RowsWritten, confirmKey,
+			fmt.Sprintf("procedure wrote about %d rows", res.RowsWritten), "Changes have been rolled back"); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	// The tables a procedure touches are not visible from the CALL.
+	c.cache.Invalidate(nil, false)
+
+	res.Message = fmt.Sprintf("Procedure %s executed successfully. Rows written: ~%d", name, res.RowsWritten)
+	return res, nil
+}
+
+// guardProcedureNonTx applies NONTX_WRITE_POLICY to a routine that may
+// write. The tables it writes cannot be known, and a rollback would not
+// undo non-transactional ones, so the call is refused (block) or needs
+// confirmation before it runs, which replaces the gate after it.
+// confirmed reports that the confirmation was given.
+func (c *Client) guardProcedureNonTx(r *routine, binding, confirmKey string) (confirmed bool, err error) {
+	switch c.nonTxPolicy {
+	case NonTxOff:
+		return false, nil
+	case NonTxBlock:
+		return false, fmt.Errorf("call refused: %s (%s) may write to non-transactional tables, and NONTX_WRITE_POLICY=block forbids writes that cannot be rolled back", r.name, r.dataAccess)
+	}
+	err = c.requireConfirmation(binding, -1, -1, confirmKey,
+		fmt.Sprintf("%s (%s) may write to non-transactional tables, which cannot be rolled back, so the call must be confirmed before it runs", r.name, r.dataAccess), "Nothing was executed")
+	return err == nil, err
+}
+
+// lookupRoutine reads a procedure's data-access declaration and parameters.
+func lookupRoutine(ctx context.Context, conn *pinnedConn, name string) (*routine, error) {
+	r := &routine{}
+	err := conn.QueryRowContext(ctx, `
+		SELECT ROUTINE_NAME, SQL_DATA_ACCESS
+		FROM INFORMATION_SCHEMA.ROUTINES
+		WHERE ROUTINE_SCHEMA = DATABASE() AND ROUTINE_TYPE = 'PROCEDURE' AND ROUTINE_NAME = ?
+	`, name).Scan(&r.name, &r.dataAccess)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("procedure '%s' not found in the current database", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up procedure: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `
+		SELECT PARAMETER_NAME, PARAMETER_MODE, DATA_TYPE
+		FROM INFORMATION_SCHEMA.PARAMETERS
+		WHERE SPECIFIC_SCHEMA = DATABASE() AND SPECIFIC_NAME = ? AND ROUTINE_TYPE = 'PROCEDURE'
+		ORDER BY ORDINAL_POSITION
+	`, r.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read procedure parameters: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p ProcedureParam
+		if err := rows.Scan(&p.Name, &p.Mode, &p.DataType); err != nil {
+			return nil, err
+		}
+		p.Mode = strings.ToUpper(p.Mode)
+		p.DataType = strings.ToLower(p.DataType)
+		r.params = append(r.params, p)
+	}
+	return r, rows.Err()
+}
+
+// callSetup is one statement that seeds an OUT/INOUT session variable.
+type callSetup struct {
+	param string
+	query string
+	args  []interface{}
+}
+
+// outVar maps an OUT/INOUT parameter to the session variable holding it.
+type outVar struct {
+	param    string
+	variable string
+}
+
+// buildCall builds the CALL statement for r with args bound. outVars
+// lists the OUT/INOUT parameters and their session variables in order.
+func buildCall(r *routine, args map[string]interface{}) (call string, callArgs []interface{}, setup []callSetup, outVars []outVar, err error) {
+	known := make(map[string]bool, len(r.params))
+	placeholders := make([]string, 0, len(r.params))
+
+	for i, p := range r.params {
+		known[p.Name] = true
+		v, present := args[p.Name]
+		if p.Mode != "OUT" {
+			if !present {
+				return "", nil, nil, nil, fmt.Errorf("missing argument %q (%s %s); pass null for NULL", p.Name, p.Mode, p.DataType)
+			}
+			if v, err = convertProcedureArg(p, v); err != nil {
+				return "", nil, nil, nil, err
+			}
+		} else if present {
+			return "", nil, nil, nil, fmt.Errorf("argument %q is an OUT parameter and cannot be passed", p.Name)
+		}
+
+		if p.Mode == "IN" {
+			placeholders = append(placeholders, "?")
+			callArgs = append(callArgs, v)
+			continue
+		}
+		variable := fmt.Sprintf("@mcp_out_%d", i+1)
+		placeholders = append(placeholders, variable)
+		outVars = append(outVars, outVar{param: p.Name, variable: variable})
+		if p.Mode == "INOUT" {
+			setup = append(setup, callSetup{param: p.Name, query: "SET " + variable + " = ?", args: []interface{}{v}})
+		} else {
+			setup = append(setup, callSetup{param: p.Name, query: "SET " + variable + " = NULL"})
+		}
+	}
+
+	for name := range args {
+		if !known[name] {
+			return "", nil, nil, nil, fmt.Errorf("procedure %s has no parameter %q", r.name, name)
+		}
+	}
+
+	call = fmt.Sprintf("CALL `%s`(%s)", r.name, strings.Join(placeholders, ", "))
+	return call, callArgs, setup, outVars, nil
+}
+
+// BuildCall returns the CALL statement call_procedure runs for procedure
+// name with params and args, and the values bound to its IN parameters.
+func BuildCall(name string, params []ProcedureParam, args map[string]interface{}) (string, []interface{}, error) {
+	call, callArgs, _, _, err := buildCall(&routine{name: name, params: params}, args)
+	return call, callArgs, err
+}
+
+// convertProcedureArg checks a JSON value against the parameter's SQL type.
+func convertProcedureArg(p ProcedureParam, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, string:
+		return val, nil
+	case bool:
+		if val {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case float64:
+		switch p.DataType {
+		case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year", "bit":
+			if val != math.Trunc(val) {
+				return nil, fmt.Errorf("argument %q must be an integer (%s), got %v", p.Name, p.DataType, val)
+			}
+			return int64(val), nil
+		case "decimal", "numeric":
+			return strconv.FormatFloat(val, 'f', -1, 64), nil
+		}
+		return val, nil
+	}
+	return nil, fmt.Errorf("argument %q must be a string, number, boolean or null", p.Name)
+}
+
+// readResultSets runs call and reads every result set it returns.
+func (c *Client) readResultSets(ctx context.Context, tx *sql.Tx, call string, args []interface{}, res *ProcedureResult) error {
+	rows, err := tx.QueryContext(ctx, call, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for {
+		cols, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		if len(cols) > 0 {
+			set, err := ReadRows(rows, c.resultLimits)
+			if err != nil {
+				return err
+			}
+			c.maskResult(call, set)
+			res.ResultSets = append(res.ResultSets, set)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// readOutParams reads the session variables holding OUT/INOUT values.
+func readOutParams(ctx context.Context, tx *sql.Tx, outVars []outVar, res *ProcedureResult) error {
+	selects := make([]string, len(outVars))
+	for i, o := range outVars {
+		selects[i] = o.variable
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT "+strings.Join(selects, ", "))
+	if err != nil {
+		return fmt.Errorf("failed to read OUT parameters: %w", err)
+	}
+	defer rows.Close()
+
+	out, err := ReadRows(rows, ResultLimits{MaxRows: 1})
+	if err != nil {
+		return fmt.Errorf("failed to read OUT parameters: %w", err)
+	}
+	for i, o := range outVars {
+		var v interface{}
+		if len(out.Rows) == 1 {
+			v = out.Rows[0][i]
+		}
+		res.OutParams = append(res.OutParams, OutParam{Name: o.param, Value: v})
+	}
+	return nil
+}
+
+// handlerRowCount returns the session's Handler_write + Handler_update +
+// Handler_delete total. SHOW STATUS can itself write to a temporary table,
+// which CallProcedure measures and subtracts.
+func handlerRowCount(ctx context.Context, tx *sql.Tx) (int64, error) {
+	rows, err := tx.QueryContext(ctx, "SHOW SESSION STATUS WHERE Variable_name IN ('Handler_write', 'Handler_update', 'Handler_delete')")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read session status: %w", err)
+	}
+	defer rows.Close()
+
+	var total int64
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return 0, err
+		}
+		n, _ := strconv.ParseInt(value, 10, 64)
+		total += n
+	}
+	return total, rows.Err()
+}
@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Database-enforced read-only mode.
+//
+// The read tools (query, sample, count, views, indexes, explain,
+// database_info, tables, describe) are gated on their leading verb, but a
+// verb check cannot see that "WITH x AS (...) DELETE ..." (MySQL 8) is a
+// write, or that a SELECT calls a stored function that modifies data. As
+// defence in depth every read runs inside START TRANSACTION READ ONLY and
+// is always rolled back, so the server itself refuses any write.
+//
+// Writes to TEMPORARY tables are still permitted by MySQL inside a
+// read-only transaction; they are session-local and vanish with the
+// connection.
+
+// readOnlyTxOptions is passed to BeginTx for every read.
+var readOnlyTxOptions = &sql.TxOptions{ReadOnly: true}
+
+// erReadOnlyTransaction is ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION.
+const erReadOnlyTransaction = 1792
+
+// beginReadOnly starts a read-only transaction on a pool connection. The
+// caller must always Rollback it.
+func (c *Client) beginReadOnly(ctx context.Context) (*sql.Tx, error) {
+	tx, err := c.db.BeginTx(ctx, readOnlyTxOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	return tx, nil
+}
+
+// ReadOnlyViolation rewrites the server's read-only rejection into a
+// message that tells the caller which tool to use instead. Other errors
+// are returned unchanged.
+func ReadOnlyViolation(err error) error {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) && me.Number == erReadOnlyTransaction {
+		return fmt.Errorf("statement attempted to modify data inside a read-only transaction; use 'execute' for modifications: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,297 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WHERE policy.
+//
+// The row-count gate only sees how many rows a write touched, so a
+// full-table DELETE on a near-empty staging table passes it. With
+// REQUIRE_WHERE=true, UPDATE and DELETE statements (single- or
+// multi-table, with or without a leading WITH) are checked before they
+// run and rejected when:
+//
+//   - there is no top-level WHERE clause (JOIN ... ON conditions do not
+//     count: UPDATE a JOIN b ON ... touches every joined row)
+//   - the WHERE, one of its top-level OR branches, or one of the AND
+//     terms of a branch, references no column at all (WHERE 1=1,
+//     WHERE TRUE, WHERE ? = ?, ... OR 1, WHERE id = id AND 1)
+//   - such a term compares an expression with itself (WHERE id = id)
+//
+// The check is syntactic. Subqueries are assumed to depend on the row. A
+// call can override it with allow_full_table=true
+// (ExecOptions.AllowFullTable), but the agent sets that flag itself, so
+// the override needs confirm_key (SAFETY_KEY or a one-time token) before
+// anything runs. A confirmed override stands in for the row-count gate of
+// the same call.
+
+// ExecOptions are per-call options for writes.
+type ExecOptions struct {
+	// AllowFullTable overrides REQUIRE_WHERE for this call.
+	AllowFullTable bool
+}
+
+// checkWherePolicy applies REQUIRE_WHERE to the statements of one call.
+// With AllowFullTable, the statements it would refuse need confirmKey
+// instead, for binding as for requireConfirmation; confirmed reports that
+// such an override was confirmed.
+func (c *Client) checkWherePolicy(queries []string, opts ExecOptions, binding, confirmKey string) (confirmed bool, err error) {
+	if !c.securityConfig.RequireWhere {
+		return false, nil
+	}
+	var refused error
+	for i, q := range queries {
+		err := CheckWhereClause(q)
+		if err == nil {
+			continue
+		}
+		if len(queries) > 1 {
+			err = fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		if !opts.AllowFullTable {
+			return false, fmt.Errorf("%w (REQUIRE_WHERE). Add a WHERE clause that selects the rows; a write that must change every row needs allow_full_table=true and the user's confirmation (confirm_key)", err)
+		}
+		if refused == nil {
+			refused = err
+		}
+	}
+	if refused == nil {
+		return false, nil
+	}
+	if err := c.requireConfirmation("REQUIRE_WHERE\x00"+binding, -1, -1, confirmKey,
+		fmt.Sprintf("%s, and allow_full_table=true overrides REQUIRE_WHERE only with confirmation", refused), "Nothing was executed"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckWhereClause returns an error when query is an UPDATE or DELETE
+// that has no WHERE clause, or one that does not depend on the row.
+// Other statements pass. A statement that cannot be fully tokenized is
+// refused, including one with a versioned (/*!NNNNN) or MariaDB (/*M!)
+// executable comment, whose WHERE only some servers would see.
+func CheckWhereClause(query string) error {
+	tokens, ok := tokenizeSQL(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	start := dmlVerb(tokens)
+	if start < 0 {
+		return nil
+	}
+	verb := tokens[start].upper
+	if !ok {
+		return fmt.Errorf("%s could not be parsed to check its WHERE clause", verb)
+	}
+
+	where := -1
+	for i := start + 1; i < len(tokens) && !(tokens[i].depth == 0 && tokens[i].isPunct(";")); i++ {
+		if tokens[i].depth == 0 && tokens[i].is("WHERE") {
+			where = i
+			break
+		}
+	}
+	if where < 0 {
+		return fmt.Errorf("%s without a WHERE clause would change every row", verb)
+	}
+
+	end := len(tokens)
+	for i := where + 1; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.depth == 0 && (t.is("ORDER") || t.is("LIMIT") || t.is("RETURNING") || t.isPunct(";")) {
+			end = i
+			break
+		}
+	}
+	if end == where+1 {
+		return fmt.Errorf("%s has an empty WHERE clause", verb)
+	}
+	if problem := whereProblem(tokens[where+1 : end]); problem != "" {
+		return fmt.Errorf("%s with WHERE %s: %s", verb, query[tokens[where+1].pos:tokens[end-1].end], problem)
+	}
+	return nil
+}
+
+// dmlVerb returns the index of the UPDATE or DELETE keyword that starts
+// the statement, looking past a WITH clause, or -1 for other statements.
+func dmlVerb(tokens []sqlToken) int {
+	if len(tokens) == 0 {
+		return -1
+	}
+	if !tokens[0].is("WITH") {
+		if tokens[0].is("UPDATE") || tokens[0].is("DELETE") {
+			return 0
+		}
+		return -1
+	}
+	// The CTE bodies are parenthesized, so the first top-level statement
+	// keyword is the main statement's.
+	for i, t := range tokens[1:] {
+		if t.depth != 0 {
+			continue
+		}
+		switch t.upper {
+		case "UPDATE", "DELETE":
+			if t.kind == tokWord {
+				return i + 1
+			}
+		case "SELECT", "INSERT", "REPLACE", "TABLE", "VALUES":
+			if t.kind == tokWord {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// whereProblem explains why a WHERE expression does not select rows, or
+// returns "".
+func whereProblem(expr []sqlToken) string {
+	if len(expr) == 0 {
+		return "the condition is empty"
+	}
+	depth := expr[0].depth
+	var branches [][]sqlToken
+	from := 0
+	for i, t := range expr {
+		if t.depth == depth && (t.is("OR") || t.isPunct("||")) {
+			branches = append(branches, expr[from:i])
+			from = i + 1
+		}
+	}
+	branches = append(branches, expr[from:])
+
+	for _, b := range branches {
+		if len(b) == 0 {
+			return "the condition is incomplete"
+		}
+		// A branch wrapped in parentheses is checked as an expression.
+		if b[0].isPunct("(") && matchingParen(b, 0) == len(b)-1 && len(b) > 2 {
+			if p := whereProblem(b[1 : len(b)-1]); p != "" {
+				return p
+			}
+			continue
+		}
+		if p := termProblem(b, len(branches) > 1); p != "" {
+			return p
+		}
+	}
+	return ""
+}
+
+// termProblem checks each AND term of an OR branch: WHERE id = id AND 1
+// matches every row although one comparison names a column.
+func termProblem(branch []sqlToken, inOr bool) string {
+	depth := branch[0].depth
+	var terms [][]sqlToken
+	from, between := 0, false
+	for i, t := range branch {
+		if t.depth != depth {
+			continue
+		}
+		switch {
+		case t.is("BETWEEN"):
+			between = true
+		case t.is("AND") && between:
+			between = false // BETWEEN x AND y is one term
+		case t.is("AND") || t.isPunct("&&"):
+			terms = append(terms, branch[from:i])
+			from = i + 1
+		}
+	}
+	terms = append(terms, branch[from:])
+
+	for _, term := range terms {
+		if len(term) == 0 {
+			return "the condition is incomplete"
+		}
+		if term[0].isPunct("(") && matchingParen(term, 0) == len(term)-1 && len(term) > 2 {
+			if p := whereProblem(term[1 : len(term)-1]); p != "" {
+				return p
+			}
+			continue
+		}
+		if !referencesColumn(term) {
+			switch {
+			case len(terms) > 1:
+				return "an AND term references no column, so it does not narrow the rows"
+			case inOr:
+				return "an OR branch references no column, so it matches every row or none"
+			}
+			return "the condition references no column, so it matches every row or none"
+		}
+		if comparesWithItself(term) {
+			return "the condition compares an expression with itself"
+		}
+	}
+	return ""
+}
+
+// constantWords are bare words that never name a column.
+var constantWords = map[string]bool{
+	"TRUE": true, "FALSE": true, "NULL": true, "UNKNOWN": true, "AND": true, "OR": true, "NOT": true,
+	"XOR": true, "IS": true, "IN": true, "BETWEEN": true, "LIKE": true, "ESCAPE": true, "REGEXP": true,
+	"RLIKE": true, "DIV": true, "MOD": true, "BINARY": true, "INTERVAL": true, "CASE": true, "WHEN": true,
+	"THEN": true, "ELSE": true, "END": true, "CURRENT_DATE": true, "CURRENT_TIME": true,
+	"CURRENT_TIMESTAMP": true, "CURRENT_USER": true,
+}
+
+// literalWords start a temporal literal when a string follows them
+// (DATE '2020-01-01'); on their own they may name a column.
+var literalWords = map[string]bool{"DATE": true, "TIME": true, "TIMESTAMP": true}
+
+// referencesColumn reports whether tokens may depend on the row: a
+// quoted identifier, a bare word that is not a keyword or function name,
+// or a subquery.
+func referencesColumn(tokens []sqlToken) bool {
+	for i, t := range tokens {
+		switch {
+		case t.kind == tokIdent:
+			return true
+		case t.kind != tokWord:
+			continue
+		case t.upper == "SELECT":
+			return true
+		case constantWords[t.upper]:
+			continue
+		case literalWords[t.upper] && i+1 < len(tokens) && tokens[i+1].kind == tokString:
+			continue
+		case i+1 < len(tokens) && tokens[i+1].isPunct("("):
+			continue // function call
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// comparesWithItself reports "x = x" (also <=>, >=, <=, LIKE).
+func comparesWithItself(tokens []sqlToken) bool {
+	depth := tokens[0].depth
+	for i, t := range tokens {
+		if t.depth != depth || !(t.isPunct("=") || t.isPunct("<=>") || t.isPunct(">=") || t.isPunct("<=") || t.is("LIKE")) {
+			continue
+		}
+		left, right := tokens[:i], tokens[i+1:]
+		if len(left) == 0 || len(left) != len(right) {
+			return false
+		}
+		for j := range left {
+			if tokenText(left[j]) != tokenText(right[j]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func tokenText(t sqlToken) string {
+	if t.kind == tokIdent || t.kind == tokWord {
+		return strings.ToLower(t.text)
+	}
+	return t.text
+}
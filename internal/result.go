@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default result limits. The formatter only shows the first rows of a
+// result anyway, so these exist to bound memory, not to shape output.
+const (
+	DefaultMaxResultRows  = 1000
+	DefaultMaxResultBytes = 4 << 20 // 4 MiB
+
+	// rowChunkSize is how many rows share one backing allocation.
+	rowChunkSize = 64
+	// valueOverhead approximates the per-value cost of an interface{} slot.
+	valueOverhead = 16
+)
+
+// ResultLimits bounds how much of a result set is read into memory.
+// A zero or negative field disables that limit.
+type ResultLimits struct {
+	MaxRows  int
+	MaxBytes int
+}
+
+// QueryResult holds the result of a database query (rows + metadata).
+//
+// Rows are ordered value arrays aligned with Columns, so a result keeps
+// the column order of the SELECT list and never loses a column because
+// another one has the same name (SELECT a.id, b.id ...). Callers that
+// want name-based access use RowMap, which keys values by UniqueColumns.
+type QueryResult struct {
+	Columns  []string        `json:"columns"`
+	Rows     [][]interface{} `json:"rows"`
+	RowCount int             `json:"row_count"`
+	Message  string          `json:"message,omitempty"`
+	// Truncated is set when MaxRows or MaxBytes stopped the read early;
+	// the rows present are a prefix of the full result.
+	Truncated bool `json:"truncated,omitempty"`
+	// LimitApplied is the automatic LIMIT added to the statement (0 if
+	// none); HasMore reports that rows beyond it exist.
+	LimitApplied int  `json:"limit_applied,omitempty"`
+	HasMore      bool `json:"has_more,omitempty"`
+	// CostWarning is set when EXPLAIN_GATE=warn found the plan expensive.
+	CostWarning string `json:"cost_warning,omitempty"`
+	// Write holds the details of a write statement (Execute).
+	Write *WriteInfo `json:"write,omitempty"`
+	// OperationID names the undo journal entry of a committed write.
+	OperationID string `json:"operation_id,omitempty"`
+	// Cache is set when the result came from the result cache.
+	Cache *CacheHit `json:"cache,omitempty"`
+	// Masked lists the columns MASKING_RULES masked.
+	Masked []MaskedColumn `json:"masked,omitempty"`
+}
+
+// UniqueColumns returns the column names with duplicates renamed so that
+// every name is distinct: the first occurrence keeps its name, later ones
+// get a numeric suffix (id, id_2, id_3). A suffix that would collide with
+// another real column name is skipped.
+func (r *QueryResult) UniqueColumns() []string {
+	return dedupeColumnNames(r.Columns)
+}
+
+// RowMap returns row i keyed by UniqueColumns. It is the compatibility
+// accessor for code that looks values up by column name. Returns nil when
+// i is out of range.
+func (r *QueryResult) RowMap(i int) map[string]interface{} {
+	if i < 0 || i >= len(r.Rows) {
+		return nil
+	}
+	names := r.UniqueColumns()
+	row := r.Rows[i]
+	m := make(map[string]interface{}, len(names))
+	for j, name := range names {
+		if j < len(row) {
+			m[name] = row[j]
+		}
+	}
+	return m
+}
+
+// dedupeColumnNames implements UniqueColumns.
+func dedupeColumnNames(columns []string) []string {
+	original := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		original[c] = true
+	}
+
+	used := make(map[string]bool, len(columns))
+	out := make([]string, len(columns))
+	for i, c := range columns {
+		name := c
+		if used[name] {
+			for n := 2; ; n++ {
+				candidate := c + "_" + strconv.Itoa(n)
+				if !used[candidate] && !original[candidate] {
+					name = candidate
+					break
+				}
+			}
+		}
+		used[name] = true
+		out[i] = name
+	}
+	return out
+}
+
+// ReadRows streams rows into a QueryResult, stopping as soon as limits.MaxRows
+// rows have been kept or the estimated size of the kept values would exceed
+// limits.MaxBytes. In that case Truncated is set and the remaining rows are
+// left unread; the caller owns rows and is responsible for closing it.
+//
+// Scan destinations are allocated once per call and rows are carved out of
+// shared chunks, so the steady-state cost per row is the row's own values.
+func ReadRows(rows *sql.Rows, limits ResultLimits) (*QueryResult, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(columns)
+	result := &QueryResult{
+		Columns: columns,
+		Rows:    make([][]interface{}, 0),
+	}
+
+	values := make([]scanValue, n)
+	ptrs := make([]interface{}, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, t := range types {
+			values[i].binary = IsBinaryType(t.DatabaseTypeName())
+		}
+	}
+
+	var chunk []interface{}
+	size := 0
+	for rows.Next() {
+		if limits.MaxRows > 0 && len(result.Rows) >= limits.MaxRows {
+			result.Truncated = true
+			break
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		rowSize := 0
+		for i := range values {
+			rowSize += valueSize(values[i].v)
+		}
+		if limits.MaxBytes > 0 && size+rowSize > limits.MaxBytes {
+			result.Truncated = true
+			break
+		}
+		size += rowSize
+
+		if len(chunk) < n {
+			chunk = make([]interface{}, n*rowChunkSize)
+		}
+		row := chunk[:n:n]
+		chunk = chunk[n:]
+		for i := range values {
+			row[i] = values[i].v
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	result.RowCount = len(result.Rows)
+	if result.Truncated {
+		return result, nil
+	}
+	return result, rows.Err()
+}
+
+// scanValue is a reusable scan destination. Scanning into it sees the
+// driver's value before database/sql clones it, so []byte is converted to
+// string (for JSON serialization) with a single copy. Binary columns keep
+// their bytes as a []byte copy instead, so images and other blobs are not
+// mangled into invalid UTF-8.
+type scanValue struct {
+	v      interface{}
+	binary bool
+}
+
+// Scan implements sql.Scanner.
+func (s *scanValue) Scan(src interface{}) error {
+	if b, ok := src.([]byte); ok {
+		if s.binary {
+			s.v = append([]byte(nil), b...)
+		} else {
+			s.v = string(b)
+		}
+	} else {
+		s.v = src
+	}
+	return nil
+}
+
+// IsBinaryType reports whether a column type (as reported by the driver's
+// DatabaseTypeName) holds raw bytes rather than text. The MySQL driver
+// reports TEXT and VARCHAR columns as such, and BLOB/VARBINARY only for
+// the binary character set.
+func IsBinaryType(dbType string) bool {
+	switch strings.ToUpper(dbType) {
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "GEOMETRY", "BIT":
+		return true
+	}
+	return false
+}
+
+// valueSize estimates the memory held by one scanned value.
+func valueSize(v interface{}) int {
+	switch t := v.(type) {
+	case string:
+		return valueOverhead + len(t)
+	case []byte:
+		return valueOverhead + len(t)
+	case time.Time:
+		return valueOverhead + 24
+	default:
+		return valueOverhead + 8
+	}
+}
+
+// processRows converts database rows to QueryResult under the given
+// result limits. If the read is truncated, cancel is called before the
+// cursor is closed so the driver abandons the rest of the stream instead
+// of draining it from the server.
+func (c *Client) processRows(rows *sql.Rows, cancel context.CancelFunc, limits ResultLimits) (*QueryResult, error) {
+	result, err := ReadRows(rows, limits)
+	if result != nil && result.Truncated && cancel != nil {
+		cancel()
+	}
+	rows.Close()
+	return result, err
+}
@@ -0,0 +1,369 @@
+package internal
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Saved query library.
+//
+// SAVED_QUERIES_DIR points at a directory of vetted .sql files. Each file is
+// one statement preceded by "-- key: value" front-matter:
+//
+//	-- description: Orders a customer placed since a date
+//	-- mode: read
+//	-- param: customer_id integer Customer primary key
+//	-- param: since date Earliest order date (YYYY-MM-DD)
+//	SELECT id, total FROM orders
+//	WHERE customer_id = :customer_id AND created_at >= :since
+//
+// The file name without .sql is the query name. Placeholders are written
+// :name and are bound as prepared-statement parameters, never spliced into
+// the SQL text. mode is read (default) or write; read queries run like the
+// query tool, write queries like execute, including ValidateQuery and the
+// MAX_SAFE_ROWS gate.
+
+// SavedParamTypes lists the parameter types a saved query may declare.
+var SavedParamTypes = []string{"string", "integer", "number", "boolean", "date", "datetime"}
+
+// SavedParam is one declared parameter of a saved query.
+type SavedParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+// SavedQuery is one parsed .sql file.
+type SavedQuery struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Mode        string       `json:"mode"`
+	Params      []SavedParam `json:"parameters"`
+	SQL         string       `json:"-"`
+}
+
+// SavedQueryLibrary holds the queries loaded from SAVED_QUERIES_DIR.
+type SavedQueryLibrary struct {
+	Dir     string
+	queries map[string]*SavedQuery
+}
+
+var (
+	savedQueryName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]*$`)
+	frontMatter    = regexp.MustCompile(`^--\s*([A-Za-z_]+)\s*:\s*(.*)$`)
+)
+
+// LoadSavedQueries reads every .sql file in dir. Files that fail to parse
+// are logged and skipped so one bad file does not hide the rest.
+func LoadSavedQueries(dir string) (*SavedQueryLibrary, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saved queries directory: %w", err)
+	}
+
+	lib := &SavedQueryLibrary{Dir: dir, queries: make(map[string]*SavedQuery)}
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".sql") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			log.Printf("WARNING: saved query %s: %v", e.Name(), err)
+			continue
+		}
+		q, err := ParseSavedQuery(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())), string(data))
+		if err != nil {
+			log.Printf("WARNING: saved query %s skipped: %v", e.Name(), err)
+			continue
+		}
+		lib.queries[q.Name] = q
+	}
+	return lib, nil
+}
+
+// ParseSavedQuery parses the content of one saved query file.
+func ParseSavedQuery(name, content string) (*SavedQuery, error) {
+	if !savedQueryName.MatchString(name) {
+		return nil, fmt.Errorf("invalid saved query name %q", name)
+	}
+	q := &SavedQuery{Name: name, Mode: "read"}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	body := 0
+	for ; body < len(lines); body++ {
+		line := strings.TrimSpace(lines[body])
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		m := frontMatter.FindStringSubmatch(line)
+		if m == nil {
+			continue // plain comment
+		}
+		value := strings.TrimSpace(m[2])
+		switch strings.ToLower(m[1]) {
+		case "description":
+			q.Description = value
+		case "mode":
+			q.Mode = strings.ToLower(value)
+		case "param":
+			p, err := parseSavedParam(value)
+			if err != nil {
+				return nil, err
+			}
+			for _, existing := range q.Params {
+				if existing.Name == p.Name {
+					return nil, fmt.Errorf("parameter %q declared twice", p.Name)
+				}
+			}
+			q.Params = append(q.Params, p)
+		default:
+			return nil, fmt.Errorf("unknown front-matter key %q", m[1])
+		}
+	}
+
+	q.SQL = strings.TrimRight(strings.TrimSpace(strings.Join(lines[body:], "\n")), ";")
+	if q.SQL == "" {
+		return nil, fmt.Errorf("no SQL statement")
+	}
+	if q.Description == "" {
+		return nil, fmt.Errorf("missing description")
+	}
+
+	verb := firstVerb(StripComments(q.SQL))
+	switch q.Mode {
+	case "read":
+		if !containsVerb(verb, readOnlyVerbs) {
+			return nil, fmt.Errorf("mode read requires a read-only statement, got %s", verb)
+		}
+	case "write":
+		if !containsVerb(verb, writeVerbs) {
+			return nil, fmt.Errorf("mode write requires INSERT, UPDATE, DELETE or REPLACE, got %s", verb)
+		}
+	default:
+		return nil, fmt.Errorf("mode must be read or write, got %q", q.Mode)
+	}
+
+	used, err := NamedPlaceholders(q.SQL)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]bool, len(q.Params))
+	for _, p := range q.Params {
+		declared[p.Name] = true
+		if !used[p.Name] {
+			return nil, fmt.Errorf("parameter %q is declared but not used", p.Name)
+		}
+	}
+	for name := range used {
+		if !declared[name] {
+			return nil, fmt.Errorf("placeholder :%s is not declared", name)
+		}
+	}
+	return q, nil
+}
+
+// parseSavedParam parses "name type [description]".
+func parseSavedParam(value string) (SavedParam, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return SavedParam{}, fmt.Errorf("param needs a name and a type: %q", value)
+	}
+	p := SavedParam{
+		Name:        fields[0],
+		Type:        strings.ToLower(fields[1]),
+		Description: strings.Join(fields[2:], " "),
+	}
+	if !isValidIdentifier(p.Name) {
+		return SavedParam{}, fmt.Errorf("invalid parameter name %q", p.Name)
+	}
+	for _, t := range SavedParamTypes {
+		if p.Type == t {
+			return p, nil
+		}
+	}
+	return SavedParam{}, fmt.Errorf("parameter %q has unknown type %q (want one of %s)", p.Name, p.Type, strings.Join(SavedParamTypes, ", "))
+}
+
+// Get returns the named query.
+func (l *SavedQueryLibrary) Get(name string) (*SavedQuery, bool) {
+	if l == nil {
+		return nil, false
+	}
+	q, ok := l.queries[name]
+	return q, ok
+}
+
+// List returns the queries sorted by name.
+func (l *SavedQueryLibrary) List() []*SavedQuery {
+	if l == nil {
+		return nil
+	}
+	list := make([]*SavedQuery, 0, len(l.queries))
+	for _, q := range l.queries {
+		list = append(list, q)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Bind checks values against the declared parameters and returns the
+// statement with ? placeholders and the arguments in order.
+func (q *SavedQuery) Bind(values map[string]interface{}) (string, []interface{}, error) {
+	converted := make(map[string]interface{}, len(q.Params))
+	for _, p := range q.Params {
+		v, ok := values[p.Name]
+		if !ok || v == nil {
+			return "", nil, fmt.Errorf("missing parameter %q (%s)", p.Name, p.Type)
+		}
+		cv, err := p.Convert(v)
+		if err != nil {
+			return "", nil, err
+		}
+		converted[p.Name] = cv
+	}
+	for name := range values {
+		if _, ok := converted[name]; !ok {
+			return "", nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	return BindNamedParams(q.SQL, converted)
+}
+
+// RunSavedQuery binds values and runs q. Read queries take the query path
+// (ValidateQuery, prepared statement, read-only transaction, result cache);
+// write queries take the Execute path with its MAX_SAFE_ROWS gate.
+func (c *Client) RunSavedQuery(q *SavedQuery, values map[string]interface{}, confirmKey string) (*QueryResult, error) {
+	query, args, err := q.Bind(values)
+	if err != nil {
+		return nil, fmt.Errorf("saved query %s: %w", q.Name, err)
+	}
+	if q.Mode == "write" {
+		return c.ExecuteWithArgs(query, confirmKey, args...)
+	}
+	return c.QueryWithOptions(query, QueryOptions{Args: args, Cache: CacheQuery})
+}
+
+// Convert checks a JSON-decoded value against the parameter type.
+func (p SavedParam) Convert(v interface{}) (interface{}, error) {
+	switch p.Type {
+	case "string":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case "integer":
+		switch n := v.(type) {
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+				return int64(n), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(n, 10, 64); err == nil {
+				return i, nil
+			}
+		}
+	case "number":
+		switch n := v.(type) {
+		case float64:
+			return n, nil
+		case string:
+			if f, err := strconv.ParseFloat(n, 64); err == nil {
+				return f, nil
+			}
+		}
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case "date":
+		if s, ok := v.(string); ok {
+			if _, err := time.Parse("2006-01-02", s); err == nil {
+				return s, nil
+			}
+		}
+	case "datetime":
+		if s, ok := v.(string); ok {
+			for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339} {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t.Format("2006-01-02 15:04:05"), nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("parameter %q must be %s, got %v", p.Name, p.Type, v)
+}
+
+// BindNamedParams rewrites :name placeholders to ? and returns the values
+// in placeholder order. Text inside literals and comments is left alone,
+// and a statement that also uses ? placeholders is rejected.
+func BindNamedParams(query string, values map[string]interface{}) (string, []interface{}, error) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return "", nil, fmt.Errorf("could not parse statement for parameter binding")
+	}
+
+	var sb strings.Builder
+	var args []interface{}
+	last := 0
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind == tokParam {
+			return "", nil, fmt.Errorf("use :name placeholders, not ?")
+		}
+		name, ok := namedPlaceholderAt(tokens, i)
+		if !ok {
+			continue
+		}
+		v, ok := values[name]
+		if !ok {
+			return "", nil, fmt.Errorf("no value for :%s", name)
+		}
+		sb.WriteString(query[last:tokens[i].pos])
+		sb.WriteByte('?')
+		args = append(args, v)
+		last = tokens[i+1].end
+		i++
+	}
+	sb.WriteString(query[last:])
+	return sb.String(), args, nil
+}
+
+// NamedPlaceholders returns the :name placeholders used in query.
+func NamedPlaceholders(query string) (map[string]bool, error) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return nil, fmt.Errorf("could not parse statement")
+	}
+	used := make(map[string]bool)
+	for i := range tokens {
+		if tokens[i].kind == tokParam {
+			return nil, fmt.Errorf("use :name placeholders, not ?")
+		}
+		if name, ok := namedPlaceholderAt(tokens, i); ok {
+			used[name] = true
+		}
+	}
+	return used, nil
+}
+
+// namedPlaceholderAt reports whether tokens[i] is the ':' of a :name
+// placeholder (colon immediately followed by a bare word).
+func namedPlaceholderAt(tokens []sqlToken, i int) (string, bool) {
+	if !tokens[i].isPunct(":") || i+1 >= len(tokens) {
+		return "", false
+	}
+	next := tokens[i+1]
+	if next.kind != tokWord || next.pos != tokens[i].end {
+		return "", false
+	}
+	return next.text, true
+}
@@ -0,0 +1,757 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Lightweight SQL scanning.
+//
+// This is not a SQL parser. It tokenizes a statement (skipping comments,
+// keeping string literals and quoted identifiers intact) and tracks
+// parenthesis depth, which is enough to answer the structural questions
+// the policy layer asks: which tables does this statement reference, does
+// it have a top-level WHERE or LIMIT, where does a clause start and end.
+// When a question cannot be answered with confidence the helpers say so
+// instead of guessing.
+
+// tokenKind classifies a sqlToken.
+type tokenKind int
+
+const (
+	tokWord     tokenKind = iota // keyword or bare identifier
+	tokIdent                     // `backtick` quoted identifier (text is unquoted)
+	tokString                    // 'single' or "double" quoted literal (text is raw, with quotes)
+	tokNumber                    // numeric literal
+	tokPunct                     // operator or punctuation: ( ) , . ; = <> <= ...
+	tokParam                     // ? placeholder
+	tokVariable                  // @user_var or @@system_var
+)
+
+// sqlToken is one lexical token of a statement.
+type sqlToken struct {
+	kind  tokenKind
+	text  string
+	upper string // uppercased text, for keyword comparison
+	depth int    // parenthesis depth the token sits at
+	pos   int    // byte offset of the token in the source
+	end   int    // byte offset just past the token
+}
+
+// is reports whether t is the bare word w (w must be uppercase).
+func (t sqlToken) is(w string) bool {
+	return t.kind == tokWord && t.upper == w
+}
+
+// isPunct reports whether t is the punctuation p.
+func (t sqlToken) isPunct(p string) bool {
+	return t.kind == tokPunct && t.text == p
+}
+
+// isName reports whether t can be an identifier (bare or quoted).
+func (t sqlToken) isName() bool {
+	return t.kind == tokIdent || t.kind == tokWord
+}
+
+// tokenizeSQL splits a statement into tokens. Comments are skipped, except
+// MySQL executable comments (/*! ... */), whose content the server runs and
+// which are therefore tokenized as code. A versioned executable comment
+// (/*!NNNNN ... */) or a MariaDB one (/*M! ... */) is tokenized as code too,
+// but whether the server runs it depends on its version and flavor, so it
+// is reported through ok=false like unbalanced quotes or parentheses.
+func tokenizeSQL(s string) (tokens []sqlToken, ok bool) {
+	depth := 0
+	ok = true
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+
+		case c == '#' || (c == '-' && isLineComment(s[i:])):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+
+		case c == '/' && (strings.HasPrefix(s[i:], "/*!") || strings.HasPrefix(s[i:], "/*M!")):
+			// Executable comment: skip the marker and optional version
+			// number, tokenize the body, and drop the closing "*/".
+			if s[i+2] == 'M' {
+				ok = false
+				i++
+			}
+			i += 3
+			if i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				ok = false
+			}
+			for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+				i++
+			}
+			end := strings.Index(s[i:], "*/")
+			if end < 0 {
+				return tokens, false
+			}
+			inner, innerOK := tokenizeSQL(s[i : i+end])
+			if !innerOK {
+				ok = false
+			}
+			for _, t := range inner {
+				t.depth += depth
+				t.pos += i
+				t.end += i
+				tokens = append(tokens, t)
+			}
+			i += end + 2
+
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return tokens, false
+			}
+			i += 2 + end + 2
+
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			closed := false
+			for j < len(s) {
+				if s[j] == '\\' && c != '`' {
+					j += 2
+					continue
+				}
+				if s[j] == c {
+					if j+1 < len(s) && s[j+1] == c { // doubled quote escape
+						j += 2
+						continue
+					}
+					closed = true
+					break
+				}
+				j++
+			}
+			if !closed {
+				return tokens, false
+			}
+			if c == '`' {
+				name := strings.ReplaceAll(s[i+1:j], "``", "`")
+				tokens = append(tokens, sqlToken{kind: tokIdent, text: name, upper: strings.ToUpper(name), depth: depth, pos: i, end: j + 1})
+			} else {
+				tokens = append(tokens, sqlToken{kind: tokString, text: s[i : j+1], depth: depth, pos: i, end: j + 1})
+			}
+			i = j + 1
+
+		case c == '(':
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: "(", depth: depth, pos: i, end: i + 1})
+			depth++
+			i++
+
+		case c == ')':
+			depth--
+			if depth < 0 {
+				ok = false
+				depth = 0
+			}
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: ")", depth: depth, pos: i, end: i + 1})
+			i++
+
+		case c == '?':
+			tokens = append(tokens, sqlToken{kind: tokParam, text: "?", depth: depth, pos: i, end: i + 1})
+			i++
+
+		case c == '@':
+			j := i + 1
+			for j < len(s) && (s[j] == '@' || isWordByte(s[j]) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{kind: tokVariable, text: s[i:j], upper: strings.ToUpper(s[i:j]), depth: depth, pos: i, end: j})
+			i = j
+
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9' && !prevIsName(tokens)):
+			j := i
+			for j < len(s) && (isWordByte(s[j]) || s[j] == '.') {
+				j++
+			}
+			// Signed exponent: 1e-5, 2.5E+3
+			if (s[j-1] == 'e' || s[j-1] == 'E') && j+1 < len(s) && (s[j] == '-' || s[j] == '+') && s[j+1] >= '0' && s[j+1] <= '9' {
+				j++
+				for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+			}
+			text := s[i:j]
+			// Identifiers may start with a digit (e.g. 1st_table) as long
+			// as they are not a numeric literal.
+			kind := tokWord
+			if numberPattern.MatchString(text) {
+				kind = tokNumber
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: text, upper: strings.ToUpper(text), depth: depth, pos: i, end: j})
+			i = j
+
+		case isWordByte(c) || c >= 0x80:
+			j := i
+			for j < len(s) && (isWordByte(s[j]) || s[j] >= 0x80) {
+				j++
+			}
+			text := s[i:j]
+			tokens = append(tokens, sqlToken{kind: tokWord, text: text, upper: strings.ToUpper(text), depth: depth, pos: i, end: j})
+			i = j
+
+		default:
+			j := i + 1
+			// Multi-character operators.
+			for _, op := range []string{"<=>", "<>", "<=", ">=", "!=", ":=", "||", "&&", "->>", "->", "<<", ">>"} {
+				if strings.HasPrefix(s[i:], op) {
+					j = i + len(op)
+					break
+				}
+			}
+			tokens = append(tokens, sqlToken{kind: tokPunct, text: s[i:j], depth: depth, pos: i, end: j})
+			i = j
+		}
+	}
+	if depth != 0 {
+		ok = false
+	}
+	return tokens, ok
+}
+
+// numberPattern matches decimal, hex (0x..) and binary (0b..) literals.
+var numberPattern = regexp.MustCompile(`^(0[xX][0-9a-fA-F]+|0[bB][01]+|[0-9]*\.?[0-9]+\.?([eE][-+]?[0-9]+)?)$`)
+
+// isLineComment reports whether s starts a "--" comment. MySQL only treats
+// "--" as a comment when whitespace or a control character follows it, so
+// "0--0" is 0 minus minus 0.
+func isLineComment(s string) bool {
+	return strings.HasPrefix(s, "--") && (len(s) == 2 || s[2] <= ' ')
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// prevIsName reports whether the last token is a name, in which case a
+// following ".5" is a qualifier separator, not a decimal.
+func prevIsName(tokens []sqlToken) bool {
+	return len(tokens) > 0 && tokens[len(tokens)-1].isName()
+}
+
+// matchingParen returns the index of the ')' closing the '(' at open, or -1.
+func matchingParen(tokens []sqlToken, open int) int {
+	d := tokens[open].depth
+	for j := open + 1; j < len(tokens); j++ {
+		if tokens[j].isPunct(")") && tokens[j].depth == d {
+			return j
+		}
+	}
+	return -1
+}
+
+// NormalizeSQL returns a canonical form of a statement that ignores
+// comments, whitespace and keyword case but keeps literals and identifiers
+// exactly. Two statements with the same normal form are the same statement.
+// ok is false when the statement could not be fully tokenized.
+func NormalizeSQL(query string) (normalized string, ok bool) {
+	tokens, ok := tokenizeSQL(query)
+	var sb strings.Builder
+	for i, t := range tokens {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		switch t.kind {
+		case tokWord:
+			if sqlKeywords[t.upper] {
+				sb.WriteString(t.upper)
+			} else {
+				sb.WriteString(t.text)
+			}
+		case tokIdent:
+			sb.WriteString("`" + strings.ReplaceAll(t.text, "`", "``") + "`")
+		default:
+			sb.WriteString(t.text)
+		}
+	}
+	return sb.String(), ok
+}
+
+// TableRef is a table referenced by a statement. Schema is empty when the
+// reference is unqualified (i.e. the connection's current database).
+type TableRef struct {
+	Schema string
+	Name   string
+}
+
+// String returns the reference as written: "schema.table" or "table".
+func (t TableRef) String() string {
+	if t.Schema != "" {
+		return t.Schema + "." + t.Name
+	}
+	return t.Name
+}
+
+// sqlKeywords are reserved words that can never be a table alias. They end
+// a table factor when scanning FROM/JOIN/UPDATE lists.
+var sqlKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "BY": true,
+	"HAVING": true, "LIMIT": true, "OFFSET": true, "UNION": true, "EXCEPT": true, "INTERSECT": true,
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true, "OUTER": true, "CROSS": true,
+	"NATURAL": true, "STRAIGHT_JOIN": true, "ON": true, "USING": true, "AS": true, "SET": true,
+	"VALUES": true, "VALUE": true, "INTO": true, "FOR": true, "LOCK": true, "WINDOW": true,
+	"USE": true, "IGNORE": true, "FORCE": true, "PARTITION": true, "RETURNING": true,
+	"INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "WITH": true, "RECURSIVE": true,
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true, "LIKE": true,
+	"BETWEEN": true, "EXISTS": true, "CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"DISTINCT": true, "ALL": true, "ASC": true, "DESC": true, "DUPLICATE": true, "KEY": true,
+	"LATERAL": true, "TABLE": true, "INDEX": true, "PROCEDURE": true, "SHARE": true, "MODE": true,
+}
+
+// ReferencedTables returns every table a statement reads or writes:
+// FROM and JOIN lists (including comma joins and subqueries at any depth),
+// UPDATE targets, INSERT/REPLACE targets, DELETE ... USING lists, TABLE
+// statements, DESCRIBE targets, SHOW COLUMNS/INDEX/CREATE TABLE targets,
+// and the table of DDL statements. Common table expression names defined by WITH are excluded.
+//
+// ok is false when the statement's tables cannot be determined with
+// confidence: unbalanced quoting, CALL (a procedure can touch anything),
+// SHOW forms that enumerate tables, or any FROM/JOIN position holding
+// something that is neither a table name nor a subquery.
+func ReferencedTables(query string) (tables []TableRef, ok bool) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok || len(tokens) == 0 {
+		return nil, false
+	}
+
+	ctes := cteNames(tokens)
+	seen := map[string]bool{}
+	add := func(ref TableRef) {
+		if ref.Schema == "" && ctes[strings.ToLower(ref.Name)] {
+			return
+		}
+		if ref.Schema == "" && strings.EqualFold(ref.Name, "DUAL") {
+			return
+		}
+		key := strings.ToLower(ref.String())
+		if !seen[key] {
+			seen[key] = true
+			tables = append(tables, ref)
+		}
+	}
+
+	first := tokens[0].upper
+	switch first {
+	case "CALL", "EXEC", "EXECUTE":
+		return nil, false
+	case "SHOW":
+		return showTables(tokens)
+	case "DESCRIBE", "DESC", "EXPLAIN":
+		if len(tokens) > 1 && !sqlKeywords[tokens[1].upper] && tokens[1].isName() && !isStatementVerb(tokens[1].upper) {
+			ref, _, good := parseTableName(tokens, 1)
+			if !good {
+				return nil, false
+			}
+			add(ref)
+			return tables, true
+		}
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME":
+		return ddlTables(tokens)
+	}
+
+	if !scanTableRefs(tokens, false, add) {
+		return nil, false
+	}
+	return tables, true
+}
+
+// scanTableRefs walks tokens and reports every table reference through add.
+// When fromList is set, tokens start with a table list (the inside of a
+// parenthesized join such as "FROM (t1 JOIN t2 ON ...)").
+func scanTableRefs(tokens []sqlToken, fromList bool, add func(TableRef)) bool {
+	// queryScope[d] records whether the innermost parenthesis at depth d
+	// holds a statement (so FROM there is a table list) rather than a
+	// function call like EXTRACT(YEAR FROM d) or TRIM(x FROM y).
+	queryScope := map[int]bool{}
+	start := 0
+	if fromList && len(tokens) > 0 {
+		queryScope[tokens[0].depth] = true
+		next, ok := parseTableList(tokens, 0, add)
+		if !ok {
+			return false
+		}
+		start = next
+	}
+
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.isPunct("(") {
+			queryScope[t.depth+1] = false
+			continue
+		}
+		if t.kind != tokWord {
+			continue
+		}
+
+		switch t.upper {
+		case "SELECT", "DELETE", "WITH":
+			queryScope[t.depth] = true
+
+		case "INSERT", "REPLACE":
+			j := i + 1
+			for j < len(tokens) && (tokens[j].is("LOW_PRIORITY") || tokens[j].is("DELAYED") || tokens[j].is("HIGH_PRIORITY") || tokens[j].is("IGNORE")) {
+				j++
+			}
+			if j < len(tokens) && tokens[j].is("INTO") {
+				j++
+			}
+			if j < len(tokens) && tokens[j].isPunct("(") {
+				continue // REPLACE(str, from, to) function
+			}
+			queryScope[t.depth] = true
+			ref, next, good := parseTableName(tokens, j)
+			if !good {
+				return false
+			}
+			add(ref)
+			i = next - 1
+
+		case "UPDATE":
+			if i > 0 && (tokens[i-1].is("KEY") || tokens[i-1].is("FOR")) {
+				continue // ON DUPLICATE KEY UPDATE / SELECT ... FOR UPDATE
+			}
+			queryScope[t.depth] = true
+			j := i + 1
+			for j < len(tokens) && (tokens[j].is("LOW_PRIORITY") || tokens[j].is("IGNORE")) {
+				j++
+			}
+			next, good := parseTableList(tokens, j, add)
+			if !good {
+				return false
+			}
+			i = next - 1
+
+		case "FROM", "USING":
+			if !queryScope[t.depth] {
+				continue
+			}
+			if t.upper == "USING" && i+1 < len(tokens) && tokens[i+1].isPunct("(") {
+				continue // JOIN ... USING (col)
+			}
+			next, good := parseTableList(tokens, i+1, add)
+			if !good {
+				return false
+			}
+			i = next - 1
+
+		case "TABLE":
+			// TABLE t is SELECT * FROM t and can start any query
+			// expression (EXISTS(TABLE t), UNION TABLE t, INSERT ...
+			// TABLE t). The word is reserved, so elsewhere it also
+			// precedes table names: ANALYZE TABLE t1, t2, LOCK TABLE t.
+			next, good := parseTableList(tokens, i+1, add)
+			if !good {
+				return false
+			}
+			i = next - 1
+
+		case "JOIN", "STRAIGHT_JOIN":
+			if !queryScope[t.depth] {
+				continue
+			}
+			next, good := parseTableFactor(tokens, i+1, add)
+			if !good {
+				return false
+			}
+			i = next - 1
+		}
+	}
+	return true
+}
+
+// isStatementVerb reports whether w starts a statement that EXPLAIN or
+// DESCRIBE can wrap.
+func isStatementVerb(w string) bool {
+	switch w {
+	case "SELECT", "WITH", "INSERT", "UPDATE", "DELETE", "REPLACE", "TABLE", "FORMAT", "ANALYZE", "EXTENDED", "PARTITIONS", "FOR":
+		return true
+	}
+	return false
+}
+
+// cteNames collects the names defined by WITH clauses, lowercased.
+func cteNames(tokens []sqlToken) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].is("WITH") {
+			continue
+		}
+		j := i + 1
+		if j < len(tokens) && tokens[j].is("RECURSIVE") {
+			j++
+		}
+		for j < len(tokens) && tokens[j].isName() {
+			names[strings.ToLower(tokens[j].text)] = true
+			j++
+			if j < len(tokens) && tokens[j].isPunct("(") { // column list
+				if j = matchingParen(tokens, j); j < 0 {
+					return names
+				}
+				j++
+			}
+			if j >= len(tokens) || !tokens[j].is("AS") {
+				break
+			}
+			j++
+			if j >= len(tokens) || !tokens[j].isPunct("(") {
+				break
+			}
+			if j = matchingParen(tokens, j); j < 0 {
+				return names
+			}
+			j++
+			if j >= len(tokens) || !tokens[j].isPunct(",") {
+				break
+			}
+			j++
+		}
+	}
+	return names
+}
+
+// parseTableName reads "name" or "schema.name" at i.
+func parseTableName(tokens []sqlToken, i int) (ref TableRef, next int, ok bool) {
+	if i >= len(tokens) || !tokens[i].isName() || (tokens[i].kind == tokWord && sqlKeywords[tokens[i].upper]) {
+		return TableRef{}, i, false
+	}
+	ref.Name = tokens[i].text
+	i++
+	if i+1 < len(tokens) && tokens[i].isPunct(".") && tokens[i+1].isName() {
+		ref.Schema = ref.Name
+		ref.Name = tokens[i+1].text
+		i += 2
+	}
+	return ref, i, true
+}
+
+// parseTableList reads a comma-separated list of table factors at i.
+func parseTableList(tokens []sqlToken, i int, add func(TableRef)) (next int, ok bool) {
+	for {
+		i, ok = parseTableFactor(tokens, i, add)
+		if !ok {
+			return i, false
+		}
+		if i < len(tokens) && tokens[i].isPunct(",") {
+			i++
+			continue
+		}
+		return i, true
+	}
+}
+
+// parseTableFactor reads one table reference at i: a table name or a
+// parenthesized subquery/join, followed by optional PARTITION, alias and
+// index hints.
+func parseTableFactor(tokens []sqlToken, i int, add func(TableRef)) (next int, ok bool) {
+	if i < len(tokens) && tokens[i].is("LATERAL") {
+		i++
+	}
+	if i >= len(tokens) {
+		return i, false
+	}
+
+	if tokens[i].isPunct("(") {
+		close := matchingParen(tokens, i)
+		if close < 0 || close == i+1 {
+			return i, false
+		}
+		inner := tokens[i+1 : close]
+		subquery := inner[0].is("SELECT") || inner[0].is("WITH") || inner[0].is("TABLE") || inner[0].is("VALUES") || inner[0].isPunct("(")
+		if !scanTableRefs(inner, !subquery, add) {
+			return i, false
+		}
+		return skipAlias(tokens, close+1), true
+	}
+
+	ref, j, ok := parseTableName(tokens, i)
+	if !ok {
+		return i, false
+	}
+	// name(...) is a table function (JSON_TABLE, ...), not a table.
+	if j < len(tokens) && tokens[j].isPunct("(") {
+		return i, false
+	}
+	add(ref)
+
+	if j+1 < len(tokens) && tokens[j].is("PARTITION") && tokens[j+1].isPunct("(") {
+		if j = matchingParen(tokens, j+1); j < 0 {
+			return i, false
+		}
+		j++
+	}
+	j = skipAlias(tokens, j)
+	// Index hints: {USE|IGNORE|FORCE} {INDEX|KEY} [FOR ...] (list)
+	for j+1 < len(tokens) && (tokens[j].is("USE") || tokens[j].is("IGNORE") || tokens[j].is("FORCE")) && (tokens[j+1].is("INDEX") || tokens[j+1].is("KEY")) {
+		k := j + 2
+		for k < len(tokens) && !tokens[k].isPunct("(") {
+			k++
+		}
+		if k >= len(tokens) {
+			return i, false
+		}
+		if k = matchingParen(tokens, k); k < 0 {
+			return i, false
+		}
+		j = k + 1
+		if j < len(tokens) && tokens[j].isPunct(",") && j+1 < len(tokens) && (tokens[j+1].is("USE") || tokens[j+1].is("IGNORE") || tokens[j+1].is("FORCE")) {
+			j++
+		}
+	}
+	return j, true
+}
+
+// skipAlias skips an optional "[AS] alias" at i.
+func skipAlias(tokens []sqlToken, i int) int {
+	if i < len(tokens) && tokens[i].is("AS") {
+		i++
+	}
+	if i < len(tokens) && (tokens[i].kind == tokIdent || (tokens[i].kind == tokWord && !sqlKeywords[tokens[i].upper])) {
+		i++
+	}
+	return i
+}
+
+// showTables handles SHOW statements. Forms naming one table report it;
+// forms that list or inspect many tables are not determinable; the rest
+// (VARIABLES, STATUS, WARNINGS, ...) reference no tables.
+func showTables(tokens []sqlToken) ([]TableRef, bool) {
+	i := 1
+	for i < len(tokens) && (tokens[i].is("FULL") || tokens[i].is("EXTENDED")) {
+		i++
+	}
+	if i >= len(tokens) {
+		return nil, true
+	}
+
+	switch tokens[i].upper {
+	case "COLUMNS", "FIELDS", "INDEX", "INDEXES", "KEYS":
+		i++
+		if i >= len(tokens) || !(tokens[i].is("FROM") || tokens[i].is("IN")) {
+			return nil, false
+		}
+		ref, j, ok := parseTableName(tokens, i+1)
+		if !ok {
+			return nil, false
+		}
+		if j+1 < len(tokens) && (tokens[j].is("FROM") || tokens[j].is("IN")) && tokens[j+1].isName() {
+			ref.Schema = tokens[j+1].text
+		}
+		return []TableRef{ref}, true
+	case "CREATE":
+		if i+1 < len(tokens) && (tokens[i+1].is("TABLE") || tokens[i+1].is("VIEW")) {
+			ref, _, ok := parseTableName(tokens, i+2)
+			if !ok {
+				return nil, false
+			}
+			return []TableRef{ref}, true
+		}
+		return nil, false
+	case "TABLES", "TABLE", "OPEN", "TRIGGERS", "EVENTS", "PROCEDURE", "FUNCTION":
+		return nil, false
+	}
+	return nil, true
+}
+
+// ddlTables handles CREATE/ALTER/DROP/TRUNCATE/RENAME TABLE and CREATE/DROP
+// INDEX ... ON. Any other DDL object (views, procedures, triggers, ...) is
+// not determinable.
+func ddlTables(tokens []sqlToken) ([]TableRef, bool) {
+	i := 1
+	for i < len(tokens) && (tokens[i].is("TEMPORARY") || tokens[i].is("OR") || tokens[i].is("REPLACE") || tokens[i].is("ONLINE") || tokens[i].is("UNIQUE") || tokens[i].is("FULLTEXT") || tokens[i].is("SPATIAL")) {
+		i++
+	}
+	if tokens[0].is("TRUNCATE") && i < len(tokens) && !tokens[i].is("TABLE") {
+		ref, _, ok := parseTableName(tokens, i)
+		if !ok {
+			return nil, false
+		}
+		return []TableRef{ref}, true
+	}
+	if i >= len(tokens) {
+		return nil, false
+	}
+
+	switch {
+	case tokens[i].is("TABLE"):
+		i++
+		if i+1 < len(tokens) && tokens[i].is("IF") {
+			i += 2 // IF EXISTS
+			if i < len(tokens) && tokens[i-1].is("NOT") {
+				i++ // IF NOT EXISTS
+			}
+		}
+		var tables []TableRef
+		for {
+			ref, next, ok := parseTableName(tokens, i)
+			if !ok {
+				return nil, false
+			}
+			tables = append(tables, ref)
+			i = next
+			// RENAME TABLE a TO b, c TO d
+			if i < len(tokens) && tokens[i].is("TO") {
+				ref, next, ok = parseTableName(tokens, i+1)
+				if !ok {
+					return nil, false
+				}
+				tables = append(tables, ref)
+				i = next
+			}
+			if i < len(tokens) && tokens[i].isPunct(",") && tokens[0].upper != "CREATE" && tokens[0].upper != "ALTER" {
+				i++
+				continue
+			}
+			break
+		}
+		// CREATE TABLE ... LIKE other / AS SELECT ... / AS TABLE other
+		// also reads tables.
+		for j := i; j < len(tokens); j++ {
+			if tokens[j].is("LIKE") {
+				if ref, _, ok := parseTableName(tokens, j+1); ok {
+					tables = append(tables, ref)
+				}
+			}
+			if tokens[j].is("SELECT") || tokens[j].is("TABLE") || tokens[j].is("WITH") {
+				more, ok := ReferencedTables(sqlFrom(tokens, j))
+				if !ok {
+					return nil, false
+				}
+				tables = append(tables, more...)
+				break
+			}
+		}
+		return tables, true
+	case tokens[i].is("INDEX"):
+		for j := i; j < len(tokens); j++ {
+			if tokens[j].is("ON") {
+				ref, _, ok := parseTableName(tokens, j+1)
+				if !ok {
+					return nil, false
+				}
+				return []TableRef{ref}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// sqlFrom re-renders tokens[i:] as SQL text.
+func sqlFrom(tokens []sqlToken, i int) string {
+	var sb strings.Builder
+	for _, t := range tokens[i:] {
+		if t.kind == tokIdent {
+			sb.WriteString("`" + strings.ReplaceAll(t.text, "`", "``") + "`")
+		} else {
+			sb.WriteString(t.text)
+		}
+		sb.WriteByte(' ')
+	}
+	return sb.String()
+}
@@ -0,0 +1,392 @@
+package internal
+
+import (
+	"container/heap"
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column statistics summary.
+//
+// With summarize=true the query tool does not keep rows. Each row is fed
+// to one accumulator per column and dropped, so the whole result (up to
+// SUMMARY_MAX_ROWS rows, default 1,000,000) is described in constant
+// memory:
+//
+//   - every column: non-NULL count, NULL count and a distinct estimate
+//     (a k-minimum-values sketch, exact below summaryDistinctK values)
+//   - numbers: min, max, mean and standard deviation
+//   - dates and times: min and max
+//   - strings: the most frequent values, plus min/max length
+//
+// Top values are counted exactly for the first summaryTopKeys distinct
+// values; later new values are not tracked and the list is marked
+// approximate.
+
+// DefaultSummaryMaxRows is the SUMMARY_MAX_ROWS default.
+const DefaultSummaryMaxRows = 1000000
+
+const (
+	summaryDistinctK = 1024
+	summaryTopKeys   = 10000
+	summaryTopN      = 5
+)
+
+// Column kinds used by the summary.
+const (
+	KindNumber = "number"
+	KindDate   = "date"
+	KindString = "string"
+	KindBinary = "binary"
+)
+
+// ValueCount is one entry of a column's most frequent values.
+type ValueCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ColumnSummary holds the statistics for one column.
+type ColumnSummary struct {
+	Name          string       `json:"name"`
+	Type          string       `json:"type,omitempty"`
+	Kind          string       `json:"kind"`
+	Count         int64        `json:"count"`
+	Nulls         int64        `json:"nulls"`
+	Distinct      int64        `json:"distinct"`
+	DistinctExact bool         `json:"distinct_exact"`
+	Min           string       `json:"min,omitempty"`
+	Max           string       `json:"max,omitempty"`
+	Mean          float64      `json:"mean,omitempty"`
+	Stddev        float64      `json:"stddev,omitempty"`
+	MinLength     int          `json:"min_length,omitempty"`
+	MaxLength     int          `json:"max_length,omitempty"`
+	Top           []ValueCount `json:"top,omitempty"`
+	// TopApproximate is set when more distinct values were seen than
+	// could be counted, so Top may miss values that became frequent late.
+	TopApproximate bool `json:"top_approximate,omitempty"`
+	// Masked is the MASKING_RULES strategy of a masked column, whose value
+	// statistics are left out.
+	Masked string `json:"masked,omitempty"`
+}
+
+// ResultSummary describes a whole result set column by column.
+type ResultSummary struct {
+	Columns  []*ColumnSummary `json:"columns"`
+	RowCount int64            `json:"row_count"`
+	// Truncated is set when SUMMARY_MAX_ROWS stopped the read early.
+	Truncated   bool   `json:"truncated,omitempty"`
+	CostWarning string `json:"cost_warning,omitempty"`
+}
+
+// Summarize runs a read query like QueryWithOptions but returns column
+// statistics over the full result instead of the rows. AutoLimit and
+// Cache in opts are ignored.
+func (c *Client) Summarize(query string, opts QueryOptions) (*ResultSummary, error) {
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	if err := c.ValidateQuery(query); err != nil {
+		return nil, fmt.Errorf("security validation failed: %w", err)
+	}
+
+	var summary *ResultSummary
+	costWarning, err := c.runRead(query, opts, func(rows *sql.Rows, cancel context.CancelFunc) (err error) {
+		defer rows.Close()
+		summary, err = SummarizeRows(rows, c.summaryMaxRows)
+		if summary != nil && summary.Truncated && cancel != nil {
+			cancel()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.maskSummary(query, summary)
+	summary.CostWarning = costWarning
+	return summary, nil
+}
+
+// SummarizeRows streams rows through the column accumulators, stopping
+// after maxRows rows (no limit when maxRows <= 0). The caller owns rows.
+func SummarizeRows(rows *sql.Rows, maxRows int64) (*ResultSummary, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(columns)
+	values := make([]scanValue, n)
+	ptrs := make([]interface{}, n)
+	accs := make([]*columnAccumulator, n)
+	for i := range values {
+		ptrs[i] = &values[i]
+		accs[i] = newColumnAccumulator(columns[i], "")
+	}
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, t := range types {
+			values[i].binary = IsBinaryType(t.DatabaseTypeName())
+			accs[i] = newColumnAccumulator(columns[i], t.DatabaseTypeName())
+		}
+	}
+
+	summary := &ResultSummary{}
+	for rows.Next() {
+		if maxRows > 0 && summary.RowCount >= maxRows {
+			summary.Truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i := range values {
+			accs[i].add(values[i].v)
+		}
+		summary.RowCount++
+	}
+
+	for _, a := range accs {
+		summary.Columns = append(summary.Columns, a.summary())
+	}
+	if summary.Truncated {
+		return summary, nil
+	}
+	return summary, rows.Err()
+}
+
+// summaryKind maps a driver column type to a summary kind; "" means the
+// kind is inferred from the first non-NULL value.
+func summaryKind(dbType string) string {
+	t := strings.ToUpper(dbType)
+	switch {
+	case t == "":
+		return ""
+	case IsBinaryType(t):
+		return KindBinary
+	case strings.Contains(t, "INT"), t == "DECIMAL", t == "FLOAT", t == "DOUBLE", t == "YEAR":
+		return KindNumber
+	case t == "DATE", t == "DATETIME", t == "TIMESTAMP":
+		return KindDate
+	}
+	return KindString
+}
+
+// columnAccumulator collects the statistics of one column.
+type columnAccumulator struct {
+	s        ColumnSummary
+	distinct kmvSketch
+
+	// numbers (Welford's online mean and variance)
+	mean, m2, min, max float64
+	numbers            int64
+
+	// dates, as comparable strings
+	minDate, maxDate string
+
+	// strings
+	top map[string]int64
+}
+
+func newColumnAccumulator(name, dbType string) *columnAccumulator {
+	return &columnAccumulator{
+		s:        ColumnSummary{Name: name, Type: dbType, Kind: summaryKind(dbType)},
+		distinct: kmvSketch{k: summaryDistinctK, seen: make(map[uint64]bool)},
+		top:      make(map[string]int64),
+	}
+}
+
+func (a *columnAccumulator) add(v interface{}) {
+	if v == nil {
+		a.s.Nulls++
+		return
+	}
+	a.s.Count++
+	if a.s.Kind == "" {
+		a.s.Kind = inferKind(v)
+	}
+
+	text := summaryText(v)
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	a.distinct.add(h.Sum64())
+
+	switch a.s.Kind {
+	case KindNumber:
+		if f, ok := toFloat(v); ok {
+			a.addNumber(f)
+		}
+	case KindDate:
+		if a.minDate == "" || text < a.minDate {
+			a.minDate = text
+		}
+		if text > a.maxDate {
+			a.maxDate = text
+		}
+	case KindString:
+		a.addString(text)
+	}
+}
+
+func (a *columnAccumulator) addNumber(f float64) {
+	a.numbers++
+	if a.numbers == 1 || f < a.min {
+		a.min = f
+	}
+	if a.numbers == 1 || f > a.max {
+		a.max = f
+	}
+	delta := f - a.mean
+	a.mean += delta / float64(a.numbers)
+	a.m2 += delta * (f - a.mean)
+}
+
+func (a *columnAccumulator) addString(text string) {
+	length := len([]rune(text))
+	if a.s.Count == 1 || length < a.s.MinLength {
+		a.s.MinLength = length
+	}
+	if length > a.s.MaxLength {
+		a.s.MaxLength = length
+	}
+	if _, ok := a.top[text]; ok || len(a.top) < summaryTopKeys {
+		a.top[text]++
+	} else {
+		a.s.TopApproximate = true
+	}
+}
+
+func (a *columnAccumulator) summary() *ColumnSummary {
+	s := a.s
+	s.Distinct, s.DistinctExact = a.distinct.estimate()
+	switch s.Kind {
+	case KindNumber:
+		if a.numbers > 0 {
+			s.Min = strconv.FormatFloat(a.min, 'f', -1, 64)
+			s.Max = strconv.FormatFloat(a.max, 'f', -1, 64)
+			s.Mean = a.mean
+			if a.numbers > 1 {
+				s.Stddev = math.Sqrt(a.m2 / float64(a.numbers-1))
+			}
+		}
+	case KindDate:
+		s.Min, s.Max = a.minDate, a.maxDate
+	case KindString:
+		for v, n := range a.top {
+			s.Top = append(s.Top, ValueCount{Value: v, Count: n})
+		}
+		sort.Slice(s.Top, func(i, j int) bool {
+			if s.Top[i].Count != s.Top[j].Count {
+				return s.Top[i].Count > s.Top[j].Count
+			}
+			return s.Top[i].Value < s.Top[j].Value
+		})
+		if len(s.Top) > summaryTopN {
+			s.Top = s.Top[:summaryTopN]
+		}
+	}
+	return &s
+}
+
+// inferKind picks a kind from a value when the driver reported no type.
+func inferKind(v interface{}) string {
+	switch v.(type) {
+	case int64, int32, int, uint64, uint32, float64, float32:
+		return KindNumber
+	case time.Time:
+		return KindDate
+	case []byte:
+		return KindBinary
+	}
+	return KindString
+}
+
+// summaryText renders a value for hashing, date comparison and top-k.
+func summaryText(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format("2006-01-02 15:04:05.999999")
+	}
+	return fmt.Sprint(v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case int32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case uint32:
+		return float64(x), true
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// kmvSketch estimates the number of distinct hashes by keeping the k
+// smallest: if the k-th smallest of n uniform hashes is h, n is about
+// (k-1) * 2^64 / h. Below k distinct values the count is exact.
+type kmvSketch struct {
+	k    int
+	heap hashHeap // max-heap of the k smallest hashes
+	seen map[uint64]bool
+}
+
+func (s *kmvSketch) add(h uint64) {
+	if s.seen[h] {
+		return
+	}
+	if len(s.heap) < s.k {
+		s.seen[h] = true
+		heap.Push(&s.heap, h)
+		return
+	}
+	if h >= s.heap[0] {
+		return
+	}
+	delete(s.seen, s.heap[0])
+	s.seen[h] = true
+	s.heap[0] = h
+	heap.Fix(&s.heap, 0)
+}
+
+func (s *kmvSketch) estimate() (n int64, exact bool) {
+	if len(s.heap) < s.k {
+		return int64(len(s.heap)), true
+	}
+	return int64(float64(s.k-1) * math.Exp2(64) / float64(s.heap[0])), false
+}
+
+type hashHeap []uint64
+
+func (h hashHeap) Len() int            { return len(h) }
+func (h hashHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
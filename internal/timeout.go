@@ -3,6 +3,10 @@ package internal
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -40,18 +44,50 @@ type TimeoutConfig struct {
 	Connection time.Duration
 }
 
-// NewTimeoutConfig creates a new timeout configuration with sensible defaults
+// NewTimeoutConfig creates a new timeout configuration with sensible defaults.
+// Each profile can be overridden through its environment variable
+// (TIMEOUT_DEFAULT, TIMEOUT_QUERY, TIMEOUT_LONG_QUERY, TIMEOUT_WRITE,
+// TIMEOUT_ADMIN, TIMEOUT_CONNECTION), given either as a Go duration
+// ("90s", "2m") or as a whole number of seconds.
 func NewTimeoutConfig() *TimeoutConfig {
 	return &TimeoutConfig{
-		Default:    30 * time.Second,
-		Query:      30 * time.Second,
-		LongQuery:  5 * time.Minute,
-		Write:      60 * time.Second,
-		Admin:      15 * time.Second,
-		Connection: 5 * time.Second,
+		Default:    getEnvTimeout("TIMEOUT_DEFAULT", 30*time.Second),
+		Query:      getEnvTimeout("TIMEOUT_QUERY", 30*time.Second),
+		LongQuery:  getEnvTimeout("TIMEOUT_LONG_QUERY", 5*time.Minute),
+		Write:      getEnvTimeout("TIMEOUT_WRITE", 60*time.Second),
+		Admin:      getEnvTimeout("TIMEOUT_ADMIN", 15*time.Second),
+		Connection: getEnvTimeout("TIMEOUT_CONNECTION", 5*time.Second),
 	}
 }
 
+// getEnvTimeout reads a timeout from the environment, falling back to
+// defaultVal (with a warning) when the value is unparseable or fails
+// ValidateTimeoutDuration.
+func getEnvTimeout(key string, defaultVal time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultVal
+	}
+
+	d, err := parseTimeout(raw)
+	if err == nil {
+		err = ValidateTimeoutDuration(d)
+	}
+	if err != nil {
+		log.Printf("WARNING: ignoring %s=%q (%v); using %s", key, raw, err, defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
+// parseTimeout accepts a Go duration string or a bare number of seconds.
+func parseTimeout(raw string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(raw)
+}
+
 // GetTimeout returns the timeout for the given profile
 func (tc *TimeoutConfig) GetTimeout(profile TimeoutProfile) time.Duration {
 	if tc == nil {
@@ -80,6 +116,38 @@ func (tc *TimeoutConfig) TimeoutContext(ctx context.Context, profile TimeoutProf
 	return context.WithTimeout(ctx, timeout)
 }
 
+// RequestedTimeout resolves a caller-requested timeout for a profile.
+// A non-positive request means "use the profile's timeout"; any request is
+// capped at the LongQuery timeout, which is the ceiling for a single call.
+func (tc *TimeoutConfig) RequestedTimeout(profile TimeoutProfile, requested time.Duration) time.Duration {
+	if requested <= 0 {
+		return tc.GetTimeout(profile)
+	}
+	if ceiling := tc.GetTimeout(ProfileLongQuery); requested > ceiling {
+		return ceiling
+	}
+	return requested
+}
+
+// TimeoutContextWithin is TimeoutContext with a caller-requested timeout,
+// resolved through RequestedTimeout.
+func (tc *TimeoutConfig) TimeoutContextWithin(ctx context.Context, profile TimeoutProfile, requested time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, tc.RequestedTimeout(profile, requested))
+}
+
+// Longest returns the largest configured timeout. The driver-level socket
+// read/write timeouts are set to this so they never cut a statement off
+// before its context does.
+func (tc *TimeoutConfig) Longest() time.Duration {
+	longest := tc.GetTimeout(ProfileDefault)
+	for _, p := range []TimeoutProfile{ProfileQuery, ProfileLongQuery, ProfileWrite, ProfileAdmin, ProfileConnection} {
+		if d := tc.GetTimeout(p); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
 // TimeoutDetails tracks timeout-related information for an operation
 type TimeoutDetails struct {
 	Profile        TimeoutProfile `json:"profile"`
@@ -0,0 +1,341 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Interactive transactions.
+//
+// BeginTransaction pins a pool connection, starts a transaction on it and
+// returns an opaque handle. query and execute calls that pass the handle run
+// on that connection, so the agent can read its own uncommitted writes
+// before deciding to Commit or Rollback. The MAX_SAFE_ROWS gate is applied
+// at commit time to the rows affected by all writes in the transaction.
+//
+// A transaction that is not used for TX_IDLE_TIMEOUT (default 60s) is rolled
+// back and its connection returned to the pool. At most
+// MAX_OPEN_TRANSACTIONS (default 3) can be open at once, since each holds a
+// connection and its row locks. A statement that breaks the transaction
+// (a kill on timeout, a lost connection, a deadlock, which InnoDB answers
+// by rolling the whole transaction back) closes the handle as well.
+
+// Transaction defaults.
+const (
+	DefaultTxIdleTimeout       = 60 * time.Second
+	DefaultMaxOpenTransactions = 3
+)
+
+// erLockDeadlock is ER_LOCK_DEADLOCK; InnoDB rolls back the transaction.
+const erLockDeadlock = 1213
+
+// openTx is one interactive transaction.
+type openTx struct {
+	id       string
+	conn     *pinnedConn
+	tx       *sql.Tx
+	started  time.Time
+	lastUsed time.Time
+	timer    *time.Timer
+	inUse    bool
+	affected int64
+	writes   []string
+}
+
+// txManager tracks the open interactive transactions.
+type txManager struct {
+	mu          sync.Mutex
+	open        map[string]*openTx
+	idleTimeout time.Duration
+	maxOpen     int
+}
+
+func newTxManager() *txManager {
+	m := &txManager{
+		open:        make(map[string]*openTx),
+		idleTimeout: getEnvTimeout("TX_IDLE_TIMEOUT", DefaultTxIdleTimeout),
+		maxOpen:     getEnvIntOrDefault("MAX_OPEN_TRANSACTIONS", DefaultMaxOpenTransactions),
+	}
+	if m.idleTimeout <= 0 {
+		log.Printf("WARNING: invalid TX_IDLE_TIMEOUT, using %s", DefaultTxIdleTimeout)
+		m.idleTimeout = DefaultTxIdleTimeout
+	}
+	return m
+}
+
+// TransactionInfo describes an open transaction.
+type TransactionInfo struct {
+	ID          string        `json:"id"`
+	IdleTimeout time.Duration `json:"idle_timeout"`
+	Affected    int64         `json:"affected"`
+	Statements  int           `json:"statements"`
+}
+
+// BeginTransaction starts an interactive transaction and returns its handle.
+func (c *Client) BeginTransaction() (*TransactionInfo, error) {
+	if err := c.refuseInApprovalMode("begin_transaction"); err != nil {
+		return nil, err
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	m := c.txns
+	m.mu.Lock()
+	if len(m.open) >= m.maxOpen {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("too many open transactions (%d, MAX_OPEN_TRANSACTIONS); commit or roll back one first", m.maxOpen)
+	}
+	t := &openTx{id: newTxID(), inUse: true}
+	m.open[t.id] = t
+	m.mu.Unlock()
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileConnection)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err == nil {
+		// The transaction outlives this call, so it must not inherit the
+		// call's context.
+		t.tx, err = conn.BeginTx(context.Background(), nil)
+		if err != nil {
+			conn.Close()
+			err = fmt.Errorf("failed to begin transaction: %w", err)
+		}
+	}
+	if err != nil {
+		m.mu.Lock()
+		delete(m.open, t.id)
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	t.conn = conn
+	t.started = time.Now()
+	m.release(t)
+	log.Printf("Transaction %s started (connection %d)", t.id, conn.id)
+	return &TransactionInfo{ID: t.id, IdleTimeout: m.idleTimeout}, nil
+}
+
+// Commit applies the MAX_SAFE_ROWS gate to the transaction's writes and
+// commits it. Without a valid confirmKey past the limit the transaction
+// stays open, so the caller can confirm or roll back.
+func (c *Client) Commit(id, confirmKey string) (*TransactionInfo, error) {
+	t, err := c.txns.acquire(id)
+	if err != nil {
+		return nil, err
+	}
+
+	binding := bindStatement(id+"\x02"+strings.Join(t.writes, "\x02"), nil)
+	if err := c.confirmLargeWrite(binding, t.affected, confirmKey,
+		fmt.Sprintf("transaction %s affects %d rows", id, t.affected), "The transaction is still open; confirm the commit or roll back"); err != nil {
+		c.txns.release(t)
+		return nil, err
+	}
+
+	err = t.tx.Commit()
+	c.txns.close(t)
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction %s: %w", id, err)
+	}
+	for _, q := range t.writes {
+		c.invalidateCache(q)
+	}
+	return t.info(), nil
+}
+
+// Rollback discards the transaction.
+func (c *Client) Rollback(id string) (*TransactionInfo, error) {
+	t, err := c.txns.acquire(id)
+	if err != nil {
+		return nil, err
+	}
+	err = t.tx.Rollback()
+	c.txns.close(t)
+	if err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return nil, fmt.Errorf("failed to roll back transaction %s: %w", id, err)
+	}
+	return t.info(), nil
+}
+
+// ExecuteInTransaction runs a write inside an open transaction without
+// committing it. The affected rows count towards the commit-time gate;
+// writes to non-transactional tables are gated before they run, with
+// confirmKey.
+func (c *Client) ExecuteInTransaction(id, query, confirmKey string, opts ExecOptions, args ...interface{}) (*QueryResult, error) {
+	if err := c.ValidateQuery(query); err != nil {
+		return nil, fmt.Errorf("security validation failed: %w", err)
+	}
+	binding := id + "\x00" + bindStatement(query, args)
+	confirmed, err := c.checkWherePolicy([]string{query}, opts, binding, confirmKey)
+	if err != nil {
+		return nil, err
+	}
+	if containsVerb(firstVerb(StripComments(query)), ddlVerbs) {
+		return nil, fmt.Errorf("DDL is not allowed inside a transaction: MySQL commits it implicitly")
+	}
+
+	t, err := c.txns.acquire(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	// The commit-time gate cannot hold back writes to non-transactional
+	// tables, so they are gated here, unless a confirmed allow_full_table
+	// override already covers the write. The count locks rows, so it runs
+	// under a kill watch.
+	nonTx, err := c.nonTxTargets(query)
+	if err == nil && (!confirmed || c.nonTxPolicy == NonTxBlock) {
+		stmts := []BatchStatement{{SQL: query, Args: args}}
+		watch := c.watchForKill(ctx, t.conn)
+		err = watch.stop(c.guardNonTx(nonTx, binding, confirmKey, func() (int64, bool, error) {
+			return countStatements(ctx, t.tx, stmts)
+		}))
+	}
+	if err != nil {
+		c.txns.finish(t, err)
+		return nil, fmt.Errorf("%w%s", err, brokenNote(t, err))
+	}
+
+	_, info, err := c.execWrite(ctx, t.conn, t.tx, query, args)
+	if err != nil {
+		c.txns.finish(t, err)
+		return nil, fmt.Errorf("execution failed: %w%s", err, brokenNote(t, err))
+	}
+
+	affected := info.Affected
+	t.affected += affected
+	t.writes = append(t.writes, query)
+	pending := t.affected
+	c.txns.release(t)
+
+	return &QueryResult{
+		RowCount: int(affected),
+		Message: fmt.Sprintf("Rows affected: %d (transaction %s, not committed; %d rows pending in total)",
+			affected, id, pending),
+		Write: info,
+	}, nil
+}
+
+// readInTransaction runs a read on an open transaction's connection.
+func (c *Client) readInTransaction(ctx context.Context, query string, opts QueryOptions, read func(rows *sql.Rows, cancel context.CancelFunc) error) (string, error) {
+	t, err := c.txns.acquire(opts.Tx)
+	if err != nil {
+		return "", err
+	}
+	// Cancelling the context would drop the connection and with it the
+	// transaction, so reads stop by draining instead (nil cancel).
+	costWarning, err := c.readIn(ctx, t.conn, t.tx, query, opts, func(rows *sql.Rows, _ context.CancelFunc) error {
+		return read(rows, nil)
+	})
+	c.txns.finish(t, err)
+	if err != nil {
+		return "", fmt.Errorf("%w%s", err, brokenNote(t, err))
+	}
+	return costWarning, nil
+}
+
+func (t *openTx) info() *TransactionInfo {
+	return &TransactionInfo{ID: t.id, Affected: t.affected, Statements: len(t.writes)}
+}
+
+// acquire marks a transaction in use so the idle timer leaves it alone.
+func (m *txManager) acquire(id string) (*openTx, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.open[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown transaction %q: it was committed, rolled back, or expired after %s idle (TX_IDLE_TIMEOUT)", id, m.idleTimeout)
+	}
+	if t.inUse {
+		return nil, fmt.Errorf("transaction %s is busy with another statement", id)
+	}
+	t.inUse = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	return t, nil
+}
+
+// release returns a transaction to idle and re-arms its idle timer.
+func (m *txManager) release(t *openTx) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.inUse = false
+	t.lastUsed = time.Now()
+	t.timer = time.AfterFunc(m.idleTimeout, func() { m.expire(t) })
+}
+
+// finish releases t, or closes it when err left the transaction unusable.
+func (m *txManager) finish(t *openTx, err error) {
+	if txBroken(err) {
+		t.tx.Rollback()
+		m.close(t)
+		return
+	}
+	m.release(t)
+}
+
+// close forgets a transaction and returns its connection to the pool.
+func (m *txManager) close(t *openTx) {
+	m.mu.Lock()
+	delete(m.open, t.id)
+	m.mu.Unlock()
+	t.conn.Close()
+}
+
+// expire rolls back a transaction left idle for the idle timeout.
+func (m *txManager) expire(t *openTx) {
+	m.mu.Lock()
+	if m.open[t.id] != t || t.inUse || time.Since(t.lastUsed) < m.idleTimeout {
+		m.mu.Unlock()
+		return
+	}
+	t.inUse = true
+	m.mu.Unlock()
+
+	t.tx.Rollback()
+	m.close(t)
+	log.Printf("Transaction %s rolled back after %s idle (%d statements, %d rows discarded)", t.id, m.idleTimeout, len(t.writes), t.affected)
+}
+
+// txBroken reports whether err means the transaction can no longer be used.
+func txBroken(err error) bool {
+	if err == nil {
+		return false
+	}
+	var killed *QueryKilledError
+	var me *mysql.MySQLError
+	return errors.As(err, &killed) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrTxDone) || errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &me) && me.Number == erLockDeadlock
+}
+
+// brokenNote tells the caller the transaction is gone after err.
+func brokenNote(t *openTx, err error) string {
+	if !txBroken(err) {
+		return ""
+	}
+	return fmt.Sprintf(". Transaction %s was rolled back and closed", t.id)
+}
+
+func newTxID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "tx_" + hex.EncodeToString(b)
+}
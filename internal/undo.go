@@ -0,0 +1,717 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Undo journal.
+//
+// With UNDO_JOURNAL_DIR set, every write committed through Execute records
+// what it changed in a JSON file named after a new operation ID, written
+// before the commit:
+//
+//   - UPDATE: the before and after image of each changed row
+//   - DELETE: the before image of each deleted row
+//   - INSERT: the inserted rows, read back by their AUTO_INCREMENT key;
+//     only when the keys are first, first+inc, ... (inc being
+//     auto_increment_increment), so no other session's rows are taken
+//
+// Only single-table statements on tables with a primary key, touching at
+// most UNDO_MAX_ROWS rows (default 1000), are journaled; for anything else
+// the result says why not. UPDATE/DELETE rows are read with SELECT ... FOR
+// UPDATE inside the write's transaction, so the image matches what the
+// statement saw. The newest UNDO_MAX_OPERATIONS entries (default 100) are
+// kept.
+//
+// RevertOperation locks the journaled rows, checks that they still match
+// what the operation left behind, and runs the inverse statements (UPDATE
+// back, re-INSERT, DELETE) in one transaction. Changes made by triggers or
+// ON DELETE CASCADE are not in the journal and are not reverted.
+
+// Undo journal defaults.
+const (
+	DefaultUndoMaxRows       = 1000
+	DefaultUndoMaxOperations = 100
+)
+
+// UndoJournal stores undo entries as files in a directory.
+type UndoJournal struct {
+	dir     string
+	maxRows int
+	maxOps  int
+}
+
+// NewUndoJournal reads UNDO_JOURNAL_DIR, UNDO_MAX_ROWS and
+// UNDO_MAX_OPERATIONS. It returns nil when UNDO_JOURNAL_DIR is unset.
+func NewUndoJournal() *UndoJournal {
+	dir := os.Getenv("UNDO_JOURNAL_DIR")
+	if dir == "" {
+		return nil
+	}
+	j := &UndoJournal{
+		dir:     dir,
+		maxRows: getEnvIntOrDefault("UNDO_MAX_ROWS", DefaultUndoMaxRows),
+		maxOps:  getEnvIntOrDefault("UNDO_MAX_OPERATIONS", DefaultUndoMaxOperations),
+	}
+	if j.maxRows <= 0 {
+		log.Printf("WARNING: invalid UNDO_MAX_ROWS, using %d", DefaultUndoMaxRows)
+		j.maxRows = DefaultUndoMaxRows
+	}
+	if j.maxOps <= 0 {
+		log.Printf("WARNING: invalid UNDO_MAX_OPERATIONS, using %d", DefaultUndoMaxOperations)
+		j.maxOps = DefaultUndoMaxOperations
+	}
+	return j
+}
+
+// UndoEntry is one journaled write.
+type UndoEntry struct {
+	ID         string     `json:"id"`
+	Time       time.Time  `json:"time"`
+	SQL        string     `json:"sql"`
+	Verb       string     `json:"verb"`
+	Schema     string     `json:"schema"`
+	Table      string     `json:"table"`
+	Columns    []string   `json:"columns"`
+	Key        []string   `json:"key"`
+	Before     []UndoRow  `json:"before,omitempty"`
+	After      []UndoRow  `json:"after,omitempty"`
+	RevertedAt *time.Time `json:"reverted_at,omitempty"`
+}
+
+// UndoRow is a row image. Bytes and times are stored tagged
+// ({"base64": ...}, {"time": ...}) so they come back with their type;
+// integers come back as int64.
+type UndoRow []interface{}
+
+func (r UndoRow) MarshalJSON() ([]byte, error) {
+	cells := make([]interface{}, len(r))
+	for i, v := range r {
+		switch x := v.(type) {
+		case []byte:
+			cells[i] = map[string]string{"base64": base64.StdEncoding.EncodeToString(x)}
+		case time.Time:
+			cells[i] = map[string]string{"time": x.Format(time.RFC3339Nano)}
+		default:
+			cells[i] = v
+		}
+	}
+	return json.Marshal(cells)
+}
+
+func (r *UndoRow) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var cells []interface{}
+	if err := dec.Decode(&cells); err != nil {
+		return err
+	}
+	row := make(UndoRow, len(cells))
+	for i, cell := range cells {
+		switch x := cell.(type) {
+		case json.Number:
+			if n, err := x.Int64(); err == nil {
+				row[i] = n
+			} else if f, err := x.Float64(); err == nil {
+				row[i] = f
+			} else {
+				return err
+			}
+		case map[string]interface{}:
+			if s, ok := x["base64"].(string); ok {
+				b, err := base64.StdEncoding.DecodeString(s)
+				if err != nil {
+					return err
+				}
+				row[i] = b
+			} else if s, ok := x["time"].(string); ok {
+				t, err := time.Parse(time.RFC3339Nano, s)
+				if err != nil {
+					return err
+				}
+				row[i] = t
+			} else {
+				return fmt.Errorf("unknown value in undo row: %v", x)
+			}
+		default:
+			row[i] = x
+		}
+	}
+	*r = row
+	return nil
+}
+
+// undoCapture follows one write through Execute. A nil capture means the
+// journal is off; a capture with a note and no entry means this write is
+// not journaled, and why.
+type undoCapture struct {
+	journal *UndoJournal
+	entry   *UndoEntry
+	before  *QueryResult
+	idx     []int
+	note    string
+}
+
+// beginUndo prepares the journal entry for query before it runs on tx,
+// reading the rows an UPDATE or DELETE is about to change.
+func (c *Client) beginUndo(ctx context.Context, tx *sql.Tx, query string, args []interface{}) *undoCapture {
+	if c.undo == nil {
+		return nil
+	}
+	u := &undoCapture{journal: c.undo}
+
+	shape, ok := parseDMLShape(query)
+	if !ok {
+		return u.skip("only single-table INSERT, UPDATE and DELETE can be journaled")
+	}
+	table := shape.table
+	if table.Schema == "" {
+		if err := tx.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&table.Schema); err != nil {
+			return u.skip("could not determine the current database: %v", err)
+		}
+	}
+	key, autoIncrement, err := primaryKey(ctx, tx, table)
+	if err != nil {
+		return u.skip("%v", err)
+	}
+	if len(key) == 0 {
+		return u.skip("%s has no primary key", table)
+	}
+
+	e := &UndoEntry{SQL: query, Verb: shape.verb, Schema: table.Schema, Table: table.Name, Key: key}
+	switch shape.verb {
+	case "INSERT":
+		if !autoIncrement || len(key) != 1 {
+			return u.skip("inserts are only journaled for tables with an AUTO_INCREMENT primary key")
+		}
+		if hasOnDuplicateKey(query) {
+			return u.skip("INSERT ... ON DUPLICATE KEY UPDATE cannot be journaled")
+		}
+	default:
+		sel := "SELECT * FROM " + strings.TrimSpace(shape.factor+" "+shape.tail) + " FOR UPDATE"
+		rows, err := tx.QueryContext(ctx, sel, args[min(shape.skip, len(args)):]...)
+		if err != nil {
+			return u.skip("could not read the rows before the write: %v", err)
+		}
+		before, err := ReadRows(rows, ResultLimits{MaxRows: u.journal.maxRows})
+		rows.Close()
+		if err != nil {
+			return u.skip("could not read the rows before the write: %v", err)
+		}
+		if before.Truncated {
+			return u.skip("more than UNDO_MAX_ROWS (%d) rows", u.journal.maxRows)
+		}
+		if u.idx = columnIndexes(before.Columns, key); u.idx == nil {
+			return u.skip("the primary key of %s is not in the result", table)
+		}
+		u.before = before
+		e.Columns = before.Columns
+	}
+	u.entry = e
+	return u
+}
+
+func (u *undoCapture) skip(format string, args ...interface{}) *undoCapture {
+	u.entry = nil
+	u.note = fmt.Sprintf(format, args...)
+	return u
+}
+
+// finish records the after images once the write has run on tx.
+func (u *undoCapture) finish(ctx context.Context, tx *sql.Tx, result sql.Result, affected int64) {
+	if u == nil || u.entry == nil {
+		return
+	}
+	e := u.entry
+	table := TableRef{Schema: e.Schema, Name: e.Table}
+	if affected == 0 {
+		u.skip("no rows changed")
+		return
+	}
+
+	switch e.Verb {
+	case "DELETE":
+		if affected != int64(len(u.before.Rows)) {
+			u.skip("deleted %d rows but %d were read beforehand", affected, len(u.before.Rows))
+			return
+		}
+		for _, row := range u.before.Rows {
+			e.Before = append(e.Before, row)
+		}
+	case "UPDATE":
+		sel, keyArgs := selectByKey(table, e.Key, u.idx, u.before.Rows)
+		after, err := readTx(ctx, tx, sel, keyArgs...)
+		if err != nil {
+			u.skip("could not read the rows after the write: %v", err)
+			return
+		}
+		byKey := make(map[string][]interface{}, len(after.Rows))
+		for _, row := range after.Rows {
+			byKey[rowKey(row, u.idx)] = row
+		}
+		for _, row := range u.before.Rows {
+			a, ok := byKey[rowKey(row, u.idx)]
+			if !ok {
+				u.skip("the statement changed primary key values")
+				return
+			}
+			if !sameRow(row, a) {
+				e.Before = append(e.Before, row)
+				e.After = append(e.After, a)
+			}
+		}
+	case "INSERT":
+		if affected > int64(u.journal.maxRows) {
+			u.skip("more than UNDO_MAX_ROWS (%d) rows", u.journal.maxRows)
+			return
+		}
+		first, _ := result.LastInsertId()
+		if first == 0 {
+			u.skip("no AUTO_INCREMENT value was generated")
+			return
+		}
+		// The statement's IDs are first, first+inc, ... only if nothing
+		// interleaved with it (interleaved lock mode, INSERT ... SELECT);
+		// rows that do not follow that sequence may be another session's.
+		var inc int64
+		if err := tx.QueryRowContext(ctx, "SELECT @@auto_increment_increment").Scan(&inc); err != nil || inc < 1 {
+			u.skip("could not read auto_increment_increment")
+			return
+		}
+		after, err := readTx(ctx, tx, fmt.Sprintf("SELECT * FROM %s WHERE %s >= ? ORDER BY %s LIMIT %d",
+			quoteTable(table), quoteIdent(e.Key[0]), quoteIdent(e.Key[0]), affected), first)
+		if err != nil || int64(after.RowCount) != affected {
+			u.skip("could not read back the inserted rows")
+			return
+		}
+		idx := columnIndexes(after.Columns, e.Key)
+		if idx == nil || !consecutiveIDs(after.Rows, idx[0], first, inc) {
+			u.skip("the inserted rows did not get consecutive AUTO_INCREMENT values")
+			return
+		}
+		e.Columns = after.Columns
+		for _, row := range after.Rows {
+			e.After = append(e.After, row)
+		}
+	}
+}
+
+// consecutiveIDs reports whether column col of rows holds exactly first,
+// first+inc, first+2*inc, ...
+func consecutiveIDs(rows [][]interface{}, col int, first, inc int64) bool {
+	for k, row := range rows {
+		v := row[col]
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		if fmt.Sprint(v) != strconv.FormatInt(first+int64(k)*inc, 10) {
+			return false
+		}
+	}
+	return true
+}
+
+// save writes the entry before the commit. A journal that cannot be
+// written does not block the write; the result says it is not journaled.
+func (u *undoCapture) save() {
+	if u == nil || u.entry == nil {
+		return
+	}
+	u.entry.ID = newOperationID()
+	u.entry.Time = time.Now()
+	if err := u.journal.write(u.entry); err != nil {
+		log.Printf("WARNING: undo journal: %v", err)
+		u.skip("could not write the undo journal: %v", err)
+	}
+}
+
+// discard removes the entry of a write whose commit failed.
+func (u *undoCapture) discard() {
+	if u == nil || u.entry == nil {
+		return
+	}
+	os.Remove(u.journal.path(u.entry.ID))
+	u.entry = nil
+}
+
+// describe adds the operation ID, or why there is none, to a result.
+func (u *undoCapture) describe(res *QueryResult) {
+	switch {
+	case u == nil:
+	case u.entry != nil:
+		res.OperationID = u.entry.ID
+		res.Message += fmt.Sprintf(". Operation ID: %s (revert_operation undoes it)", u.entry.ID)
+	case u.note != "":
+		res.Message += ". Not journaled for undo: " + u.note
+	}
+}
+
+var operationIDPattern = regexp.MustCompile(`^op_[0-9a-f]{24}$`)
+
+func newOperationID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "op_" + hex.EncodeToString(b)
+}
+
+func (j *UndoJournal) path(id string) string {
+	return filepath.Join(j.dir, id+".json")
+}
+
+// write stores e atomically and prunes the oldest entries.
+func (j *UndoJournal) write(e *UndoEntry) error {
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := j.path(e.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path(e.ID)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	j.prune()
+	return nil
+}
+
+// Load reads the entry of an operation.
+func (j *UndoJournal) Load(id string) (*UndoEntry, error) {
+	if !operationIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid operation ID %q", id)
+	}
+	data, err := os.ReadFile(j.path(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("unknown operation %q: it was not journaled or is older than the last %d operations (UNDO_MAX_OPERATIONS)", id, j.maxOps)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e UndoEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("undo journal entry %s is corrupt: %w", id, err)
+	}
+	return &e, nil
+}
+
+// prune keeps the newest maxOps entries.
+func (j *UndoJournal) prune() {
+	files, err := filepath.Glob(filepath.Join(j.dir, "op_*.json"))
+	if err != nil || len(files) <= j.maxOps {
+		return
+	}
+	modTime := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		if st, err := os.Stat(f); err == nil {
+			modTime[f] = st.ModTime()
+		}
+	}
+	sort.Slice(files, func(a, b int) bool { return modTime[files[a]].Before(modTime[files[b]]) })
+	for _, f := range files[:len(files)-j.maxOps] {
+		os.Remove(f)
+	}
+}
+
+// RevertOperation undoes a journaled write. It refuses when any of the
+// rows changed after the operation, and applies the MAX_SAFE_ROWS gate to
+// the rows it restores.
+func (c *Client) RevertOperation(id, confirmKey string) (*QueryResult, error) {
+	if err := c.refuseInApprovalMode("revert_operation"); err != nil {
+		return nil, err
+	}
+	if c.undo == nil {
+		return nil, fmt.Errorf("the undo journal is disabled; set UNDO_JOURNAL_DIR to record operations")
+	}
+	e, err := c.undo.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.RevertedAt != nil {
+		return nil, fmt.Errorf("operation %s was already reverted at %s", id, e.RevertedAt.Format(time.RFC3339))
+	}
+
+	table := TableRef{Schema: e.Schema, Name: e.Table}
+	if !c.tableAllowed(table) {
+		return nil, fmt.Errorf("access to table '%s' is not allowed (ALLOWED_TABLES)", table)
+	}
+	idx := columnIndexes(e.Columns, e.Key)
+	expected := e.After
+	if e.Verb == "DELETE" {
+		expected = e.Before
+	}
+	if idx == nil || len(expected) == 0 {
+		return nil, fmt.Errorf("undo journal entry %s has nothing to revert", id)
+	}
+
+	// A rollback cannot undo a non-transactional table, so its gate runs
+	// before the statements and replaces the one after them. Every
+	// restored row is one statement affecting one row.
+	var nonTx []string
+	if c.nonTxPolicy != NonTxOff {
+		if nonTx, err = c.nonTxTables([]TableRef{table}); err != nil {
+			return nil, err
+		}
+	}
+	binding := bindStatement("REVERT "+id, nil)
+	if err := c.guardNonTx(nonTx, binding, confirmKey, func() (int64, bool, error) {
+		return int64(len(expected)), true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.timeoutConfig.TimeoutContext(context.Background(), ProfileWrite)
+	defer cancel()
+
+	conn, err := c.pinConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows := make([][]interface{}, len(expected))
+	for i, r := range expected {
+		rows[i] = r
+	}
+	// The locking read can wait as long as the writes, so the kill watch
+	// covers it too.
+	watch := c.watchForKill(ctx, conn)
+	sel, keyArgs := selectByKey(table, e.Key, idx, rows)
+	current, err := readTx(ctx, tx, sel+" FOR UPDATE", keyArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the current rows: %w", watch.stop(err))
+	}
+	if strings.Join(current.Columns, ",") != strings.Join(e.Columns, ",") {
+		watch.stop(nil)
+		return nil, fmt.Errorf("cannot revert operation %s: the columns of %s changed since it ran", id, table)
+	}
+	if err := revertConflicts(e, idx, current.Rows); err != nil {
+		watch.stop(nil)
+		return nil, err
+	}
+
+	generated, err := generatedColumns(ctx, tx, table)
+	if err != nil {
+		return nil, watch.stop(err)
+	}
+
+	var affected int64
+	for i := range expected {
+		stmt, args := e.inverse(i, idx, generated)
+		result, err := tx.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, fmt.Errorf("revert failed: %w. %s", c.withholdError(stmt, watch.stop(err)), rolledBack(nonTx, "Nothing was changed"))
+		}
+		if n, _ := result.RowsAffected(); n != 1 {
+			watch.stop(nil)
+			return nil, fmt.Errorf("revert failed: restoring row %s affected %d rows. %s", keyText(e.Key, expected[i], idx), n, rolledBack(nonTx, "Nothing was changed"))
+		}
+		affected++
+	}
+	if err := watch.stop(nil); err != nil {
+		return nil, err
+	}
+
+	if len(nonTx) == 0 {
+		if err := c.confirmLargeWrite(binding, affected, confirmKey,
+			fmt.Sprintf("revert of %s affects %d rows", id, affected), "Changes have been rolled back"); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	c.cache.Invalidate([]TableRef{table}, true)
+
+	now := time.Now()
+	e.RevertedAt = &now
+	if err := c.undo.write(e); err != nil {
+		log.Printf("WARNING: undo journal: could not mark %s reverted: %v", id, err)
+	}
+
+	return &QueryResult{
+		RowCount: int(affected),
+		Message:  fmt.Sprintf("Operation %s reverted (%s on %s). Rows restored: %d", id, e.Verb, table, affected),
+	}, nil
+}
+
+// revertConflicts checks that the rows are as the operation left them:
+// updated and inserted rows unchanged, deleted rows still absent.
+func revertConflicts(e *UndoEntry, idx []int, current [][]interface{}) error {
+	byKey := make(map[string][]interface{}, len(current))
+	for _, row := range current {
+		byKey[rowKey(row, idx)] = row
+	}
+	expected := e.After
+	if e.Verb == "DELETE" {
+		expected = e.Before
+	}
+
+	var conflicts []string
+	for _, row := range expected {
+		cur, found := byKey[rowKey(row, idx)]
+		switch {
+		case e.Verb == "DELETE" && found:
+			conflicts = append(conflicts, keyText(e.Key, row, idx)+" exists again")
+		case e.Verb != "DELETE" && !found:
+			conflicts = append(conflicts, keyText(e.Key, row, idx)+" was deleted")
+		case e.Verb != "DELETE" && !sameRow(cur, row):
+			conflicts = append(conflicts, keyText(e.Key, row, idx)+" was modified")
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "cannot revert operation %s: %d of %d rows changed since it ran", e.ID, len(conflicts), len(expected))
+	for i, c := range conflicts {
+		if i == 5 {
+			fmt.Fprintf(&sb, "\n  ... and %d more", len(conflicts)-i)
+			break
+		}
+		sb.WriteString("\n  " + c)
+	}
+	return fmt.Errorf("%s", sb.String())
+}
+
+// inverse builds the statement that undoes row i of the entry.
+func (e *UndoEntry) inverse(i int, idx []int, generated map[string]bool) (string, []interface{}) {
+	table := quoteTable(TableRef{Schema: e.Schema, Name: e.Table})
+	var where []string
+	var keyArgs []interface{}
+	key := e.Before
+	if e.Verb == "INSERT" {
+		key = e.After
+	}
+	for k, col := range e.Key {
+		where = append(where, quoteIdent(col)+" <=> ?")
+		keyArgs = append(keyArgs, key[i][idx[k]])
+	}
+
+	switch e.Verb {
+	case "INSERT":
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", table, strings.Join(where, " AND ")), keyArgs
+	case "DELETE":
+		var cols, marks []string
+		var args []interface{}
+		for c, col := range e.Columns {
+			if generated[strings.ToLower(col)] {
+				continue
+			}
+			cols = append(cols, quoteIdent(col))
+			marks = append(marks, "?")
+			args = append(args, e.Before[i][c])
+		}
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(marks, ", ")), args
+	}
+
+	var set []string
+	var args []interface{}
+	for c, col := range e.Columns {
+		if generated[strings.ToLower(col)] || sameValue(e.Before[i][c], e.After[i][c]) {
+			continue
+		}
+		set = append(set, quoteIdent(col)+" = ?")
+		args = append(args, e.Before[i][c])
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), strings.Join(where, " AND ")), append(args, keyArgs...)
+}
+
+// generatedColumns returns the lower-cased generated columns of a table,
+// which cannot be written.
+func generatedColumns(ctx context.Context, tx *sql.Tx, table TableRef) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT COLUMN_NAME
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COALESCE(GENERATION_EXPRESSION, '') <> ''
+	`, table.Schema, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated columns: %w", err)
+	}
+	defer rows.Close()
+
+	generated := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		generated[strings.ToLower(name)] = true
+	}
+	return generated, rows.Err()
+}
+
+// hasOnDuplicateKey reports an INSERT ... ON DUPLICATE KEY UPDATE.
+func hasOnDuplicateKey(query string) bool {
+	tokens, _ := tokenizeSQL(query)
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].depth == 0 && tokens[i].is("ON") && tokens[i+1].is("DUPLICATE") {
+			return true
+		}
+	}
+	return false
+}
+
+func keyText(key []string, row []interface{}, idx []int) string {
+	parts := make([]string, len(key))
+	for i, col := range key {
+		parts[i] = fmt.Sprintf("%s=%v", col, row[idx[i]])
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func sameRow(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameValue(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue compares two scanned values; text and binary protocol reads
+// of the same value compare equal.
+func sameValue(a, b interface{}) bool {
+	switch x := a.(type) {
+	case []byte:
+		y, ok := b.([]byte)
+		return ok && bytes.Equal(x, y)
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
@@ -0,0 +1,141 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Write details.
+//
+// execWrite runs a write and collects what the driver's RowsAffected alone
+// hides:
+//
+//   - LastInsertId
+//   - the server's warnings (SHOW WARNINGS right after the statement, before
+//     anything else clears them), so truncation and implicit conversion are
+//     not silent. Their messages can quote row values, so they are dropped
+//     when a MASKING_RULES rule applies to the statement.
+//   - for a single-table UPDATE, rows matched next to rows changed. The
+//     driver does not expose the "Rows matched" info string, and the DSN
+//     does not set clientFoundRows, so matched rows are counted with a
+//     locking SELECT over the statement's own WHERE/ORDER BY/LIMIT before
+//     it runs. It locks the rows the UPDATE is about to lock anyway.
+//   - the statement's elapsed time
+
+// MaxWriteWarnings caps the warnings returned for one statement; the
+// total is still reported.
+const MaxWriteWarnings = 20
+
+// SQLWarning is one row of SHOW WARNINGS.
+type SQLWarning struct {
+	Level   string `json:"level"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteInfo describes a write statement's outcome.
+type WriteInfo struct {
+	Affected     int64 `json:"affected"`
+	LastInsertID int64 `json:"last_insert_id,omitempty"`
+	// Matched and Changed are set for single-table UPDATEs only: rows
+	// the WHERE found, and rows whose values actually changed.
+	Matched      *int64       `json:"matched,omitempty"`
+	Changed      *int64       `json:"changed,omitempty"`
+	Warnings     []SQLWarning `json:"warnings,omitempty"`
+	WarningCount int          `json:"warning_count,omitempty"`
+	// WarningsWithheld is set when MASKING_RULES dropped the warning
+	// messages, which can quote row values.
+	WarningsWithheld bool          `json:"warnings_withheld,omitempty"`
+	Elapsed          time.Duration `json:"elapsed"`
+}
+
+// execWrite runs query on tx under a kill watch on conn. The watch covers
+// the locking count of matched rows too, which can wait on locks as long
+// as the statement.
+func (c *Client) execWrite(ctx context.Context, conn *pinnedConn, tx *sql.Tx, query string, args []interface{}) (sql.Result, *WriteInfo, error) {
+	info := &WriteInfo{}
+	watch := c.watchForKill(ctx, conn)
+	if shape, ok := parseDMLShape(query); ok && shape.verb == "UPDATE" {
+		if matched, err := countMatched(ctx, tx, shape, args); err == nil {
+			info.Matched = &matched
+		}
+	}
+
+	start := time.Now()
+	result, err := tx.ExecContext(ctx, query, args...)
+	info.Elapsed = time.Since(start)
+	if err = watch.stop(err); err != nil {
+		return nil, nil, c.withholdError(query, err)
+	}
+
+	info.Affected, _ = result.RowsAffected()
+	info.LastInsertID, _ = result.LastInsertId()
+	if info.Matched != nil {
+		changed := info.Affected
+		info.Changed = &changed
+	}
+	info.Warnings, info.WarningCount = readWarnings(ctx, tx)
+	info.WarningsWithheld = c.securityConfig.Masking.RedactWarnings(query, info.Warnings)
+	return result, info, nil
+}
+
+// countMatched counts, and locks, the rows a single-table UPDATE matches.
+func countMatched(ctx context.Context, tx *sql.Tx, shape *dmlShape, args []interface{}) (int64, error) {
+	from := strings.TrimSpace(shape.factor + " " + shape.tail)
+	args = args[min(shape.skip, len(args)):]
+
+	var n int64
+	if !hasTopLevelWord(shape.tail, "LIMIT") {
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+from+" FOR UPDATE", args...).Scan(&n)
+		return n, err
+	}
+	// COUNT(*) would ignore the LIMIT, so count the limited rows.
+	rows, err := tx.QueryContext(ctx, "SELECT 1 FROM "+from+" FOR UPDATE", args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		n++
+	}
+	return n, rows.Err()
+}
+
+// readWarnings returns the warnings of the statement that just ran on tx.
+// SHOW COUNT(*) WARNINGS and SHOW WARNINGS do not clear them.
+func readWarnings(ctx context.Context, tx *sql.Tx) ([]SQLWarning, int) {
+	var count int
+	if err := tx.QueryRowContext(ctx, "SHOW COUNT(*) WARNINGS").Scan(&count); err != nil || count == 0 {
+		return nil, 0
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("SHOW WARNINGS LIMIT %d", MaxWriteWarnings))
+	if err != nil {
+		return nil, count
+	}
+	defer rows.Close()
+
+	var warnings []SQLWarning
+	for rows.Next() {
+		var w SQLWarning
+		if err := rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			break
+		}
+		warnings = append(warnings, w)
+	}
+	return warnings, count
+}
+
+// hasTopLevelWord reports whether word appears as a keyword outside
+// parentheses in s.
+func hasTopLevelWord(s, word string) bool {
+	tokens, _ := tokenizeSQL(s)
+	for _, t := range tokens {
+		if t.depth == 0 && t.is(word) {
+			return true
+		}
+	}
+	return false
+}